@@ -2,4 +2,7 @@ package assets
 
 import "embed"
 
+// Assets holds the migrations, which are applied on start and by the migrate command.
+//
+//go:embed migrations
 var Assets embed.FS
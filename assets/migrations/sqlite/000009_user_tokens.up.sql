@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+
+    user_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL,
+
+    expires_at INTEGER NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE videos DROP COLUMN duration;
+ALTER TABLE videos DROP COLUMN status;
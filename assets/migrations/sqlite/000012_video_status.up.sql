@@ -0,0 +1,2 @@
+ALTER TABLE videos ADD COLUMN status TEXT NOT NULL DEFAULT 'ready';
+ALTER TABLE videos ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
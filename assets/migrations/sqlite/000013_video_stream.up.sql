@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN stream_path TEXT NOT NULL DEFAULT '';
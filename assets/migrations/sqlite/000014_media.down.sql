@@ -0,0 +1,4 @@
+ALTER TABLE uploads DROP COLUMN kind;
+
+DROP INDEX IF EXISTS media_owner_id_idx;
+DROP TABLE IF EXISTS media;
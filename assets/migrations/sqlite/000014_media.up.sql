@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS media (
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+
+    owner_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+
+    parent TEXT NOT NULL,
+    name TEXT NOT NULL,
+
+    size INTEGER NOT NULL,
+    content_type TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+
+    UNIQUE (parent, name),
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS media_owner_id_idx ON media (owner_id);
+
+ALTER TABLE uploads ADD COLUMN kind TEXT NOT NULL DEFAULT 'video';
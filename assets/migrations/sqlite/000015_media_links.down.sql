@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_avatar_path_idx;
+DROP INDEX IF EXISTS videos_thumbnail_path_idx;
+DROP INDEX IF EXISTS videos_video_path_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS videos_video_path_idx ON videos (video_path);
+CREATE INDEX IF NOT EXISTS videos_thumbnail_path_idx ON videos (thumbnail_path);
+CREATE INDEX IF NOT EXISTS users_avatar_path_idx ON users (avatar_path);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS media_variants (
+    media_id TEXT NOT NULL,
+    size INTEGER NOT NULL,
+
+    name TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+
+    content_type TEXT NOT NULL,
+    bytes INTEGER NOT NULL,
+
+    PRIMARY KEY (media_id, size),
+    UNIQUE (name),
+    FOREIGN KEY (media_id) REFERENCES media(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_created_at_id_idx;
+DROP INDEX IF EXISTS comments_video_id_created_at_id_idx;
+DROP INDEX IF EXISTS videos_author_id_created_at_id_idx;
+DROP INDEX IF EXISTS videos_views_id_idx;
+DROP INDEX IF EXISTS videos_created_at_id_idx;
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS videos_created_at_id_idx ON videos (created_at, id);
+CREATE INDEX IF NOT EXISTS videos_views_id_idx ON videos (views, id);
+CREATE INDEX IF NOT EXISTS videos_author_id_created_at_id_idx ON videos (author_id, created_at, id);
+CREATE INDEX IF NOT EXISTS comments_video_id_created_at_id_idx ON comments (video_id, created_at, id);
+CREATE INDEX IF NOT EXISTS users_created_at_id_idx ON users (created_at, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS subscriptions_to_user_id_created_at_id_idx;
+DROP INDEX IF EXISTS subscriptions_from_user_id_created_at_id_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS subscriptions_from_user_id_created_at_id_idx ON subscriptions (from_user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS subscriptions_to_user_id_created_at_id_idx ON subscriptions (to_user_id, created_at, id);
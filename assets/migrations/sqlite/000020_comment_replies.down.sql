@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS comments_parent_id_created_at_id_idx;
+
+DELETE FROM comments WHERE parent_id IS NOT NULL;
+
+ALTER TABLE comments DROP COLUMN parent_id;
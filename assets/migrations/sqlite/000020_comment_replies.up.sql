@@ -0,0 +1,3 @@
+ALTER TABLE comments ADD COLUMN parent_id TEXT REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS comments_parent_id_created_at_id_idx ON comments (parent_id, created_at, id);
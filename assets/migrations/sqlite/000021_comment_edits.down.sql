@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS comment_edits_insert;
+DROP TABLE IF EXISTS comment_edits;
+
+ALTER TABLE comments DROP COLUMN is_deleted;
+ALTER TABLE comments DROP COLUMN is_edited;
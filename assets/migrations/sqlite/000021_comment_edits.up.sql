@@ -0,0 +1,21 @@
+ALTER TABLE comments ADD COLUMN is_edited INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN is_deleted INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS comment_edits (
+    id INTEGER PRIMARY KEY,
+    comment_id TEXT NOT NULL,
+
+    message TEXT NOT NULL,
+    edited_at INTEGER NOT NULL,
+
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS comment_edits_comment_id_idx ON comment_edits (comment_id);
+
+-- The text a comment had before an edit is kept, removing the text of a deleted comment is not an edit.
+CREATE TRIGGER IF NOT EXISTS comment_edits_insert AFTER UPDATE OF message ON comments
+WHEN old.message <> new.message AND new.is_deleted = 0
+BEGIN
+    INSERT INTO comment_edits (comment_id, message, edited_at) VALUES (old.id, old.message, new.updated_at);
+END;
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS comment_ratings_delete;
+DROP TRIGGER IF EXISTS comment_ratings_update;
+DROP TRIGGER IF EXISTS comment_ratings_insert;
+
+DROP INDEX IF EXISTS comments_video_id_score_id_idx;
+
+ALTER TABLE comments DROP COLUMN is_hearted;
+ALTER TABLE comments DROP COLUMN is_pinned;
+ALTER TABLE comments DROP COLUMN dislikes;
+ALTER TABLE comments DROP COLUMN likes;
+
+DROP TABLE IF EXISTS comment_ratings;
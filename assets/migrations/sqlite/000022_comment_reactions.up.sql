@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS comment_ratings (
+    id TEXT NOT NULL PRIMARY KEY,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+
+    user_id TEXT NOT NULL,
+    comment_id TEXT NOT NULL,
+
+    is_like INTEGER NOT NULL DEFAULT 0,
+
+    UNIQUE(user_id, comment_id),
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS comment_ratings_comment_id_idx ON comment_ratings (comment_id);
+
+ALTER TABLE comments ADD COLUMN likes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN dislikes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN is_pinned INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN is_hearted INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS comments_video_id_score_id_idx ON comments (video_id, (likes - dislikes), id);
+
+-- The counters of a comment follow its ratings, so comments can be sorted by them.
+CREATE TRIGGER IF NOT EXISTS comment_ratings_insert AFTER INSERT ON comment_ratings
+BEGIN
+    UPDATE comments SET
+        likes = likes + (new.is_like > 0),
+        dislikes = dislikes + (new.is_like <= 0)
+    WHERE id = new.comment_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comment_ratings_update AFTER UPDATE OF is_like ON comment_ratings
+BEGIN
+    UPDATE comments SET
+        likes = likes - (old.is_like > 0) + (new.is_like > 0),
+        dislikes = dislikes - (old.is_like <= 0) + (new.is_like <= 0)
+    WHERE id = new.comment_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS comment_ratings_delete AFTER DELETE ON comment_ratings
+BEGIN
+    UPDATE comments SET
+        likes = likes - (old.is_like > 0),
+        dislikes = dislikes - (old.is_like <= 0)
+    WHERE id = old.comment_id;
+END;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS ratings_delete;
+DROP TRIGGER IF EXISTS ratings_update;
+DROP TRIGGER IF EXISTS ratings_insert;
+
+ALTER TABLE videos DROP COLUMN dislikes;
+ALTER TABLE videos DROP COLUMN likes;
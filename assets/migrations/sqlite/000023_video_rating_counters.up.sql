@@ -0,0 +1,31 @@
+ALTER TABLE videos ADD COLUMN likes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE videos ADD COLUMN dislikes INTEGER NOT NULL DEFAULT 0;
+
+UPDATE videos SET
+    likes = (SELECT COUNT(*) FROM ratings WHERE ratings.video_id = videos.id AND ratings.is_like > 0),
+    dislikes = (SELECT COUNT(*) FROM ratings WHERE ratings.video_id = videos.id AND ratings.is_like <= 0);
+
+-- The counters change in the same statement as the ratings, so they never drift apart.
+CREATE TRIGGER IF NOT EXISTS ratings_insert AFTER INSERT ON ratings
+BEGIN
+    UPDATE videos SET
+        likes = likes + (new.is_like > 0),
+        dislikes = dislikes + (new.is_like <= 0)
+    WHERE id = new.video_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS ratings_update AFTER UPDATE OF is_like ON ratings
+BEGIN
+    UPDATE videos SET
+        likes = likes - (old.is_like > 0) + (new.is_like > 0),
+        dislikes = dislikes - (old.is_like <= 0) + (new.is_like <= 0)
+    WHERE id = new.video_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS ratings_delete AFTER DELETE ON ratings
+BEGIN
+    UPDATE videos SET
+        likes = likes - (old.is_like > 0),
+        dislikes = dislikes - (old.is_like <= 0)
+    WHERE id = old.video_id;
+END;
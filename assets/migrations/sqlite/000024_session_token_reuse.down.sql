@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS sessions_previous_refresh_token_idx;
+
+ALTER TABLE sessions DROP COLUMN previous_refresh_token;
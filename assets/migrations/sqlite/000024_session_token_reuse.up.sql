@@ -0,0 +1,4 @@
+-- The refresh token replaced by the last rotation, presenting it again revokes the session.
+ALTER TABLE sessions ADD COLUMN previous_refresh_token TEXT;
+
+CREATE INDEX IF NOT EXISTS sessions_previous_refresh_token_idx ON sessions (previous_refresh_token);
@@ -0,0 +1,6 @@
+-- Parts stored since the up migration are not named after their index, uploads in progress with them cannot be completed.
+ALTER TABLE uploads ADD COLUMN parts INTEGER NOT NULL DEFAULT 0;
+
+UPDATE uploads SET parts = length(part_names) - length(replace(part_names, char(10), ''));
+
+ALTER TABLE uploads DROP COLUMN part_names;
@@ -0,0 +1,10 @@
+-- Every chunk is stored under a name of its own, the names of the recorded chunks end with a newline each.
+ALTER TABLE uploads ADD COLUMN part_names TEXT NOT NULL DEFAULT '';
+
+-- Parts of uploads in progress were named after their index.
+UPDATE uploads SET part_names = (
+    WITH RECURSIVE part(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM part WHERE i + 1 < uploads.parts)
+    SELECT group_concat(uploads.id || '.' || i || char(10), '') FROM part
+) WHERE parts > 0;
+
+ALTER TABLE uploads DROP COLUMN parts;
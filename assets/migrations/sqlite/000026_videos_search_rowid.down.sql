@@ -0,0 +1,42 @@
+DROP TRIGGER IF EXISTS videos_search_author_update;
+DROP TRIGGER IF EXISTS videos_search_delete;
+DROP TRIGGER IF EXISTS videos_search_update;
+DROP TRIGGER IF EXISTS videos_search_insert;
+DROP TABLE IF EXISTS videos_search;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS videos_search USING fts5 (
+    video_id UNINDEXED,
+    title,
+    description,
+    author_nickname,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS videos_search_insert AFTER INSERT ON videos
+BEGIN
+    INSERT INTO videos_search (video_id, title, description, author_nickname)
+    SELECT new.id, new.title, new.description, users.nickname FROM users WHERE users.id = new.author_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS videos_search_update AFTER UPDATE OF title, description, author_id ON videos
+BEGIN
+    DELETE FROM videos_search WHERE video_id = old.id;
+    INSERT INTO videos_search (video_id, title, description, author_nickname)
+    SELECT new.id, new.title, new.description, users.nickname FROM users WHERE users.id = new.author_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS videos_search_delete AFTER DELETE ON videos
+BEGIN
+    DELETE FROM videos_search WHERE video_id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS videos_search_author_update AFTER UPDATE OF nickname ON users
+BEGIN
+    UPDATE videos_search SET author_nickname = new.nickname
+    WHERE video_id IN (SELECT id FROM videos WHERE author_id = new.id);
+END;
+
+INSERT INTO videos_search (video_id, title, description, author_nickname)
+SELECT videos.id, videos.title, videos.description, users.nickname
+FROM videos JOIN users ON videos.author_id = users.id;
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/protomem/gotube/internal/blobstore/s3/fakes3"
+	"github.com/protomem/gotube/pkg/sigv4"
+)
+
+func main() {
+	var (
+		addr      = flag.String("addr", "localhost:9000", "address to listen on")
+		region    = flag.String("region", "us-east-1", "region used in signatures")
+		accessKey = flag.String("access-key", "", "access key, requests are not verified if empty")
+		secretKey = flag.String("secret-key", "", "secret key")
+	)
+
+	flag.Parse()
+
+	server := fakes3.New(sigv4.Signer{
+		Credentials: sigv4.Credentials{AccessKey: *accessKey, SecretKey: *secretKey},
+		Region:      *region,
+		Service:     "s3",
+	})
+
+	log.Printf("fake s3 listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatal(err)
+	}
+}
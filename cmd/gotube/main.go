@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
 
@@ -8,7 +9,17 @@ import (
 )
 
 func main() {
-	if err := app.New().Run(); err != nil {
+	var err error
+	switch flag.Arg(0) {
+	case "migrate":
+		err = app.New().Migrate(flag.Args()[1:])
+	case "role":
+		err = app.New().SetRole(flag.Args()[1:])
+	default:
+		err = app.New().Run()
+	}
+
+	if err != nil {
 		log.Printf("App Failed: %v", err)
 		os.Exit(1)
 	}
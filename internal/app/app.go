@@ -11,21 +11,34 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/protomem/gotube/internal/authz"
 	"github.com/protomem/gotube/internal/blobstore"
 	fsbstore "github.com/protomem/gotube/internal/blobstore/filesystem"
+	s3bstore "github.com/protomem/gotube/internal/blobstore/s3"
 	"github.com/protomem/gotube/internal/config"
 	"github.com/protomem/gotube/internal/ctxstore"
 	"github.com/protomem/gotube/internal/database"
 	sqlitedb "github.com/protomem/gotube/internal/database/sqlite"
 	"github.com/protomem/gotube/internal/handler"
+	"github.com/protomem/gotube/internal/jobqueue"
+	"github.com/protomem/gotube/internal/mailer"
+	outboxmailer "github.com/protomem/gotube/internal/mailer/outbox"
+	smtpmailer "github.com/protomem/gotube/internal/mailer/smtp"
+	"github.com/protomem/gotube/internal/mediagc"
 	"github.com/protomem/gotube/internal/middleware"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/processing"
+	fakeexec "github.com/protomem/gotube/internal/processing/fake"
+	ffmpegexec "github.com/protomem/gotube/internal/processing/ffmpeg"
 	"github.com/protomem/gotube/internal/repository"
 	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
 	"github.com/protomem/gotube/internal/service"
+	"github.com/protomem/gotube/internal/viewcounter"
 	"github.com/protomem/gotube/pkg/closing"
 	"github.com/protomem/gotube/pkg/hashing/bcrypt"
 	"github.com/protomem/gotube/pkg/logging"
 	stdlog "github.com/protomem/gotube/pkg/logging/std"
+	"github.com/protomem/gotube/pkg/urlsign"
 )
 
 type App struct {
@@ -34,6 +47,10 @@ type App struct {
 
 	db     database.DB
 	bstore blobstore.Storage
+	mailer mailer.Mailer
+	views  *viewcounter.Counter
+	jobs   *jobqueue.Queue
+	gc     *mediagc.Collector
 
 	repositories *repository.Repositories
 	services     *service.Services
@@ -54,6 +71,44 @@ func New() *App {
 	}
 }
 
+// NewWith builds and starts the app around the logger, database, blob storage and mailer
+// instead of the configured ones, the rest of its config is still read from the environment.
+// It does not listen, its routes are served through Handler until Close.
+func NewWith(logger logging.Logger, db database.DB, bstore blobstore.Storage, mailer mailer.Mailer) (*App, error) {
+	const op = "app.NewWith"
+	ctx := context.Background()
+
+	app := New()
+	app.logger = logger
+	app.db = db
+	app.bstore = bstore
+	app.mailer = mailer
+
+	if err := app.initServer(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := app.build(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := app.start(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return app, nil
+}
+
+// Handler serves the routes of the app.
+func (app *App) Handler() http.Handler {
+	return app.router
+}
+
+// Close stops the background work and closes what the app holds, Run does it on shutdown.
+func (app *App) Close(ctx context.Context) error {
+	return app.closer.Close(ctx)
+}
+
 func (app *App) Run() error {
 	const op = "app.Run"
 	ctx := context.Background()
@@ -62,18 +117,13 @@ func (app *App) Run() error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	authConf, err := app.conf.Auth()
-	if err != nil {
+	if err := app.build(); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	app.repositories = sqliterepo.New(app.logger, app.db)
-	app.services = service.New(authConf, app.repositories, bcrypt.New(bcrypt.DefaultCost))
-	app.handlers = handler.New(app.logger, app.services, app.bstore)
-	app.middlewares = middleware.New(app.logger, app.services)
-
-	app.registerOnShutdown()
-	app.setupRoutes()
+	if err := app.start(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	errs := make(chan error, 1)
 
@@ -90,6 +140,117 @@ func (app *App) Run() error {
 	return nil
 }
 
+// build wires the repositories, services, handlers and routes on top of what init opened.
+func (app *App) build() error {
+	const op = "build"
+
+	authConf, err := app.conf.Auth()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	uploadConf, err := app.conf.Upload()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	viewsConf, err := app.conf.Views()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	jobsConf, err := app.conf.Jobs()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	processingConf, err := app.conf.Processing()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	mediaConf, err := app.conf.Media()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	mediaGCConf, err := app.conf.MediaGC()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	serverConf, err := app.conf.Server()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	trustedProxies, err := middleware.ParseTrustedProxies(serverConf.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	executor, err := app.newExecutor(processingConf)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	authorizer := authz.New()
+
+	app.repositories = sqliterepo.New(app.logger, app.db)
+	app.views = viewcounter.New(app.logger, app.repositories.Video, viewcounter.Options{
+		Window:        viewsConf.Window,
+		FlushInterval: viewsConf.FlushInterval,
+		MaxPending:    viewsConf.MaxPending,
+	})
+	app.jobs = jobqueue.New(app.logger, app.repositories.Job, jobqueue.Options{
+		Workers:      jobsConf.Workers,
+		PollInterval: jobsConf.PollInterval,
+		MaxAttempts:  jobsConf.MaxAttempts,
+		Backoff:      jobsConf.Backoff,
+		MaxBackoff:   jobsConf.MaxBackoff,
+		Lease:        jobsConf.Lease,
+	})
+	app.services = service.New(
+		authConf, uploadConf, processingConf, mediaConf,
+		app.repositories, app.bstore,
+		bcrypt.New(bcrypt.DefaultCost), authorizer, app.mailer, app.views,
+		processing.New(executor, processing.DefaultSteps(processing.Limits{
+			MaxDuration:  processingConf.MaxDuration,
+			MaxDimension: processingConf.MaxDimension,
+		})...),
+		app.jobs,
+	)
+	app.gc = mediagc.New(app.logger, app.bstore, app.services.Media, mediagc.Options{
+		Interval:    mediaGCConf.Interval,
+		GracePeriod: mediaGCConf.GracePeriod,
+		DryRun:      mediaGCConf.DryRun,
+	})
+	app.handlers = handler.New(
+		app.logger, app.services, app.bstore, app.gc,
+		handler.NewMediaURLs(urlsign.New(mediaConf.URLSecret, mediaConf.URLTTL)),
+	)
+	app.middlewares = middleware.New(app.logger, app.services, trustedProxies)
+
+	app.registerOnShutdown()
+	app.setupRoutes()
+	app.registerJobs()
+
+	return nil
+}
+
+// start runs the background work of the app.
+func (app *App) start(ctx context.Context) error {
+	const op = "start"
+
+	app.views.Start()
+	if err := app.jobs.Start(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	app.gc.Start()
+
+	return nil
+}
+
 func (app *App) init() error {
 	const op = "init"
 	ctx := context.Background()
@@ -106,6 +267,10 @@ func (app *App) init() error {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := app.initMailer(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	if err := app.initServer(); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -145,10 +310,22 @@ func (app *App) initDB(ctx context.Context) error {
 		return err
 	}
 
-	app.db, err = sqlitedb.Connect(ctx, app.logger, conf.DSN)
+	db, err := sqlitedb.Connect(ctx, app.logger, conf.DSN)
 	if err != nil {
 		return err
 	}
+	app.db = db
+
+	if conf.Migrate {
+		migrator, err := app.newMigrator(db)
+		if err != nil {
+			return err
+		}
+
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -156,12 +333,74 @@ func (app *App) initDB(ctx context.Context) error {
 func (app *App) initBStore() error {
 	var err error
 
-	conf, err := app.conf.FileStorage()
+	conf, err := app.conf.BlobStore()
+	if err != nil {
+		return err
+	}
+
+	switch conf.Driver {
+	case "filesystem":
+		fsConf, err := app.conf.FileStorage()
+		if err != nil {
+			return err
+		}
+
+		app.bstore, err = fsbstore.New(app.logger, fsConf.Folder)
+		if err != nil {
+			return err
+		}
+	case "s3":
+		s3Conf, err := app.conf.S3()
+		if err != nil {
+			return err
+		}
+
+		app.bstore, err = s3bstore.New(app.logger, s3bstore.Options{
+			Endpoint:   s3Conf.Endpoint,
+			Region:     s3Conf.Region,
+			Bucket:     s3Conf.Bucket,
+			AccessKey:  s3Conf.AccessKey,
+			SecretKey:  s3Conf.SecretKey,
+			PathStyle:  s3Conf.PathStyle,
+			Prefix:     s3Conf.Prefix,
+			PartSize:   s3Conf.PartSize,
+			PresignTTL: s3Conf.PresignTTL,
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown blobstore driver %q", conf.Driver)
+	}
+
+	return nil
+}
+
+func (app *App) initMailer() error {
+	var err error
+
+	conf, err := app.conf.Mail()
 	if err != nil {
 		return err
 	}
 
-	app.bstore, err = fsbstore.New(app.logger, conf.Folder)
+	switch conf.Driver {
+	case "smtp":
+		app.mailer, err = smtpmailer.New(app.logger, smtpmailer.Options{
+			Host:     conf.SMTPHost,
+			Port:     conf.SMTPPort,
+			Username: conf.SMTPUsername,
+			Password: conf.SMTPPassword,
+			From:     conf.From,
+		})
+	case "outbox":
+		if conf.OutboxFolder == "" {
+			return fmt.Errorf("outbox mailer: a folder is required, set APP_MAIL_OUTBOX_FOLDER")
+		}
+		app.mailer, err = outboxmailer.New(app.logger, conf.OutboxFolder)
+	default:
+		err = fmt.Errorf("unknown mail driver %q", conf.Driver)
+	}
 	if err != nil {
 		return err
 	}
@@ -169,6 +408,26 @@ func (app *App) initBStore() error {
 	return nil
 }
 
+func (app *App) newExecutor(conf config.Processing) (processing.Executor, error) {
+	ffmpegOpts := ffmpegexec.Options{
+		FFmpegPath:  conf.FFmpegPath,
+		FFprobePath: conf.FFprobePath,
+	}
+
+	switch conf.Executor {
+	case "ffmpeg":
+		if !ffmpegexec.Available(ffmpegOpts) {
+			return nil, fmt.Errorf("ffmpeg executor: ffmpeg or ffprobe not found, install them or select the fake executor")
+		}
+		return ffmpegexec.New(app.logger, ffmpegOpts), nil
+	case "fake":
+		app.logger.Warn("videos are processed by the fake executor, it is meant for development only")
+		return fakeexec.New(app.logger), nil
+	default:
+		return nil, fmt.Errorf("unknown processing executor %q", conf.Executor)
+	}
+}
+
 func (app *App) initServer() error {
 	conf, err := app.conf.Server()
 	if err != nil {
@@ -185,19 +444,31 @@ func (app *App) initServer() error {
 
 func (app *App) registerOnShutdown() {
 	app.closer.Add(app.server.Shutdown)
+	app.closer.Add(app.views.Close)
+	app.closer.Add(app.jobs.Close)
+	app.closer.Add(app.gc.Close)
 	app.closer.Add(app.db.Close)
 	app.closer.Add(app.bstore.Close)
+	app.closer.Add(app.mailer.Close)
 	app.closer.Add(func(ctx context.Context) error {
 		return app.logger.WithContext(ctx).Sync()
 	})
 }
 
+func (app *App) registerJobs() {
+	app.jobs.Register(service.JobProcessVideo, jobqueue.Handler{
+		Run:  app.services.Processing.RunVideoJob,
+		Fail: app.services.Processing.FailVideoJob,
+	})
+}
+
 func (app *App) setupRoutes() {
 	router := app.router
 	middlewares := app.middlewares
 	handlers := app.handlers
 
 	router.Use(middlewares.TraceID())
+	router.Use(middlewares.ClientIP())
 	router.Use(middlewares.LogAccess(app.logger))
 	router.Use(middlewares.Recovery(app.logger))
 
@@ -210,6 +481,10 @@ func (app *App) setupRoutes() {
 
 	{
 		router.HandleFunc("/users/{userNickname}", handlers.User.Get()).Methods(http.MethodGet)
+		router.HandleFunc(
+			"/users/{userNickname}/subscribers",
+			handlers.Subscription.ListSubscribers(),
+		).Methods(http.MethodGet)
 		router.HandleFunc("/users", handlers.User.Create()).Methods(http.MethodPost)
 
 		router.Handle(
@@ -225,9 +500,33 @@ func (app *App) setupRoutes() {
 
 	{
 		router.Handle("/auth/login", handlers.Auth.Login()).Methods(http.MethodPost)
+		router.Handle("/auth/refresh", handlers.Auth.Refresh()).Methods(http.MethodPost)
+		router.Handle("/auth/verify-email", handlers.Account.VerifyEmail()).Methods(http.MethodPost)
+		router.Handle(
+			"/auth/verify-email/resend",
+			middlewares.Protect()(handlers.Account.ResendVerification()),
+		).Methods(http.MethodPost)
+		router.Handle("/auth/forgot-password", handlers.Account.ForgotPassword()).Methods(http.MethodPost)
+		router.Handle("/auth/reset-password", handlers.Account.ResetPassword()).Methods(http.MethodPost)
+		router.Handle(
+			"/auth/logout",
+			middlewares.Protect()(handlers.Auth.Logout()),
+		).Methods(http.MethodPost)
+		router.Handle(
+			"/auth/sessions",
+			middlewares.Protect()(handlers.Auth.ListSessions()),
+		).Methods(http.MethodGet)
+		router.Handle(
+			"/auth/sessions/{sessionId}",
+			middlewares.Protect()(handlers.Auth.RevokeSession()),
+		).Methods(http.MethodDelete)
 	}
 
 	{
+		router.Handle(
+			"/subs",
+			middlewares.Protect()(handlers.Subscription.ListSubscriptions()),
+		).Methods(http.MethodGet)
 		router.HandleFunc("/subs/{userNickname}", handlers.Subscription.Count()).Methods(http.MethodGet)
 		router.Handle(
 			"/subs/{userNickname}",
@@ -241,7 +540,20 @@ func (app *App) setupRoutes() {
 
 	{
 		router.HandleFunc("/videos", handlers.Video.List()).Methods(http.MethodGet)
+		router.Handle(
+			"/feed",
+			middlewares.Protect()(handlers.Video.Feed()),
+		).Methods(http.MethodGet)
 		router.HandleFunc("/videos/{videoId}", handlers.Video.Get()).Methods(http.MethodGet)
+		router.HandleFunc("/videos/{videoId}/views", handlers.Video.RecordView()).Methods(http.MethodPost)
+		router.HandleFunc(
+			"/videos/{videoId}/stream/{file}",
+			handlers.Video.Stream(),
+		).Methods(http.MethodGet, http.MethodHead)
+		router.HandleFunc(
+			"/videos/{videoId}/stream/{rendition}/{file}",
+			handlers.Video.Stream(),
+		).Methods(http.MethodGet, http.MethodHead)
 		router.Handle(
 			"/videos",
 			middlewares.Protect()(handlers.Video.Creaate()),
@@ -281,6 +593,40 @@ func (app *App) setupRoutes() {
 	}
 
 	{
+		router.HandleFunc("/comments/{commentId}/replies", handlers.Comment.ListReplies()).Methods(http.MethodGet)
+		router.HandleFunc("/comments/{commentId}/edits", handlers.Comment.ListEdits()).Methods(http.MethodGet)
+		router.Handle(
+			"/comments/{commentId}/pin",
+			middlewares.Protect()(handlers.Comment.Pin(true)),
+		).Methods(http.MethodPut)
+		router.Handle(
+			"/comments/{commentId}/pin",
+			middlewares.Protect()(handlers.Comment.Pin(false)),
+		).Methods(http.MethodDelete)
+		router.Handle(
+			"/comments/{commentId}/heart",
+			middlewares.Protect()(handlers.Comment.Heart(true)),
+		).Methods(http.MethodPut)
+		router.Handle(
+			"/comments/{commentId}/heart",
+			middlewares.Protect()(handlers.Comment.Heart(false)),
+		).Methods(http.MethodDelete)
+		router.Handle(
+			"/comments/{commentId}/rating/like",
+			middlewares.Protect()(handlers.Rating.LikeComment()),
+		).Methods(http.MethodPost)
+		router.Handle(
+			"/comments/{commentId}/rating/dislike",
+			middlewares.Protect()(handlers.Rating.DislikeComment()),
+		).Methods(http.MethodPost)
+		router.Handle(
+			"/comments/{commentId}/rating",
+			middlewares.Protect()(handlers.Rating.DeleteComment()),
+		).Methods(http.MethodDelete)
+		router.Handle(
+			"/comments/{commentId}",
+			middlewares.Protect()(handlers.Comment.Update()),
+		).Methods(http.MethodPatch)
 		router.Handle(
 			"/comments/{commentId}",
 			middlewares.Protect()(handlers.Comment.Delete()),
@@ -288,7 +634,7 @@ func (app *App) setupRoutes() {
 	}
 
 	{
-		router.HandleFunc("/media/{parent}/{file}", handlers.Media.Get()).Methods(http.MethodGet)
+		router.HandleFunc("/media/{parent}/{file}", handlers.Media.Get()).Methods(http.MethodGet, http.MethodHead)
 		router.Handle(
 			"/media/{parent}/{file}",
 			middlewares.Protect()(handlers.Media.Save()),
@@ -298,6 +644,41 @@ func (app *App) setupRoutes() {
 			middlewares.Protect()(handlers.Media.Delete()),
 		).Methods(http.MethodDelete)
 	}
+
+	{
+		router.Handle(
+			"/uploads",
+			middlewares.Protect()(handlers.Upload.Create()),
+		).Methods(http.MethodPost)
+		router.Handle(
+			"/uploads/{uploadId}",
+			middlewares.Protect()(handlers.Upload.Progress()),
+		).Methods(http.MethodHead, http.MethodGet)
+		router.Handle(
+			"/uploads/{uploadId}",
+			middlewares.Protect()(handlers.Upload.WriteChunk()),
+		).Methods(http.MethodPatch)
+		router.Handle(
+			"/uploads/{uploadId}/complete",
+			middlewares.Protect()(handlers.Upload.Complete()),
+		).Methods(http.MethodPost)
+		router.Handle(
+			"/uploads/{uploadId}",
+			middlewares.Protect()(handlers.Upload.Abort()),
+		).Methods(http.MethodDelete)
+	}
+
+	{
+		admin := router.PathPrefix("/admin").Subrouter()
+		admin.Use(middlewares.Protect())
+		admin.Use(middlewares.RequireRole(model.RoleAdmin))
+
+		admin.HandleFunc("/users", handlers.Admin.ListUsers()).Methods(http.MethodGet)
+		admin.HandleFunc("/users/{userNickname}", handlers.Admin.UpdateUser()).Methods(http.MethodPut, http.MethodPatch)
+		admin.HandleFunc("/users/{userNickname}", handlers.Admin.DeleteUser()).Methods(http.MethodDelete)
+
+		admin.HandleFunc("/media/gc", handlers.Admin.CollectMedia()).Methods(http.MethodPost)
+	}
 }
 
 func (app *App) serverStart(_ context.Context, errs chan<- error) {
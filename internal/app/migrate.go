@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/protomem/gotube/assets"
+	sqlitedb "github.com/protomem/gotube/internal/database/sqlite"
+)
+
+// Migrate runs the migrate command: up, down with the number of migrations to revert, one by default, or status.
+func (app *App) Migrate(args []string) error {
+	const op = "app.Migrate"
+	ctx := context.Background()
+
+	if len(args) == 0 {
+		return fmt.Errorf("%s: missing command, expected up, down or status", op)
+	}
+
+	db, err := app.connectDB(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = db.Close(ctx) }()
+
+	migrator, err := app.newMigrator(db)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("%s: invalid number of migrations %q", op, args[1])
+			}
+		}
+
+		err = migrator.Down(ctx, steps)
+	case "status":
+		var statuses []sqlitedb.MigrationStatus
+		statuses, err = migrator.Status(ctx)
+		if err == nil {
+			err = printMigrationStatus(statuses)
+		}
+	default:
+		return fmt.Errorf("%s: unknown command %q, expected up, down or status", op, args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// connectDB sets up the logger and the database for the commands which run without the server.
+func (app *App) connectDB(ctx context.Context) (*sqlitedb.DB, error) {
+	if err := app.initLogger(); err != nil {
+		return nil, err
+	}
+
+	conf, err := app.conf.SQLiteDB()
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlitedb.Connect(ctx, app.logger, conf.DSN)
+}
+
+func (app *App) newMigrator(db *sqlitedb.DB) (*sqlitedb.Migrator, error) {
+	migrations, err := fs.Sub(assets.Assets, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	return sqlitedb.NewMigrator(app.logger, db, migrations)
+}
+
+func printMigrationStatus(statuses []sqlitedb.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Modified {
+			state = "modified"
+		}
+		if status.Unknown {
+			state = "unknown"
+		}
+
+		fmt.Fprintf(w, "%06d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+
+	return w.Flush()
+}
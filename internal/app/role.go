@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
+)
+
+// SetRole runs the role command, which sets the role of a user by nickname.
+// It is how the first admin is made, since only admins may change roles through the API.
+func (app *App) SetRole(args []string) error {
+	const op = "app.SetRole"
+	ctx := context.Background()
+
+	if len(args) != 2 {
+		return fmt.Errorf("%s: expected a nickname and a role", op)
+	}
+
+	nickname, role := args[0], model.Role(args[1])
+	if !role.Valid() {
+		return fmt.Errorf("%s: %w %q", op, model.ErrInvalidRole, role)
+	}
+
+	db, err := app.connectDB(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = db.Close(ctx) }()
+
+	repo := sqliterepo.NewUser(app.logger, db)
+
+	user, err := repo.GetByNickname(ctx, nickname)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := repo.Update(ctx, user.ID, repository.UpdateUserDTO{Role: &role}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	app.logger.Info("user role set", "nickname", nickname, "role", role)
+
+	return nil
+}
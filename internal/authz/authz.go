@@ -0,0 +1,166 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/ctxstore"
+	"github.com/protomem/gotube/internal/model"
+)
+
+type Action string
+
+const (
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionManage Action = "manage"
+	// ActionCurate is pinning and hearting the comments of a video, which speaks for its author.
+	ActionCurate Action = "curate"
+)
+
+type Resource string
+
+const (
+	ResourceUser    Resource = "user"
+	ResourceVideo   Resource = "video"
+	ResourceComment Resource = "comment"
+	ResourceMedia   Resource = "media"
+)
+
+type Object struct {
+	Resource Resource
+	OwnerID  model.ID
+}
+
+func UserObject(user model.User) Object {
+	return Object{Resource: ResourceUser, OwnerID: user.ID}
+}
+
+func VideoObject(video model.Video) Object {
+	return Object{Resource: ResourceVideo, OwnerID: video.Author.ID}
+}
+
+func CommentObject(comment model.Comment) Object {
+	return Object{Resource: ResourceComment, OwnerID: comment.Author.ID}
+}
+
+func MediaObject(ownerID model.ID) Object {
+	return Object{Resource: ResourceMedia, OwnerID: ownerID}
+}
+
+// MediaParentObject treats the parent folder of a media file as the ID of its owner.
+func MediaParentObject(parent string) Object {
+	ownerID, err := uuid.Parse(parent)
+	if err != nil {
+		ownerID = uuid.Nil
+	}
+	return MediaObject(ownerID)
+}
+
+type Policy interface {
+	Allow(actor model.User, action Action, obj Object) bool
+}
+
+type PolicyFunc func(actor model.User, action Action, obj Object) bool
+
+func (fn PolicyFunc) Allow(actor model.User, action Action, obj Object) bool {
+	return fn(actor, action, obj)
+}
+
+// OwnerOnly allows any action only to the owner of the object.
+func OwnerOnly() Policy {
+	return PolicyFunc(func(actor model.User, _ Action, obj Object) bool {
+		return actor.ID == obj.OwnerID
+	})
+}
+
+// HasRole allows any action to users with one of the given roles.
+func HasRole(roles ...model.Role) Policy {
+	return PolicyFunc(func(actor model.User, _ Action, _ Object) bool {
+		for _, role := range roles {
+			if actor.Role == role {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ForActions restricts the policy to the given actions.
+func ForActions(policy Policy, actions ...Action) Policy {
+	return PolicyFunc(func(actor model.User, action Action, obj Object) bool {
+		for _, a := range actions {
+			if a == action {
+				return policy.Allow(actor, action, obj)
+			}
+		}
+		return false
+	})
+}
+
+// AnyOf allows the action if at least one of the policies allows it.
+func AnyOf(policies ...Policy) Policy {
+	return PolicyFunc(func(actor model.User, action Action, obj Object) bool {
+		for _, policy := range policies {
+			if policy.Allow(actor, action, obj) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+type Authorizer struct {
+	policies map[Resource]Policy
+}
+
+func New() *Authorizer {
+	return &Authorizer{
+		policies: map[Resource]Policy{
+			ResourceUser:    AnyOf(ForActions(OwnerOnly(), ActionUpdate, ActionDelete), HasRole(model.RoleAdmin)),
+			ResourceVideo:   staffModerated(),
+			ResourceComment: authorEdited(),
+			ResourceMedia:   staffModerated(),
+		},
+	}
+}
+
+// staffModerated lets owners do anything, admins anything but curate and moderators delete.
+func staffModerated() Policy {
+	return AnyOf(
+		OwnerOnly(),
+		ForActions(HasRole(model.RoleAdmin), ActionUpdate, ActionDelete, ActionManage),
+		ForActions(HasRole(model.RoleModerator), ActionDelete),
+	)
+}
+
+// authorEdited lets only owners edit, so nobody else puts words in their mouth, while staff may delete.
+func authorEdited() Policy {
+	return AnyOf(
+		OwnerOnly(),
+		ForActions(HasRole(model.RoleAdmin, model.RoleModerator), ActionDelete),
+	)
+}
+
+func (a *Authorizer) SetPolicy(resource Resource, policy Policy) {
+	a.policies[resource] = policy
+}
+
+// Authorize checks that the user stored in the context may perform the action on the object.
+// Unknown resources are denied.
+func (a *Authorizer) Authorize(ctx context.Context, action Action, obj Object) error {
+	const op = "authz.Authorize"
+
+	actor, ok := ctxstore.User(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, model.ErrForbidden)
+	}
+
+	policy, ok := a.policies[obj.Resource]
+	if !ok || !policy.Allow(actor, action, obj) {
+		return fmt.Errorf("%s: %s %s: %w", op, action, obj.Resource, model.ErrForbidden)
+	}
+
+	return nil
+}
@@ -3,11 +3,18 @@ package filesystem
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/protomem/gotube/internal/blobstore"
 	"github.com/protomem/gotube/pkg/logging"
@@ -15,9 +22,33 @@ import (
 
 var _ blobstore.Storage = (*Storage)(nil)
 
+// Layout of the base folder. Names start with a dot, so they never clash with parents of the legacy layout.
+const (
+	_objectsFolder = ".objects"
+	_refsFolder    = ".refs"
+	_tmpFolder     = ".tmp"
+)
+
+// ref is the content of an index file, it links a parent/name to the object holding its data.
+type ref struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+	// Type is the content type given on Put, refs written before it was kept resolve it by extension.
+	Type string `json:"type,omitempty"`
+}
+
+// Storage keeps every distinct content once, under its SHA-256, and maps names to
+// contents with small index files. Writes go to a temporary file which is synced
+// and renamed into place, so readers never see partially written data.
+//
+// Reference counts are rebuilt from the index on start, which keeps them consistent
+// with the index after a crash.
 type Storage struct {
 	logger     logging.Logger
 	baseFolder string
+
+	mux  sync.Mutex
+	refs map[string]int
 }
 
 func New(logger logging.Logger, folder string) (*Storage, error) {
@@ -31,10 +62,17 @@ func New(logger logging.Logger, folder string) (*Storage, error) {
 	s := &Storage{
 		logger:     logger.With("component", "filesystem/blobstore"),
 		baseFolder: baseFolder,
+		refs:       make(map[string]int),
 	}
 
-	if err := s.initFolder(s.baseFolder); err != nil {
-		return nil, fmt.Errorf("%s: init base folder: %w", op, err)
+	for _, folder := range []string{s.baseFolder, s.objectsFolder(), s.refsFolder(), s.tmpFolder()} {
+		if err := s.initFolder(folder); err != nil {
+			return nil, fmt.Errorf("%s: init folder: %w", op, err)
+		}
+	}
+
+	if err := s.recover(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return s, nil
@@ -43,58 +81,180 @@ func New(logger logging.Logger, folder string) (*Storage, error) {
 func (s *Storage) Get(ctx context.Context, folder, filename string) (blobstore.Object, error) {
 	const op = "blobstore.Get"
 
-	folder = s.fmtFolder(folder)
-	filename = s.fmtFilename(folder, filename)
-
-	s.logger.Debug("get object", "filename", filename)
+	s.logger.Debug("get object", "folder", folder, "filename", filename)
 
-	file, err := os.ReadFile(filename)
+	r, err := s.readRef(folder, filename)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return blobstore.Object{}, fmt.Errorf("%s: %w", op, blobstore.ErrObjectNotFound)
-		}
+		return blobstore.Object{}, fmt.Errorf("%s: %w", op, err)
+	}
 
+	data, err := os.ReadFile(s.objectPath(r.Hash))
+	if err != nil {
 		return blobstore.Object{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != r.Hash {
+		s.logger.Error("object is corrupted", "hash", r.Hash, "folder", folder, "filename", filename)
+		return blobstore.Object{}, fmt.Errorf("%s: %w", op, blobstore.ErrChecksumMismatch)
+	}
+
 	return blobstore.Object{
-		Type: s.resolveType(filename),
-		Size: int64(len(file)),
-		Body: io.NopCloser(bytes.NewBuffer(file)),
+		Type: s.resolveType(r, filename),
+		Size: int64(len(data)),
+		Body: io.NopCloser(bytes.NewBuffer(data)),
+	}, nil
+}
+
+func (s *Storage) Open(ctx context.Context, folder, filename string) (blobstore.ObjectFile, error) {
+	const op = "blobstore.Open"
+
+	s.logger.Debug("open object", "folder", folder, "filename", filename)
+
+	r, err := s.readRef(folder, filename)
+	if err != nil {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	refInfo, err := os.Stat(s.refPath(folder, filename))
+	if err != nil {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	file, err := os.Open(s.objectPath(r.Hash))
+	if err != nil {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A truncated object is caught right away, other damage once the object is read through.
+	if info.Size() != r.Size {
+		_ = file.Close()
+		s.logger.Error("object is corrupted", "hash", r.Hash, "folder", folder, "filename", filename)
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, blobstore.ErrChecksumMismatch)
+	}
+
+	return blobstore.ObjectFile{
+		Type:    s.resolveType(r, filename),
+		Size:    r.Size,
+		ModTime: refInfo.ModTime(),
+		Body:    newVerifyingFile(s.logger, file, r),
 	}, nil
 }
 
 func (s *Storage) Put(ctx context.Context, folder, filename string, obj blobstore.Object) error {
 	const op = "blobstore.Put"
 
-	folder = s.fmtFolder(folder)
-	filename = s.fmtFilename(folder, filename)
+	s.logger.Debug("put object", "folder", folder, "filename", filename)
 
-	s.logger.Debug("put object", "filename", filename)
-
-	if err := s.initFolder(folder); err != nil {
-		return fmt.Errorf("%s: init folder: %w", op, err)
+	if !validName(folder) || !validName(filename) {
+		return fmt.Errorf("%s: invalid object name %q/%q", op, folder, filename)
 	}
 
-	file, err := os.Create(filename)
+	// The content is hashed while it is written, so it is read only once.
+	tmpPath, r, err := s.writeTemp(obj)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if !s.hasObject(r) {
+		if err := s.commitObject(tmpPath, r.Hash); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	oldRef, err := s.readRef(folder, filename)
+	if err != nil && !errors.Is(err, blobstore.ErrObjectNotFound) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	hadRef := err == nil
 
-	if _, err := io.CopyN(file, obj.Body, obj.Size); err != nil {
+	if err := s.writeRef(folder, filename, r); err != nil {
+		if s.refs[r.Hash] == 0 {
+			_ = s.removeObject(r.Hash)
+		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.refs[r.Hash]++
+	if hadRef {
+		if err := s.release(oldRef.Hash); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	return nil
 }
 
 func (s *Storage) Del(ctx context.Context, folder, filename string) error {
 	const op = "blobstore.Del"
 
-	folder = s.fmtFolder(folder)
-	filename = s.fmtFilename(folder, filename)
+	s.logger.Debug("delete object", "folder", folder, "filename", filename)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	r, err := s.readRef(folder, filename)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Remove(s.refPath(folder, filename)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.release(r.Hash); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Walk(ctx context.Context, fn func(info blobstore.ObjectInfo) error) error {
+	const op = "blobstore.Walk"
+
+	if err := filepath.WalkDir(s.refsFolder(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.refsFolder(), path)
+		if err != nil {
+			return err
+		}
+
+		folder, filename := filepath.Split(rel)
+		folder = filepath.Clean(folder)
+
+		r, err := s.readRef(folder, filename)
+		if err != nil {
+			return err
+		}
 
-	if err := os.Remove(filename); err != nil {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return fn(blobstore.ObjectInfo{
+			Parent:  folder,
+			Name:    filename,
+			Size:    r.Size,
+			ModTime: info.ModTime(),
+		})
+	}); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -105,19 +265,273 @@ func (s *Storage) Close(_ context.Context) error {
 	return nil
 }
 
+// release drops a reference to the object and removes the object once nothing refers to it.
+func (s *Storage) release(hash string) error {
+	s.refs[hash]--
+	if s.refs[hash] > 0 {
+		return nil
+	}
+
+	delete(s.refs, hash)
+
+	return s.removeObject(hash)
+}
+
+func (s *Storage) writeTemp(obj blobstore.Object) (string, ref, error) {
+	file, err := os.CreateTemp(s.tmpFolder(), "object-*")
+	if err != nil {
+		return "", ref{}, err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(file, hash), obj.Body, obj.Size); err != nil {
+		_ = os.Remove(file.Name())
+		return "", ref{}, err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = os.Remove(file.Name())
+		return "", ref{}, err
+	}
+
+	return file.Name(), ref{Hash: hex.EncodeToString(hash.Sum(nil)), Size: obj.Size, Type: obj.Type}, nil
+}
+
+// hasObject reports whether the content is already stored, replacing an object lost or truncated on disk.
+func (s *Storage) hasObject(r ref) bool {
+	if s.refs[r.Hash] == 0 {
+		return false
+	}
+
+	info, err := os.Stat(s.objectPath(r.Hash))
+	return err == nil && info.Size() == r.Size
+}
+
+func (s *Storage) commitObject(tmpPath, hash string) error {
+	path := s.objectPath(hash)
+
+	if err := s.initFolder(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	return syncFolder(filepath.Dir(path))
+}
+
+func (s *Storage) removeObject(hash string) error {
+	if err := os.Remove(s.objectPath(hash)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) readRef(folder, filename string) (ref, error) {
+	if !validName(folder) || !validName(filename) {
+		return ref{}, blobstore.ErrObjectNotFound
+	}
+
+	data, err := os.ReadFile(s.refPath(folder, filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ref{}, blobstore.ErrObjectNotFound
+		}
+
+		return ref{}, err
+	}
+
+	var r ref
+	if err := json.Unmarshal(data, &r); err != nil {
+		return ref{}, fmt.Errorf("decode ref %s/%s: %w", folder, filename, err)
+	}
+
+	return r, nil
+}
+
+func (s *Storage) writeRef(folder, filename string, r ref) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	path := s.refPath(folder, filename)
+	if err := s.initFolder(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(s.tmpFolder(), "ref-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+
+	return syncFolder(filepath.Dir(path))
+}
+
+// recover drops leftovers of interrupted writes, counts references and moves files
+// of the legacy layout into the store.
+func (s *Storage) recover() error {
+	if err := clearFolder(s.tmpFolder()); err != nil {
+		return fmt.Errorf("clear temp folder: %w", err)
+	}
+
+	if err := filepath.WalkDir(s.refsFolder(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.refsFolder(), path)
+		if err != nil {
+			return err
+		}
+
+		folder, filename := filepath.Split(rel)
+		r, err := s.readRef(filepath.Clean(folder), filename)
+		if err != nil {
+			return err
+		}
+
+		s.refs[r.Hash]++
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("count refs: %w", err)
+	}
+
+	// Imported files are counted by Put.
+	if err := s.importLegacy(); err != nil {
+		return fmt.Errorf("import legacy files: %w", err)
+	}
+
+	// Objects committed right before a crash may have never got a ref.
+	if err := filepath.WalkDir(s.objectsFolder(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		if s.refs[d.Name()] == 0 {
+			s.logger.Warn("remove unreferenced object", "hash", d.Name())
+			return os.Remove(path)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("remove unreferenced objects: %w", err)
+	}
+
+	return nil
+}
+
+// importLegacy moves files stored as baseFolder/parent/name by earlier versions into the store.
+func (s *Storage) importLegacy() error {
+	parents, err := os.ReadDir(s.baseFolder)
+	if err != nil {
+		return err
+	}
+
+	for _, parent := range parents {
+		if !parent.IsDir() || strings.HasPrefix(parent.Name(), ".") {
+			continue
+		}
+
+		folder := filepath.Join(s.baseFolder, parent.Name())
+
+		files, err := os.ReadDir(folder)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if !file.Type().IsRegular() {
+				continue
+			}
+
+			if err := s.importLegacyFile(parent.Name(), file.Name()); err != nil {
+				return err
+			}
+		}
+
+		// Fails on purpose while something else is left in the folder.
+		_ = os.Remove(folder)
+	}
+
+	return nil
+}
+
+func (s *Storage) importLegacyFile(folder, filename string) error {
+	path := filepath.Join(s.baseFolder, folder, filename)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := s.Put(context.Background(), folder, filename, blobstore.Object{
+		Size: info.Size(),
+		Body: file,
+	}); err != nil {
+		return err
+	}
+
+	s.logger.Info("imported legacy object", "folder", folder, "filename", filename)
+
+	return os.Remove(path)
+}
+
 func (*Storage) initFolder(folder string) error {
 	return os.MkdirAll(folder, 0700)
 }
 
-func (s *Storage) fmtFolder(folder string) string {
-	return filepath.Join(s.baseFolder, folder)
+func (s *Storage) objectsFolder() string {
+	return filepath.Join(s.baseFolder, _objectsFolder)
+}
+
+func (s *Storage) refsFolder() string {
+	return filepath.Join(s.baseFolder, _refsFolder)
+}
+
+func (s *Storage) tmpFolder() string {
+	return filepath.Join(s.baseFolder, _tmpFolder)
 }
 
-func (*Storage) fmtFilename(folder, filename string) string {
-	return filepath.Join(folder, filename)
+// objectPath fans objects out by the first byte of the hash to keep folders small.
+func (s *Storage) objectPath(hash string) string {
+	return filepath.Join(s.objectsFolder(), hash[:2], hash)
 }
 
-func (*Storage) resolveType(filename string) string {
+func (s *Storage) refPath(folder, filename string) string {
+	return filepath.Join(s.refsFolder(), folder, filename)
+}
+
+func (*Storage) resolveType(r ref, filename string) string {
+	if r.Type != "" {
+		return r.Type
+	}
+
 	ext := filepath.Ext(filename)
 	switch strings.TrimPrefix(strings.ToLower(ext), ".") {
 	case "jpg", "jpeg":
@@ -126,7 +540,44 @@ func (*Storage) resolveType(filename string) string {
 		return "image/png"
 	case "mp4":
 		return "video/mp4"
-	default:
-		return "application/octet-stream"
+	case "m3u8":
+		return "application/vnd.apple.mpegurl"
+	case "ts":
+		return "video/mp2t"
+	}
+
+	if typ := mime.TypeByExtension(ext); typ != "" {
+		return typ
+	}
+
+	return "application/octet-stream"
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func syncFolder(folder string) error {
+	dir, err := os.Open(folder)
+	if err != nil {
+		return err
 	}
+	defer func() { _ = dir.Close() }()
+
+	return dir.Sync()
+}
+
+func clearFolder(folder string) error {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(folder, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,80 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"os"
+
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+// verifyingFile checks the content against its hash when the whole object is read
+// from the start to the end. Reads after a seek to the middle, like range requests,
+// can not be verified and are passed through as is.
+type verifyingFile struct {
+	logger logging.Logger
+	file   *os.File
+	ref    ref
+
+	hash     hash.Hash
+	pos      int64
+	verified bool
+	skip     bool
+}
+
+func newVerifyingFile(logger logging.Logger, file *os.File, r ref) *verifyingFile {
+	return &verifyingFile{
+		logger: logger,
+		file:   file,
+		ref:    r,
+		hash:   sha256.New(),
+	}
+}
+
+func (f *verifyingFile) Read(p []byte) (int, error) {
+	n, err := f.file.Read(p)
+
+	if !f.skip {
+		_, _ = f.hash.Write(p[:n])
+	}
+	f.pos += int64(n)
+
+	// Readers limited to the object size, like io.CopyN, never get to EOF.
+	if !f.skip && !f.verified && f.pos == f.ref.Size {
+		f.verified = true
+
+		if hex.EncodeToString(f.hash.Sum(nil)) != f.ref.Hash {
+			f.logger.Error("object is corrupted", "hash", f.ref.Hash)
+			// Holding back the last chunk makes the transfer visibly incomplete.
+			return 0, blobstore.ErrChecksumMismatch
+		}
+	}
+
+	return n, err
+}
+
+func (f *verifyingFile) Seek(offset int64, whence int) (int64, error) {
+	pos, err := f.file.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+
+	switch {
+	case pos == 0:
+		// Back at the start, e.g. after the size was found by seeking to the end.
+		f.hash.Reset()
+		f.skip = false
+		f.verified = false
+	case pos != f.pos:
+		f.skip = true
+	}
+	f.pos = pos
+
+	return pos, nil
+}
+
+func (f *verifyingFile) Close() error {
+	return f.file.Close()
+}
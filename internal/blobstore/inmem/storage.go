@@ -1,9 +1,13 @@
 package inmem
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/protomem/gotube/internal/blobstore"
 	"github.com/protomem/gotube/pkg/logging"
@@ -11,17 +15,23 @@ import (
 
 var _ blobstore.Storage = (*Storage)(nil)
 
+type entry struct {
+	typ     string
+	data    []byte
+	modTime time.Time
+}
+
 type Storage struct {
 	logger logging.Logger
 
 	mux   sync.RWMutex
-	store map[string]blobstore.Object
+	store map[string]entry
 }
 
 func New(logger logging.Logger) (*Storage, error) {
 	return &Storage{
 		logger: logger.With("component", "in-memory/blobstore"),
-		store:  make(map[string]blobstore.Object),
+		store:  make(map[string]entry),
 	}, nil
 }
 
@@ -33,39 +43,105 @@ func (s *Storage) Get(ctx context.Context, parent, name string) (blobstore.Objec
 
 	s.logger.WithContext(ctx).Debug("get object", "parent", parent, "name", name)
 
-	obj, ok := s.store[s.fmtKey(parent, name)]
+	e, ok := s.store[s.fmtKey(parent, name)]
 	if !ok {
 		return blobstore.Object{}, fmt.Errorf("%s: %w", op, blobstore.ErrObjectNotFound)
 	}
 
-	return obj, nil
+	return blobstore.Object{
+		Type: e.typ,
+		Size: int64(len(e.data)),
+		Body: bytes.NewReader(e.data),
+	}, nil
+}
+
+func (s *Storage) Open(ctx context.Context, parent, name string) (blobstore.ObjectFile, error) {
+	const op = "blobstore.Open"
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	s.logger.WithContext(ctx).Debug("open object", "parent", parent, "name", name)
+
+	e, ok := s.store[s.fmtKey(parent, name)]
+	if !ok {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, blobstore.ErrObjectNotFound)
+	}
+
+	return blobstore.ObjectFile{
+		Type:    e.typ,
+		Size:    int64(len(e.data)),
+		ModTime: e.modTime,
+		Body:    nopCloser{bytes.NewReader(e.data)},
+	}, nil
 }
 
 func (s *Storage) Put(ctx context.Context, parent, name string, obj blobstore.Object) error {
 	const op = "blobstore.Put"
 
-	copyObj, err := obj.Clone()
-	if err != nil {
+	data := make([]byte, obj.Size)
+	if _, err := io.ReadFull(obj.Body, data); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	s.logger.WithContext(ctx).Debug("put object", "parent", parent, "name", name, "objSize", copyObj.Size, "objType", copyObj.Type)
+	s.logger.WithContext(ctx).Debug("put object", "parent", parent, "name", name, "objSize", obj.Size, "objType", obj.Type)
 
-	s.store[s.fmtKey(parent, name)] = copyObj
+	s.store[s.fmtKey(parent, name)] = entry{
+		typ:     obj.Type,
+		data:    data,
+		modTime: time.Now(),
+	}
 
 	return nil
 }
 
 func (s *Storage) Del(ctx context.Context, parent, name string) error {
+	const op = "blobstore.Del"
+
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
 	s.logger.WithContext(ctx).Debug("delete object", "parent", parent, "name", name)
 
-	delete(s.store, s.fmtKey(parent, name))
+	key := s.fmtKey(parent, name)
+	if _, ok := s.store[key]; !ok {
+		return fmt.Errorf("%s: %w", op, blobstore.ErrObjectNotFound)
+	}
+
+	delete(s.store, key)
+
+	return nil
+}
+
+func (s *Storage) Walk(ctx context.Context, fn func(info blobstore.ObjectInfo) error) error {
+	const op = "blobstore.Walk"
+
+	// fn runs on a snapshot, so it may change the storage.
+	s.mux.RLock()
+	infos := make([]blobstore.ObjectInfo, 0, len(s.store))
+	for key, e := range s.store {
+		parent, name, _ := strings.Cut(key, "/")
+		infos = append(infos, blobstore.ObjectInfo{
+			Parent:  parent,
+			Name:    name,
+			Size:    int64(len(e.data)),
+			ModTime: e.modTime,
+		})
+	}
+	s.mux.RUnlock()
+
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := fn(info); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
 	return nil
 }
@@ -74,7 +150,7 @@ func (s *Storage) Close(_ context.Context) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
-	s.store = make(map[string]blobstore.Object)
+	s.store = make(map[string]entry)
 
 	return nil
 }
@@ -82,3 +158,11 @@ func (s *Storage) Close(_ context.Context) error {
 func (s *Storage) fmtKey(parent, name string) string {
 	return fmt.Sprintf("%s/%s", parent, name)
 }
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,353 @@
+// Package fakes3 is an in-memory stand-in for an S3 compatible service, for local runs and tests.
+// It supports path-style object requests, multipart uploads and ListObjectsV2, buckets are created on first use.
+package fakes3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/pkg/sigv4"
+)
+
+type object struct {
+	data    []byte
+	typ     string
+	etag    string
+	modTime time.Time
+}
+
+type upload struct {
+	key   string
+	typ   string
+	parts map[int]object
+}
+
+// Server is an http.Handler serving objects from memory.
+type Server struct {
+	// Signer verifies request signatures, requests are not checked if it has no credentials.
+	Signer sigv4.Signer
+
+	mux     sync.Mutex
+	objects map[string]object
+	uploads map[string]upload
+}
+
+func New(signer sigv4.Signer) *Server {
+	return &Server{
+		Signer:  signer,
+		objects: make(map[string]object),
+		uploads: make(map[string]upload),
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Signer.Credentials.AccessKey != "" {
+		if err := s.Signer.Verify(r, time.Now()); err != nil {
+			writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+			return
+		}
+	}
+
+	query := r.URL.Query()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "" && key == "" && r.Method == http.MethodGet && query.Get("list-type") == "2" {
+		s.listObjects(w, bucket, query)
+		return
+	}
+
+	if bucket == "" || key == "" {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", "expected /<bucket>/<key>")
+		return
+	}
+	key = bucket + "/" + key
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.createUpload(w, r, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		s.uploadPart(w, r, query)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.completeUpload(w, r, key, query.Get("uploadId"))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		s.abortUpload(w, query.Get("uploadId"))
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, key)
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		s.getObject(w, r, key)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, key)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "unsupported request")
+	}
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, key string) {
+	obj, err := readObject(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	s.mux.Lock()
+	s.objects[key] = obj
+	s.mux.Unlock()
+
+	w.Header().Set("ETag", obj.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	s.mux.Lock()
+	obj, ok := s.objects[key]
+	s.mux.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+		return
+	}
+
+	w.Header().Set("Content-Type", obj.typ)
+	w.Header().Set("ETag", obj.etag)
+
+	// ServeContent handles Range and HEAD like S3 does.
+	http.ServeContent(w, r, "", obj.modTime, bytes.NewReader(obj.data))
+}
+
+func (s *Server) deleteObject(w http.ResponseWriter, key string) {
+	s.mux.Lock()
+	delete(s.objects, key)
+	s.mux.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// _maxKeys is the default and the largest page of ListObjectsV2.
+const _maxKeys = 1000
+
+type listEntry struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	ETag         string    `xml:"ETag"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+// listObjects returns the keys of the bucket in order, the continuation token is the last key of the page.
+func (s *Server) listObjects(w http.ResponseWriter, bucket string, query url.Values) {
+	maxKeys := _maxKeys
+	if v := query.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid max-keys")
+			return
+		}
+		maxKeys = min(n, _maxKeys)
+	}
+
+	prefix := query.Get("prefix")
+	after := query.Get("continuation-token")
+	if after == "" {
+		after = query.Get("start-after")
+	}
+
+	s.mux.Lock()
+	entries := make([]listEntry, 0)
+	for key, obj := range s.objects {
+		key, ok := strings.CutPrefix(key, bucket+"/")
+		if !ok || !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		entries = append(entries, listEntry{
+			Key:          key,
+			Size:         int64(len(obj.data)),
+			ETag:         obj.etag,
+			LastModified: obj.modTime.UTC(),
+		})
+	}
+	s.mux.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	truncated := len(entries) > maxKeys
+	next := ""
+	if truncated {
+		entries = entries[:maxKeys]
+		next = entries[len(entries)-1].Key
+	}
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName               xml.Name    `xml:"ListBucketResult"`
+		Name                  string      `xml:"Name"`
+		Prefix                string      `xml:"Prefix"`
+		KeyCount              int         `xml:"KeyCount"`
+		MaxKeys               int         `xml:"MaxKeys"`
+		IsTruncated           bool        `xml:"IsTruncated"`
+		NextContinuationToken string      `xml:"NextContinuationToken,omitempty"`
+		Contents              []listEntry `xml:"Contents"`
+	}{
+		Name:                  bucket,
+		Prefix:                prefix,
+		KeyCount:              len(entries),
+		MaxKeys:               maxKeys,
+		IsTruncated:           truncated,
+		NextContinuationToken: next,
+		Contents:              entries,
+	})
+}
+
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request, key string) {
+	id := uuid.NewString()
+
+	s.mux.Lock()
+	s.uploads[id] = upload{key: key, typ: r.Header.Get("Content-Type"), parts: make(map[int]object)}
+	s.mux.Unlock()
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+		Key      string   `xml:"Key"`
+		UploadID string   `xml:"UploadId"`
+	}{Key: key, UploadID: id})
+}
+
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, query url.Values) {
+	number, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || number < 1 {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", "invalid part number")
+		return
+	}
+
+	part, err := readObject(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	s.mux.Lock()
+	up, ok := s.uploads[query.Get("uploadId")]
+	if ok {
+		up.parts[number] = part
+	}
+	s.mux.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+
+	w.Header().Set("ETag", part.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) completeUpload(w http.ResponseWriter, r *http.Request, key, id string) {
+	var req struct {
+		Parts []struct {
+			PartNumber int    `xml:"PartNumber"`
+			ETag       string `xml:"ETag"`
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	up, ok := s.uploads[id]
+	if !ok || up.key != key {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "the specified upload does not exist")
+		return
+	}
+
+	if !sort.SliceIsSorted(req.Parts, func(i, j int) bool { return req.Parts[i].PartNumber < req.Parts[j].PartNumber }) {
+		writeError(w, http.StatusBadRequest, "InvalidPartOrder", "parts must be in ascending order")
+		return
+	}
+
+	var data []byte
+	for _, p := range req.Parts {
+		part, ok := up.parts[p.PartNumber]
+		if !ok || part.etag != p.ETag {
+			writeError(w, http.StatusBadRequest, "InvalidPart", fmt.Sprintf("part %d not found", p.PartNumber))
+			return
+		}
+		data = append(data, part.data...)
+	}
+
+	sum := md5.Sum(data)
+	obj := object{
+		data:    data,
+		typ:     up.typ,
+		etag:    fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(req.Parts)),
+		modTime: time.Now(),
+	}
+
+	s.objects[key] = obj
+	delete(s.uploads, id)
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Key     string   `xml:"Key"`
+		ETag    string   `xml:"ETag"`
+	}{Key: key, ETag: obj.etag})
+}
+
+func (s *Server) abortUpload(w http.ResponseWriter, id string) {
+	s.mux.Lock()
+	delete(s.uploads, id)
+	s.mux.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func readObject(r *http.Request) (object, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return object{}, err
+	}
+
+	if r.ContentLength >= 0 && int64(len(data)) != r.ContentLength {
+		return object{}, fmt.Errorf("read %d of %d bytes", len(data), r.ContentLength)
+	}
+
+	typ := r.Header.Get("Content-Type")
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+
+	sum := md5.Sum(data)
+
+	return object{
+		data:    data,
+		typ:     typ,
+		etag:    `"` + hex.EncodeToString(sum[:]) + `"`,
+		modTime: time.Now().Truncate(time.Second),
+	}, nil
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeXML(w, status, struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: message})
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(v)
+}
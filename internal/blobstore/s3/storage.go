@@ -0,0 +1,563 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/pkg/logging"
+	"github.com/protomem/gotube/pkg/sigv4"
+)
+
+var (
+	_ blobstore.Storage   = (*Storage)(nil)
+	_ blobstore.Presigner = (*Storage)(nil)
+)
+
+// _minPartSize is the smallest part S3 accepts for all but the last part of a multipart upload.
+const _minPartSize = 5 * 1024 * 1024
+
+type Options struct {
+	// Endpoint is the base URL of the service, e.g. https://s3.eu-central-1.amazonaws.com.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle puts the bucket into the path instead of the host name, as MinIO expects.
+	PathStyle bool
+	// Prefix is prepended to every key, so several apps can share a bucket.
+	Prefix string
+
+	// PartSize is the size of multipart upload parts, larger objects are uploaded in parts.
+	PartSize int64
+	// PresignTTL is how long presigned URLs stay valid.
+	PresignTTL time.Duration
+
+	Client *http.Client
+}
+
+// Storage keeps objects in an S3 compatible service under parent/name keys.
+type Storage struct {
+	logger   logging.Logger
+	opts     Options
+	endpoint *url.URL
+	signer   sigv4.Signer
+	client   *http.Client
+}
+
+func New(logger logging.Logger, opts Options) (*Storage, error) {
+	const op = "blobstore.New"
+
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%s: parse endpoint: %w", op, err)
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%s: invalid endpoint %q", op, opts.Endpoint)
+	}
+
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("%s: missing bucket", op)
+	}
+
+	opts.PartSize = max(opts.PartSize, _minPartSize)
+
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Storage{
+		logger:   logger.With("component", "s3/blobstore"),
+		opts:     opts,
+		endpoint: endpoint,
+		signer: sigv4.Signer{
+			Credentials: sigv4.Credentials{AccessKey: opts.AccessKey, SecretKey: opts.SecretKey},
+			Region:      opts.Region,
+			Service:     "s3",
+		},
+		client: client,
+	}, nil
+}
+
+func (s *Storage) Get(ctx context.Context, parent, name string) (blobstore.Object, error) {
+	const op = "blobstore.Get"
+
+	s.logger.Debug("get object", "parent", parent, "name", name)
+
+	res, err := s.do(ctx, http.MethodGet, s.key(parent, name), nil, nil, nil, sigv4.EmptyPayload)
+	if err != nil {
+		return blobstore.Object{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return blobstore.Object{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return blobstore.Object{
+		Type: res.Header.Get("Content-Type"),
+		Size: int64(len(data)),
+		Body: bytes.NewReader(data),
+	}, nil
+}
+
+func (s *Storage) Open(ctx context.Context, parent, name string) (blobstore.ObjectFile, error) {
+	const op = "blobstore.Open"
+
+	s.logger.Debug("open object", "parent", parent, "name", name)
+
+	key := s.key(parent, name)
+
+	res, err := s.do(ctx, http.MethodHead, key, nil, nil, nil, sigv4.EmptyPayload)
+	if err != nil {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	_ = res.Body.Close()
+
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+
+	return blobstore.ObjectFile{
+		Type:    res.Header.Get("Content-Type"),
+		Size:    res.ContentLength,
+		ModTime: modTime,
+		Body:    &rangeReader{ctx: ctx, storage: s, key: key, size: res.ContentLength},
+	}, nil
+}
+
+func (s *Storage) Put(ctx context.Context, parent, name string, obj blobstore.Object) error {
+	const op = "blobstore.Put"
+
+	s.logger.Debug("put object", "parent", parent, "name", name, "size", obj.Size)
+
+	key := s.key(parent, name)
+
+	contentType := obj.Type
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	var err error
+	if obj.Size <= s.opts.PartSize {
+		err = s.putSingle(ctx, key, contentType, obj)
+	} else {
+		err = s.putMultipart(ctx, key, contentType, obj)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Del(ctx context.Context, parent, name string) error {
+	const op = "blobstore.Del"
+
+	s.logger.Debug("delete object", "parent", parent, "name", name)
+
+	key := s.key(parent, name)
+
+	// S3 reports success for missing keys, the check keeps the contract of other storages.
+	res, err := s.do(ctx, http.MethodHead, key, nil, nil, nil, sigv4.EmptyPayload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_ = res.Body.Close()
+
+	res, err = s.do(ctx, http.MethodDelete, key, nil, nil, nil, sigv4.EmptyPayload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_ = res.Body.Close()
+
+	return nil
+}
+
+// PresignGet returns a URL which downloads the object without credentials until it expires.
+func (s *Storage) PresignGet(_ context.Context, parent, name string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(s.key(parent, name), nil).String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("blobstore.PresignGet: %w", err)
+	}
+
+	return s.signer.Presign(req, s.opts.PresignTTL, time.Now()).String(), nil
+}
+
+// Walk lists the objects under the prefix page by page, keys which are not parent/name are skipped.
+func (s *Storage) Walk(ctx context.Context, fn func(info blobstore.ObjectInfo) error) error {
+	const op = "blobstore.Walk"
+
+	prefix := ""
+	if s.opts.Prefix != "" {
+		prefix = strings.Trim(s.opts.Prefix, "/") + "/"
+	}
+
+	token := ""
+	for {
+		page, err := s.listObjects(ctx, prefix, token)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, obj := range page.Contents {
+			parent, name, ok := strings.Cut(strings.TrimPrefix(obj.Key, prefix), "/")
+			if !ok || parent == "" || name == "" || strings.Contains(name, "/") {
+				continue
+			}
+
+			if err := fn(blobstore.ObjectInfo{
+				Parent:  parent,
+				Name:    name,
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+			}); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *Storage) Close(_ context.Context) error {
+	return nil
+}
+
+func (s *Storage) putSingle(ctx context.Context, key, contentType string, obj blobstore.Object) error {
+	header := http.Header{"Content-Type": {contentType}}
+
+	res, err := s.do(ctx, http.MethodPut, key, nil, header, io.LimitReader(obj.Body, obj.Size), sigv4.UnsignedPayload, obj.Size)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *Storage) listObjects(ctx context.Context, prefix, token string) (listBucketResult, error) {
+	query := url.Values{"list-type": {"2"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+
+	res, err := s.do(ctx, http.MethodGet, "", query, nil, nil, sigv4.EmptyPayload)
+	if err != nil {
+		return listBucketResult{}, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	var result listBucketResult
+	if err := xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return listBucketResult{}, fmt.Errorf("decode list objects: %w", err)
+	}
+
+	return result, nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// putMultipart uploads the object in parts of PartSize, each part is buffered and signed with its hash.
+func (s *Storage) putMultipart(ctx context.Context, key, contentType string, obj blobstore.Object) error {
+	uploadID, err := s.createMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return err
+	}
+
+	parts, err := s.uploadParts(ctx, key, uploadID, obj)
+	if err == nil {
+		err = s.completeMultipartUpload(ctx, key, uploadID, parts)
+	}
+
+	if err != nil {
+		if abortErr := s.abortMultipartUpload(key, uploadID); abortErr != nil {
+			s.logger.Warn("failed to abort multipart upload", "key", key, "uploadId", uploadID, "err", abortErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) createMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	header := http.Header{"Content-Type": {contentType}}
+
+	res, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil, sigv4.EmptyPayload)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode create multipart upload: %w", err)
+	}
+
+	return result.UploadID, nil
+}
+
+func (s *Storage) uploadParts(ctx context.Context, key, uploadID string, obj blobstore.Object) ([]completedPart, error) {
+	parts := make([]completedPart, 0, obj.Size/s.opts.PartSize+1)
+	buf := make([]byte, s.opts.PartSize)
+
+	for remaining, number := obj.Size, 1; remaining > 0; number++ {
+		n, err := io.ReadFull(obj.Body, buf[:min(remaining, s.opts.PartSize)])
+		if err != nil {
+			return nil, err
+		}
+		remaining -= int64(n)
+
+		sum := sha256.Sum256(buf[:n])
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+
+		res, err := s.do(ctx, http.MethodPut, key, query, nil, bytes.NewReader(buf[:n]), hex.EncodeToString(sum[:]), int64(n))
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", number, err)
+		}
+		_ = res.Body.Close()
+
+		parts = append(parts, completedPart{PartNumber: number, ETag: res.Header.Get("ETag")})
+	}
+
+	return parts, nil
+}
+
+func (s *Storage) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	query := url.Values{"uploadId": {uploadID}}
+
+	res, err := s.do(ctx, http.MethodPost, key, query, nil, bytes.NewReader(body), hex.EncodeToString(sum[:]), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	// S3 may report a failure with 200 OK once it has started to respond.
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := parseError(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// abortMultipartUpload uses a fresh context, the upload has to be cleaned up even when the request was canceled.
+func (s *Storage) abortMultipartUpload(key, uploadID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil, sigv4.EmptyPayload)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+
+	return nil
+}
+
+// do sends a signed request and turns error responses into errors.
+// The size is required for requests with a body.
+func (s *Storage) do(
+	ctx context.Context,
+	method, key string, query url.Values, header http.Header,
+	body io.Reader, payloadHash string, size ...int64,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if len(size) > 0 {
+		req.ContentLength = size[0]
+	}
+
+	s.signer.Sign(req, payloadHash, time.Now())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 300 {
+		defer func() { _ = res.Body.Close() }()
+
+		if res.StatusCode == http.StatusNotFound {
+			return nil, blobstore.ErrObjectNotFound
+		}
+
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+		if err := parseError(data); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, key, err)
+		}
+
+		return nil, fmt.Errorf("%s %s: unexpected status %s", method, key, res.Status)
+	}
+
+	return res, nil
+}
+
+func (s *Storage) key(parent, name string) string {
+	return strings.TrimPrefix(path.Join(s.opts.Prefix, parent, name), "/")
+}
+
+func (s *Storage) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+
+	if s.opts.PathStyle {
+		u.Path = path.Join("/", u.Path, s.opts.Bucket, key)
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = path.Join("/", u.Path, key)
+	}
+	u.RawPath = sigv4.EncodePath(u.Path)
+
+	if query != nil {
+		// Flags like "uploads" are sent without a value.
+		u.RawQuery = strings.ReplaceAll(query.Encode(), "=&", "&")
+		u.RawQuery = strings.TrimSuffix(u.RawQuery, "=")
+	}
+
+	return &u
+}
+
+type apiError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e apiError) Error() string {
+	return "s3: " + e.Code + ": " + e.Message
+}
+
+func parseError(data []byte) error {
+	var apiErr apiError
+	if err := xml.Unmarshal(data, &apiErr); err != nil || apiErr.Code == "" {
+		return nil
+	}
+
+	if apiErr.Code == "NoSuchKey" {
+		return blobstore.ErrObjectNotFound
+	}
+
+	return apiErr
+}
+
+// rangeReader reads the object with ranged GET requests, so seeking does not download skipped bytes.
+type rangeReader struct {
+	ctx     context.Context
+	storage *Storage
+	key     string
+	size    int64
+
+	pos  int64
+	body io.ReadCloser
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		header := http.Header{"Range": {fmt.Sprintf("bytes=%d-", r.pos)}}
+
+		res, err := r.storage.do(r.ctx, http.MethodGet, r.key, nil, header, nil, sigv4.EmptyPayload)
+		if err != nil {
+			return 0, err
+		}
+		r.body = res.Body
+	}
+
+	n, err := r.body.Read(p)
+	r.pos += int64(n)
+
+	return n, err
+}
+
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, errors.New("s3.rangeReader.Seek: invalid whence")
+	}
+
+	if pos < 0 {
+		return 0, errors.New("s3.rangeReader.Seek: negative position")
+	}
+
+	if pos != r.pos && r.body != nil {
+		_ = r.body.Close()
+		r.body = nil
+	}
+	r.pos = pos
+
+	return pos, nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/internal/blobstore/s3"
+	"github.com/protomem/gotube/internal/blobstore/s3/fakes3"
+	"github.com/protomem/gotube/pkg/logging/std"
+	"github.com/protomem/gotube/pkg/sigv4"
+)
+
+const (
+	testRegion    = "us-east-1"
+	testBucket    = "gotube"
+	testAccessKey = "access-key"
+	testSecretKey = "secret-key"
+
+	// partSize is the smallest part size the storage uses.
+	partSize = 5 * 1024 * 1024
+)
+
+// fakeServer is a fake S3 which verifies signatures and records the requests it receives.
+type fakeServer struct {
+	*httptest.Server
+
+	mux  sync.Mutex
+	reqs []*http.Request
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	fake := fakes3.New(sigv4.Signer{
+		Credentials: sigv4.Credentials{AccessKey: testAccessKey, SecretKey: testSecretKey},
+		Region:      testRegion,
+		Service:     "s3",
+	})
+
+	srv := &fakeServer{}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.mux.Lock()
+		srv.reqs = append(srv.reqs, r.Clone(context.Background()))
+		srv.mux.Unlock()
+
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// requests returns the recorded requests matching the method and carrying the query parameter, if one is given.
+func (srv *fakeServer) requests(method, param string) []*http.Request {
+	srv.mux.Lock()
+	defer srv.mux.Unlock()
+
+	reqs := make([]*http.Request, 0)
+	for _, r := range srv.reqs {
+		if r.Method == method && (param == "" || r.URL.Query().Has(param)) {
+			reqs = append(reqs, r)
+		}
+	}
+
+	return reqs
+}
+
+func (srv *fakeServer) storage(t *testing.T, prefix, secretKey string) *s3.Storage {
+	t.Helper()
+
+	logger, err := std.New("error", io.Discard)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+
+	storage, err := s3.New(logger, s3.Options{
+		Endpoint:   srv.URL,
+		Region:     testRegion,
+		Bucket:     testBucket,
+		AccessKey:  testAccessKey,
+		SecretKey:  secretKey,
+		PathStyle:  true,
+		Prefix:     prefix,
+		PresignTTL: time.Minute,
+		Client:     srv.Client(),
+	})
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+
+	return storage
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := newFakeServer(t).storage(t, "", testSecretKey)
+
+	data := []byte("hello, world")
+	put(t, storage, "folder", "hello.txt", "text/plain", data)
+
+	obj, err := storage.Get(ctx, "folder", "hello.txt")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if obj.Type != "text/plain" || obj.Size != int64(len(data)) {
+		t.Fatalf("got %s of %d bytes, want text/plain of %d bytes", obj.Type, obj.Size, len(data))
+	}
+	if content := readAll(t, obj.Body); !bytes.Equal(content, data) {
+		t.Fatalf("content = %q, want %q", content, data)
+	}
+
+	file, err := storage.Open(ctx, "folder", "hello.txt")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = file.Body.Close() }()
+
+	if file.Size != int64(len(data)) || file.ModTime.IsZero() {
+		t.Fatalf("opened %d bytes modified at %v, want %d bytes with a modification time", file.Size, file.ModTime, len(data))
+	}
+	if content := readAll(t, file.Body); !bytes.Equal(content, data) {
+		t.Fatalf("opened content = %q, want %q", content, data)
+	}
+
+	if _, err := storage.Get(ctx, "folder", "missing.txt"); !errors.Is(err, blobstore.ErrObjectNotFound) {
+		t.Fatalf("get missing = %v, want %v", err, blobstore.ErrObjectNotFound)
+	}
+	if _, err := storage.Open(ctx, "folder", "missing.txt"); !errors.Is(err, blobstore.ErrObjectNotFound) {
+		t.Fatalf("open missing = %v, want %v", err, blobstore.ErrObjectNotFound)
+	}
+
+	if err := storage.Del(ctx, "folder", "hello.txt"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := storage.Get(ctx, "folder", "hello.txt"); !errors.Is(err, blobstore.ErrObjectNotFound) {
+		t.Fatalf("get deleted = %v, want %v", err, blobstore.ErrObjectNotFound)
+	}
+
+	// S3 accepts deletes of missing keys, the storage reports them like the others do.
+	if err := storage.Del(ctx, "folder", "hello.txt"); !errors.Is(err, blobstore.ErrObjectNotFound) {
+		t.Fatalf("delete missing = %v, want %v", err, blobstore.ErrObjectNotFound)
+	}
+}
+
+func TestStorageSignsRequests(t *testing.T) {
+	srv := newFakeServer(t)
+
+	put(t, srv.storage(t, "", testSecretKey), "folder", "file.bin", "", []byte("data"))
+
+	for _, r := range srv.requests(http.MethodPut, "") {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), sigv4.Algorithm+" ") {
+			t.Fatalf("request is not signed: %q", r.Header.Get("Authorization"))
+		}
+	}
+
+	// The fake checks the signatures, a wrong secret is refused.
+	err := srv.storage(t, "", "wrong-secret").Put(context.Background(), "folder", "file.bin", blobstore.Object{
+		Size: 4,
+		Body: strings.NewReader("data"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("put with wrong secret = %v, want SignatureDoesNotMatch", err)
+	}
+}
+
+func TestStoragePutMultipart(t *testing.T) {
+	srv := newFakeServer(t)
+	storage := srv.storage(t, "", testSecretKey)
+
+	data := randomBytes(2*partSize + 1024)
+	put(t, storage, "folder", "video.mp4", "", data)
+
+	if n := len(srv.requests(http.MethodPut, "uploadId")); n != 3 {
+		t.Fatalf("uploaded %d parts, want 3", n)
+	}
+	if n := len(srv.requests(http.MethodPost, "uploadId")); n != 1 {
+		t.Fatalf("completed %d uploads, want 1", n)
+	}
+
+	obj, err := storage.Get(context.Background(), "folder", "video.mp4")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if obj.Type != "video/mp4" {
+		t.Fatalf("type = %s, want the one of the extension", obj.Type)
+	}
+	if content := readAll(t, obj.Body); !bytes.Equal(content, data) {
+		t.Fatal("multipart object differs from the one put")
+	}
+}
+
+func TestStorageOpenSeek(t *testing.T) {
+	srv := newFakeServer(t)
+	storage := srv.storage(t, "", testSecretKey)
+
+	data := randomBytes(4096)
+	put(t, storage, "folder", "file.bin", "", data)
+
+	file, err := storage.Open(context.Background(), "folder", "file.bin")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = file.Body.Close() }()
+
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(file.Body, head); err != nil {
+		t.Fatalf("read head: %v", err)
+	}
+	if !bytes.Equal(head, data[:16]) {
+		t.Fatal("head differs")
+	}
+
+	tests := []struct {
+		offset int64
+		whence int
+		want   int64
+	}{
+		{1000, io.SeekStart, 1000},
+		{-96, io.SeekEnd, 4000},
+		{-2000, io.SeekCurrent, 2064},
+	}
+
+	for _, tt := range tests {
+		pos, err := file.Body.Seek(tt.offset, tt.whence)
+		if err != nil {
+			t.Fatalf("seek %d from %d: %v", tt.offset, tt.whence, err)
+		}
+		if pos != tt.want {
+			t.Fatalf("seek %d from %d = %d, want %d", tt.offset, tt.whence, pos, tt.want)
+		}
+
+		chunk := make([]byte, 64)
+		if _, err := io.ReadFull(file.Body, chunk); err != nil {
+			t.Fatalf("read at %d: %v", pos, err)
+		}
+		if !bytes.Equal(chunk, data[pos:pos+64]) {
+			t.Fatalf("read at %d differs", pos)
+		}
+
+		// Only the bytes from the position on are requested.
+		reqs := srv.requests(http.MethodGet, "")
+		if rng := reqs[len(reqs)-1].Header.Get("Range"); rng != fmt.Sprintf("bytes=%d-", pos) {
+			t.Fatalf("range = %q, want from %d", rng, pos)
+		}
+	}
+
+	if _, err := file.Body.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("seek before start succeeded")
+	}
+}
+
+func TestStorageWalk(t *testing.T) {
+	const objects = 1005 // more than a page of ListObjectsV2
+
+	ctx := context.Background()
+	srv := newFakeServer(t)
+	storage := srv.storage(t, "app", testSecretKey)
+
+	want := make(map[string]int64, objects)
+	for i := 0; i < objects; i++ {
+		info := blobstore.ObjectInfo{Parent: fmt.Sprintf("folder-%d", i%7), Name: fmt.Sprintf("file-%04d.bin", i), Size: int64(i % 13)}
+		put(t, storage, info.Parent, info.Name, "", randomBytes(int(info.Size)))
+		want[info.Parent+"/"+info.Name] = info.Size
+	}
+
+	// Keys outside the prefix belong to other apps sharing the bucket.
+	put(t, srv.storage(t, "", testSecretKey), "other", "file.bin", "", []byte("data"))
+
+	got := make(map[string]int64, objects)
+	if err := storage.Walk(ctx, func(info blobstore.ObjectInfo) error {
+		key := info.Parent + "/" + info.Name
+		if _, ok := got[key]; ok {
+			t.Fatalf("%s walked twice", key)
+		}
+		got[key] = info.Size
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("walked %d objects, want %d", len(got), len(want))
+	}
+	for key, size := range want {
+		if got[key] != size {
+			t.Fatalf("%s has %d bytes, want %d", key, got[key], size)
+		}
+	}
+
+	if n := len(srv.requests(http.MethodGet, "list-type")); n != 2 {
+		t.Fatalf("listed %d pages, want 2", n)
+	}
+
+	// An error of fn stops the walk.
+	errStop := errors.New("stop")
+	calls := 0
+	err := storage.Walk(ctx, func(blobstore.ObjectInfo) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Fatalf("walk = %v after %d calls, want %v after 1", err, calls, errStop)
+	}
+}
+
+func TestStoragePresignGet(t *testing.T) {
+	srv := newFakeServer(t)
+	storage := srv.storage(t, "", testSecretKey)
+
+	data := []byte("presigned")
+	put(t, storage, "folder", "file.txt", "text/plain", data)
+
+	link, err := storage.PresignGet(context.Background(), "folder", "file.txt")
+	if err != nil {
+		t.Fatalf("presign: %v", err)
+	}
+
+	res := get(t, srv, link)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("get presigned = %s, want 200", res.Status)
+	}
+	if content := readAll(t, res.Body); !bytes.Equal(content, data) {
+		t.Fatalf("presigned content = %q, want %q", content, data)
+	}
+
+	// The signature covers the key.
+	put(t, storage, "folder", "other.txt", "text/plain", data)
+	if res := get(t, srv, strings.Replace(link, "file.txt", "other.txt", 1)); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("get with another key = %s, want 403", res.Status)
+	}
+}
+
+func put(t *testing.T, storage blobstore.Storage, parent, name, typ string, data []byte) {
+	t.Helper()
+
+	if err := storage.Put(context.Background(), parent, name, blobstore.Object{
+		Type: typ,
+		Size: int64(len(data)),
+		Body: bytes.NewReader(data),
+	}); err != nil {
+		t.Fatalf("put %s/%s: %v", parent, name, err)
+	}
+}
+
+// get fetches the link without credentials.
+func get(t *testing.T, srv *fakeServer, link string) *http.Response {
+	t.Helper()
+
+	res, err := srv.Client().Get(link)
+	if err != nil {
+		t.Fatalf("get %s: %v", link, err)
+	}
+	t.Cleanup(func() { _ = res.Body.Close() })
+
+	return res
+}
+
+func readAll(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	return data
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	_, _ = rand.Read(data)
+
+	return data
+}
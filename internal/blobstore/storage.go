@@ -6,18 +6,32 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 )
 
-var ErrObjectNotFound = errors.New("object not found")
+var (
+	ErrObjectNotFound   = errors.New("object not found")
+	ErrChecksumMismatch = errors.New("object checksum mismatch")
+)
 
 type Storage interface {
 	Get(ctx context.Context, parent, name string) (Object, error)
+	// Open returns a seekable handle to the object, the caller must close it.
+	Open(ctx context.Context, parent, name string) (ObjectFile, error)
 	Put(ctx context.Context, parent, name string, obj Object) error
 	Del(ctx context.Context, parent, name string) error
+	// Walk calls fn for every stored object in no particular order.
+	Walk(ctx context.Context, fn func(info ObjectInfo) error) error
 
 	Close(ctx context.Context) error
 }
 
+// Presigner is implemented by storages able to hand out direct download links,
+// so objects can be fetched without passing through the app.
+type Presigner interface {
+	PresignGet(ctx context.Context, parent, name string) (string, error)
+}
+
 type Object struct {
 	Type string
 	Size int64
@@ -35,3 +49,18 @@ func (o Object) Clone() (Object, error) {
 		Body: buf,
 	}, nil
 }
+
+// ObjectInfo describes a stored object without opening it.
+type ObjectInfo struct {
+	Parent  string
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+type ObjectFile struct {
+	Type    string
+	Size    int64
+	ModTime time.Time
+	Body    io.ReadSeekCloser
+}
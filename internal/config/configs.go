@@ -8,6 +8,9 @@ import (
 type Server struct {
 	Host string `env:"HOST" envDefault:"0.0.0.0"`
 	Port int    `env:"PORT" envDefault:"8080"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed, the headers of other peers are ignored.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 }
 
 func (c *Config) Server() (Server, error) {
@@ -34,6 +37,8 @@ func (c *Config) Log() (Log, error) {
 
 type SQLiteDB struct {
 	DSN string `env:"DSN" envDefault:":memory:"`
+	// Migrate applies the pending migrations on start, otherwise they are applied with the migrate command.
+	Migrate bool `env:"MIGRATE" envDefault:"true"`
 }
 
 func (c *Config) SQLiteDB() (SQLiteDB, error) {
@@ -46,8 +51,14 @@ func (c *Config) SQLiteDB() (SQLiteDB, error) {
 }
 
 type Auth struct {
-	Secret         string        `env:"SECRET" envDefault:"secret"`
-	AccessTokenTTL time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"72h"`
+	Secret          string        `env:"SECRET" envDefault:"secret"`
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+
+	VerifyEmailTokenTTL   time.Duration `env:"VERIFY_EMAIL_TOKEN_TTL" envDefault:"24h"`
+	ResetPasswordTokenTTL time.Duration `env:"RESET_PASSWORD_TOKEN_TTL" envDefault:"1h"`
+	// LinkBaseURL is the web client address used in links sent by email.
+	LinkBaseURL string `env:"LINK_BASE_URL" envDefault:"http://localhost:5173"`
 }
 
 func (c *Config) Auth() (Auth, error) {
@@ -59,6 +70,43 @@ func (c *Config) Auth() (Auth, error) {
 	return conf, nil
 }
 
+type BlobStore struct {
+	// Driver is filesystem or s3.
+	Driver string `env:"DRIVER" envDefault:"filesystem"`
+}
+
+func (c *Config) BlobStore() (BlobStore, error) {
+	prefix := "BSTORE"
+	conf, err := newConfigParser[BlobStore](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	return conf, nil
+}
+
+type S3 struct {
+	Endpoint  string `env:"ENDPOINT" envDefault:"http://localhost:9000"`
+	Region    string `env:"REGION" envDefault:"us-east-1"`
+	Bucket    string `env:"BUCKET" envDefault:"gotube"`
+	AccessKey string `env:"ACCESS_KEY"`
+	SecretKey string `env:"SECRET_KEY"`
+	// PathStyle addresses the bucket in the path, as MinIO and most stand-ins expect.
+	PathStyle bool   `env:"PATH_STYLE" envDefault:"true"`
+	Prefix    string `env:"PREFIX"`
+
+	PartSize   int64         `env:"PART_SIZE" envDefault:"16777216"`
+	PresignTTL time.Duration `env:"PRESIGN_TTL" envDefault:"15m"`
+}
+
+func (c *Config) S3() (S3, error) {
+	prefix := "S3"
+	conf, err := newConfigParser[S3](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	return conf, nil
+}
+
 type FileStorage struct {
 	Folder string `env:"FOLDER" envDefault:"./uploads"`
 }
@@ -71,3 +119,139 @@ func (c *Config) FileStorage() (FileStorage, error) {
 	}
 	return conf, nil
 }
+
+type Mail struct {
+	// Driver is smtp, or outbox which writes the messages into a folder and is meant for development only.
+	// It has no default, so a deployment does not drop its mail unnoticed.
+	Driver       string `env:"DRIVER,required,notEmpty"`
+	OutboxFolder string `env:"OUTBOX_FOLDER" envDefault:""`
+
+	SMTPHost     string `env:"SMTP_HOST" envDefault:""`
+	SMTPPort     int    `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME" envDefault:""`
+	SMTPPassword string `env:"SMTP_PASSWORD" envDefault:""`
+	From         string `env:"FROM" envDefault:"no-reply@gotube.local"`
+}
+
+func (c *Config) Mail() (Mail, error) {
+	prefix := "MAIL"
+	conf, err := newConfigParser[Mail](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	return conf, nil
+}
+
+type Views struct {
+	Window        time.Duration `env:"WINDOW" envDefault:"30m"`
+	FlushInterval time.Duration `env:"FLUSH_INTERVAL" envDefault:"10s"`
+	MaxPending    int           `env:"MAX_PENDING" envDefault:"1000"`
+}
+
+func (c *Config) Views() (Views, error) {
+	prefix := "VIEWS"
+	conf, err := newConfigParser[Views](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	if conf.FlushInterval <= 0 {
+		return conf, fmt.Errorf("config.%s: flush interval must be positive", prefix)
+	}
+	return conf, nil
+}
+
+type Upload struct {
+	MaxSize      int64 `env:"MAX_SIZE" envDefault:"10737418240"`    // 10GB
+	MaxChunkSize int64 `env:"MAX_CHUNK_SIZE" envDefault:"67108864"` // 64MB
+	// TTL is how long an upload may go without a chunk before it is abandoned and its parts are collected,
+	// zero keeps unfinished uploads forever.
+	TTL time.Duration `env:"TTL" envDefault:"24h"`
+}
+
+func (c *Config) Upload() (Upload, error) {
+	prefix := "UPLOAD"
+	conf, err := newConfigParser[Upload](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	return conf, nil
+}
+
+type Media struct {
+	// URLSecret signs media URLs handed out by the API, it has no default since a known one lets anyone forge them.
+	URLSecret string        `env:"URL_SECRET,required,notEmpty"`
+	URLTTL    time.Duration `env:"URL_TTL" envDefault:"6h"`
+	// MaxImageDimension limits the width and the height of uploaded images, zero disables the limit.
+	MaxImageDimension int `env:"MAX_IMAGE_DIMENSION" envDefault:"4096"`
+	// ImageSizes are the longer sides of the variants made of every image, in pixels.
+	ImageSizes   []int `env:"IMAGE_SIZES" envDefault:"64,176,320,720" envSeparator:","`
+	ImageQuality int   `env:"IMAGE_QUALITY" envDefault:"85"`
+}
+
+func (c *Config) Media() (Media, error) {
+	prefix := "MEDIA"
+	conf, err := newConfigParser[Media](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	return conf, nil
+}
+
+type MediaGC struct {
+	// Interval is how often orphaned media are collected, zero disables the collector.
+	Interval    time.Duration `env:"INTERVAL" envDefault:"1h"`
+	GracePeriod time.Duration `env:"GRACE_PERIOD" envDefault:"24h"`
+	DryRun      bool          `env:"DRY_RUN" envDefault:"false"`
+}
+
+func (c *Config) MediaGC() (MediaGC, error) {
+	prefix := "MEDIA_GC"
+	conf, err := newConfigParser[MediaGC](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	return conf, nil
+}
+
+type Jobs struct {
+	Workers      int           `env:"WORKERS" envDefault:"2"`
+	PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"5s"`
+	MaxAttempts  int           `env:"MAX_ATTEMPTS" envDefault:"3"`
+	Backoff      time.Duration `env:"BACKOFF" envDefault:"30s"`
+	MaxBackoff   time.Duration `env:"MAX_BACKOFF" envDefault:"30m"`
+	// Lease is how long a running job may go without a heartbeat before another worker takes it over.
+	Lease time.Duration `env:"LEASE" envDefault:"1m"`
+}
+
+func (c *Config) Jobs() (Jobs, error) {
+	prefix := "JOBS"
+	conf, err := newConfigParser[Jobs](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	if conf.PollInterval <= 0 || conf.Lease <= 0 {
+		return conf, fmt.Errorf("config.%s: poll interval and lease must be positive", prefix)
+	}
+	return conf, nil
+}
+
+type Processing struct {
+	// Executor is ffmpeg, or fake which makes up the results and is meant for development only.
+	Executor    string `env:"EXECUTOR" envDefault:"ffmpeg"`
+	FFmpegPath  string `env:"FFMPEG_PATH" envDefault:"ffmpeg"`
+	FFprobePath string `env:"FFPROBE_PATH" envDefault:"ffprobe"`
+	// WorkDir holds temporary files of running tasks, the system temp folder by default.
+	WorkDir string `env:"WORK_DIR" envDefault:""`
+	// MaxDuration and MaxDimension reject longer or larger videos once they are probed, zero disables a limit.
+	MaxDuration  time.Duration `env:"MAX_DURATION" envDefault:"4h"`
+	MaxDimension int           `env:"MAX_DIMENSION" envDefault:"4096"`
+}
+
+func (c *Config) Processing() (Processing, error) {
+	prefix := "PROCESSING"
+	conf, err := newConfigParser[Processing](c.cache).parse(c.fmtPrefix(prefix))
+	if err != nil {
+		return conf, fmt.Errorf("config.%s: %w", prefix, err)
+	}
+	return conf, nil
+}
@@ -10,8 +10,10 @@ import (
 type Key string
 
 const (
-	_traceID = Key("traceId")
-	_user    = Key("user")
+	_traceID  = Key("traceId")
+	_clientIP = Key("clientIp")
+	_user     = Key("user")
+	_session  = Key("session")
 )
 
 func WithTraceID(ctx context.Context, traceID string) context.Context {
@@ -32,6 +34,24 @@ func MustTraceID(ctx context.Context) string {
 	return traceID
 }
 
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, _clientIP, ip)
+}
+
+func RequestWithClientIP(r *http.Request, ip string) *http.Request {
+	return r.WithContext(WithClientIP(r.Context(), ip))
+}
+
+func ClientIP(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(_clientIP).(string)
+	return ip, ok
+}
+
+func MustClientIP(ctx context.Context) string {
+	ip, _ := ClientIP(ctx)
+	return ip
+}
+
 func WithUser(ctx context.Context, user model.User) context.Context {
 	return context.WithValue(ctx, _user, user)
 }
@@ -49,3 +69,29 @@ func MustUser(ctx context.Context) model.User {
 	user, _ := User(ctx)
 	return user
 }
+
+func Role(ctx context.Context) (model.Role, bool) {
+	user, ok := User(ctx)
+	if !ok {
+		return "", false
+	}
+	return user.Role, true
+}
+
+func WithSession(ctx context.Context, session model.Session) context.Context {
+	return context.WithValue(ctx, _session, session)
+}
+
+func RequestWithSession(r *http.Request, session model.Session) *http.Request {
+	return r.WithContext(WithSession(r.Context(), session))
+}
+
+func Session(ctx context.Context) (model.Session, bool) {
+	session, ok := ctx.Value(_session).(model.Session)
+	return session, ok
+}
+
+func MustSession(ctx context.Context) model.Session {
+	session, _ := Session(ctx)
+	return session
+}
@@ -25,5 +25,11 @@ type DB interface {
 	Query(ctx context.Context, query string, args ...any) (Rows, error)
 	QueryRow(ctx context.Context, query string, args ...any) Row
 
+	// WithTx runs fn in a transaction which is committed when fn returns nil and rolled back otherwise.
+	// The queries made with the context given to fn are part of the transaction, and calling WithTx
+	// with it starts a savepoint, so a nested unit of work is rolled back on its own.
+	// The context must not be used by several goroutines at once.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+
 	Close(ctx context.Context) error
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/protomem/gotube/internal/database"
@@ -21,15 +22,40 @@ type DB struct {
 func Connect(ctx context.Context, logger logging.Logger, dsn string) (*DB, error) {
 	const op = "database.Connect"
 
+	// Transactions take the write lock when they begin, a deferred one which reads and then writes
+	// fails with SQLITE_BUSY instead of waiting when another connection has written in the meantime.
+	if !strings.Contains(dsn, "_txlock=") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&_txlock=immediate"
+		} else {
+			dsn += "?_txlock=immediate"
+		}
+	}
+
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// Each connection to an in-memory database opens a new empty one, so the pool has to keep a single connection.
+	if strings.HasPrefix(dsn, ":memory:") || strings.Contains(dsn, "mode=memory") {
+		db.SetMaxOpenConns(1)
+	}
+
 	if err := db.PingContext(ctx); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// Video search needs FTS5, which go-sqlite3 leaves out unless built with the sqlite_fts5 tag.
+	var fts5 bool
+	if err := db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !fts5 {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", op, ErrNoFTS5)
+	}
+
 	return &DB{
 		DB:     db,
 		logger: logger.With("component", "sqlite/database"),
@@ -40,7 +66,7 @@ func (db *DB) Exec(ctx context.Context, query string, args ...any) error {
 	const op = "database.Exec"
 	db.logger.WithContext(ctx).Debug("query exec", "query", query, "args", args, "operation", op)
 
-	if _, err := db.DB.ExecContext(ctx, query, args...); err != nil {
+	if _, err := db.querier(ctx).ExecContext(ctx, query, args...); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	return nil
@@ -50,7 +76,7 @@ func (db *DB) Query(ctx context.Context, query string, args ...any) (database.Ro
 	const op = "database.Query"
 	db.logger.WithContext(ctx).Debug("query exec", "query", query, "args", args, "operation", op)
 
-	rows, err := db.DB.QueryContext(ctx, query, args...)
+	rows, err := db.querier(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -61,7 +87,7 @@ func (db *DB) QueryRow(ctx context.Context, query string, args ...any) database.
 	const op = "database.QueryRow"
 	db.logger.WithContext(ctx).Debug("query exec", "query", query, "args", args, "operation", op)
 
-	return db.DB.QueryRowContext(ctx, query, args...)
+	return db.querier(ctx).QueryRowContext(ctx, query, args...)
 }
 
 func (db *DB) Close(_ context.Context) error {
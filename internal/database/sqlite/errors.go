@@ -7,6 +7,14 @@ import (
 	"github.com/mattn/go-sqlite3"
 )
 
+var ErrNoFTS5 = errors.New("sqlite is built without FTS5, build with -tags sqlite_fts5")
+
+var (
+	ErrMigrationChecksum = errors.New("applied migration has been modified")
+	ErrMigrationUnknown  = errors.New("applied migration is unknown to this build")
+	ErrMigrationDirty    = errors.New("database is dirty")
+)
+
 func IsNoRows(err error) bool {
 	return errors.Is(err, sql.ErrNoRows)
 }
@@ -15,3 +23,8 @@ func IsKeyConflict(err error) bool {
 	var sqlErr sqlite3.Error
 	return errors.As(err, &sqlErr) && sqlErr.ExtendedCode == sqlite3.ErrConstraintUnique
 }
+
+func IsBusy(err error) bool {
+	var sqlErr sqlite3.Error
+	return errors.As(err, &sqlErr) && sqlErr.Code == sqlite3.ErrBusy
+}
@@ -0,0 +1,385 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+// _migrationLockTimeout is how long a migration waits for another instance to finish its own.
+const _migrationLockTimeout = 5 * time.Minute
+
+var _migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+const _schemaMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT    NOT NULL,
+		checksum   TEXT    NOT NULL,
+		applied_at INTEGER NOT NULL
+	)
+`
+
+type migration struct {
+	Version  uint64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Version uint64
+	Name    string
+
+	Applied   bool
+	AppliedAt time.Time
+	// Modified is set when the file of an applied migration has changed since it was applied.
+	Modified bool
+	// Unknown is set when an applied migration has no file in this build.
+	Unknown bool
+}
+
+type appliedMigration struct {
+	Version   uint64
+	Name      string
+	Checksum  string
+	AppliedAt int64
+}
+
+// Migrator applies the migrations of a directory, named as the migrate CLI names them,
+// and records them in the schema_migrations table with the checksum of the up file.
+// Every run is a single transaction, so a failed run leaves the schema as it was, and the write lock
+// it holds keeps other instances from migrating at the same time.
+type Migrator struct {
+	logger     logging.Logger
+	db         *DB
+	migrations []migration
+}
+
+func NewMigrator(logger logging.Logger, db *DB, fsys fs.FS) (*Migrator, error) {
+	const op = "database.NewMigrator"
+
+	migrations, err := readMigrations(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{
+		logger:     logger.With("component", "sqlite/migrator"),
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies the pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	const op = "database.Migrator.Up"
+
+	if err := m.locked(ctx, true, func(ctx context.Context, applied map[uint64]appliedMigration) error {
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+
+			m.logger.WithContext(ctx).Info("applying migration", "version", mig.Version, "name", mig.Name)
+
+			if err := m.db.Exec(ctx, mig.Up); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			if err := m.record(ctx, mig, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Down reverts the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	const op = "database.Migrator.Down"
+
+	if err := m.locked(ctx, true, func(ctx context.Context, applied map[uint64]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			steps--
+
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+			}
+
+			m.logger.WithContext(ctx).Info("reverting migration", "version", mig.Version, "name", mig.Name)
+
+			if err := m.db.Exec(ctx, mig.Down); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			if err := m.db.Exec(ctx, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Status lists the migrations of this build and the applied ones it does not know, by version.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	const op = "database.Migrator.Status"
+
+	var statuses []MigrationStatus
+	if err := m.locked(ctx, false, func(ctx context.Context, applied map[uint64]appliedMigration) error {
+		statuses = make([]MigrationStatus, 0, len(m.migrations))
+		for _, mig := range m.migrations {
+			status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+
+			if entry, ok := applied[mig.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = time.Unix(entry.AppliedAt, 0)
+				status.Modified = entry.Checksum != mig.Checksum
+				delete(applied, mig.Version)
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		for _, entry := range applied {
+			statuses = append(statuses, MigrationStatus{
+				Version:   entry.Version,
+				Name:      entry.Name,
+				Applied:   true,
+				AppliedAt: time.Unix(entry.AppliedAt, 0),
+				Unknown:   true,
+			})
+		}
+
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return statuses, nil
+}
+
+// locked runs fn in a transaction with the applied migrations, waiting while another instance holds the write lock.
+// With verify the applied migrations must match the ones of this build.
+func (m *Migrator) locked(
+	ctx context.Context, verify bool,
+	fn func(ctx context.Context, applied map[uint64]appliedMigration) error,
+) error {
+	deadline := time.Now().Add(_migrationLockTimeout)
+
+	for {
+		err := m.db.WithTx(ctx, func(ctx context.Context) error {
+			applied, err := m.applied(ctx)
+			if err != nil {
+				return err
+			}
+
+			if verify {
+				if err := m.verify(applied); err != nil {
+					return err
+				}
+			}
+
+			return fn(ctx, applied)
+		})
+		if !IsBusy(err) || time.Now().After(deadline) {
+			return err
+		}
+
+		m.logger.WithContext(ctx).Warn("database is locked, waiting for another instance to finish migrating")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// applied creates the schema_migrations table if needed and returns its entries.
+func (m *Migrator) applied(ctx context.Context) (map[uint64]appliedMigration, error) {
+	if err := m.adoptLegacy(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := m.db.Exec(ctx, _schemaMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[uint64]appliedMigration)
+	for rows.Next() {
+		var entry appliedMigration
+		if err := rows.Scan(&entry.Version, &entry.Name, &entry.Checksum, &entry.AppliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[entry.Version] = entry
+	}
+
+	return applied, nil
+}
+
+// adoptLegacy replaces the schema_migrations table of the migrate CLI, which keeps only the last version,
+// with one entry per migration up to that version.
+func (m *Migrator) adoptLegacy(ctx context.Context) error {
+	var legacy bool
+	if err := m.db.QueryRow(ctx, `
+		SELECT COUNT(*) > 0 FROM pragma_table_info('schema_migrations') WHERE name = 'dirty'
+	`).Scan(&legacy); err != nil {
+		return err
+	}
+
+	if !legacy {
+		return nil
+	}
+
+	var (
+		version uint64
+		dirty   bool
+	)
+	if err := m.db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty); err != nil && !IsNoRows(err) {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("%w at version %d, fix it and force the version with the migrate CLI", ErrMigrationDirty, version)
+	}
+
+	m.logger.WithContext(ctx).Info("adopting the migrate CLI schema_migrations table", "version", version)
+
+	if err := m.db.Exec(ctx, `DROP TABLE schema_migrations`); err != nil {
+		return err
+	}
+
+	if err := m.db.Exec(ctx, _schemaMigrationsTable); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+
+		if err := m.record(ctx, mig, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) record(ctx context.Context, mig migration, appliedAt time.Time) error {
+	query := `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`
+	args := []any{mig.Version, mig.Name, mig.Checksum, appliedAt.Unix()}
+
+	return m.db.Exec(ctx, query, args...)
+}
+
+// verify fails when an applied migration has been modified or is missing from this build,
+// since the schema would then differ from the one the migrations describe.
+func (m *Migrator) verify(applied map[uint64]appliedMigration) error {
+	known := make(map[uint64]migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+
+	for version, entry := range applied {
+		mig, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %d_%s", ErrMigrationUnknown, entry.Version, entry.Name)
+		}
+
+		if mig.Checksum != entry.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrMigrationChecksum, mig.Version, mig.Name)
+		}
+	}
+
+	return nil
+}
+
+func readMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*migration)
+	for _, entry := range entries {
+		match := _migrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, mig.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			sum := sha256.Sum256(content)
+			mig.Up = string(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
@@ -0,0 +1,346 @@
+//go:build sqlite_fts5
+
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/protomem/gotube/assets"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+)
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"000001_items.up.sql":   {Data: []byte(`CREATE TABLE items (id INTEGER PRIMARY KEY);`)},
+		"000001_items.down.sql": {Data: []byte(`DROP TABLE items;`)},
+		"000002_tags.up.sql":    {Data: []byte(`CREATE TABLE tags (id INTEGER PRIMARY KEY);`)},
+		"000002_tags.down.sql":  {Data: []byte(`DROP TABLE tags;`)},
+		"000003_notes.up.sql":   {Data: []byte(`CREATE TABLE notes (id INTEGER PRIMARY KEY);`)},
+		"000003_notes.down.sql": {Data: []byte(`DROP TABLE notes;`)},
+		"README.md":             {Data: []byte(`Not a migration.`)},
+	}
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openEmpty(t)
+	migrator := newMigrator(t, db, testMigrations())
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	assertTables(t, db, "items", "notes", "tags")
+	assertApplied(t, migrator, 1, 2, 3)
+
+	// Applied migrations are not run again.
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+	assertApplied(t, migrator, 1, 2, 3)
+
+	if err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("down 1: %v", err)
+	}
+	assertTables(t, db, "items", "tags")
+	assertApplied(t, migrator, 1, 2)
+
+	// Steps beyond the applied migrations revert all of them.
+	if err := migrator.Down(ctx, 5); err != nil {
+		t.Fatalf("down 5: %v", err)
+	}
+	assertTables(t, db)
+	assertApplied(t, migrator)
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+	assertTables(t, db, "items", "notes", "tags")
+}
+
+func TestMigratorUpRollsBackFailedRun(t *testing.T) {
+	ctx := context.Background()
+	db := openEmpty(t)
+
+	migrations := testMigrations()
+	migrations["000003_notes.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE notes (`)}
+
+	if err := newMigrator(t, db, migrations).Up(ctx); err == nil {
+		t.Fatal("up with a broken migration succeeded")
+	}
+
+	// The migrations before the broken one are rolled back with it.
+	assertTables(t, db)
+	assertApplied(t, newMigrator(t, db, testMigrations()))
+}
+
+func TestMigratorStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openEmpty(t)
+
+	migrations := testMigrations()
+	delete(migrations, "000003_notes.up.sql")
+	delete(migrations, "000003_notes.down.sql")
+
+	migrator := newMigrator(t, db, migrations)
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	statuses, err := newMigrator(t, db, testMigrations()).Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	want := []sqlite.MigrationStatus{
+		{Version: 1, Name: "items", Applied: true},
+		{Version: 2, Name: "tags", Applied: true},
+		{Version: 3, Name: "notes"},
+	}
+	expectStatuses(t, statuses, want)
+
+	for _, status := range statuses[:2] {
+		if status.AppliedAt.IsZero() {
+			t.Fatalf("migration %d has no applied time", status.Version)
+		}
+	}
+}
+
+func TestMigratorChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := openEmpty(t)
+
+	if err := newMigrator(t, db, testMigrations()).Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	modified := testMigrations()
+	modified["000002_tags.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT);`)}
+	modified["000004_links.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE links (id INTEGER PRIMARY KEY);`)}
+	migrator := newMigrator(t, db, modified)
+
+	if err := migrator.Up(ctx); !errors.Is(err, sqlite.ErrMigrationChecksum) {
+		t.Fatalf("up = %v, want %v", err, sqlite.ErrMigrationChecksum)
+	}
+	if err := migrator.Down(ctx, 1); !errors.Is(err, sqlite.ErrMigrationChecksum) {
+		t.Fatalf("down = %v, want %v", err, sqlite.ErrMigrationChecksum)
+	}
+	assertTables(t, db, "items", "notes", "tags")
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	expectStatuses(t, statuses, []sqlite.MigrationStatus{
+		{Version: 1, Name: "items", Applied: true},
+		{Version: 2, Name: "tags", Applied: true, Modified: true},
+		{Version: 3, Name: "notes", Applied: true},
+		{Version: 4, Name: "links"},
+	})
+
+	// A build without an applied migration refuses to migrate as well.
+	missing := testMigrations()
+	delete(missing, "000003_notes.up.sql")
+	delete(missing, "000003_notes.down.sql")
+	migrator = newMigrator(t, db, missing)
+
+	if err := migrator.Up(ctx); !errors.Is(err, sqlite.ErrMigrationUnknown) {
+		t.Fatalf("up = %v, want %v", err, sqlite.ErrMigrationUnknown)
+	}
+
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	expectStatuses(t, statuses, []sqlite.MigrationStatus{
+		{Version: 1, Name: "items", Applied: true},
+		{Version: 2, Name: "tags", Applied: true},
+		{Version: 3, Name: "notes", Applied: true, Unknown: true},
+	})
+}
+
+func TestMigratorAdoptsLegacyTable(t *testing.T) {
+	ctx := context.Background()
+	db := openEmpty(t)
+
+	// The migrate CLI left the database at version 2.
+	for _, query := range []string{
+		`CREATE TABLE schema_migrations (version uint64, dirty bool)`,
+		`INSERT INTO schema_migrations (version, dirty) VALUES (2, false)`,
+		`CREATE TABLE items (id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE tags (id INTEGER PRIMARY KEY)`,
+	} {
+		if err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("prepare legacy schema: %v", err)
+		}
+	}
+
+	migrator := newMigrator(t, db, testMigrations())
+	assertApplied(t, migrator, 1, 2)
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	assertTables(t, db, "items", "notes", "tags")
+	assertApplied(t, migrator, 1, 2, 3)
+}
+
+func TestMigratorRefusesDirtyLegacyTable(t *testing.T) {
+	ctx := context.Background()
+	db := openEmpty(t)
+
+	for _, query := range []string{
+		`CREATE TABLE schema_migrations (version uint64, dirty bool)`,
+		`INSERT INTO schema_migrations (version, dirty) VALUES (2, true)`,
+	} {
+		if err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("prepare legacy schema: %v", err)
+		}
+	}
+
+	if err := newMigrator(t, db, testMigrations()).Up(ctx); !errors.Is(err, sqlite.ErrMigrationDirty) {
+		t.Fatalf("up = %v, want %v", err, sqlite.ErrMigrationDirty)
+	}
+	assertTables(t, db, "schema_migrations")
+}
+
+func TestNewMigratorRejectsMissingUpFile(t *testing.T) {
+	migrations := testMigrations()
+	delete(migrations, "000002_tags.up.sql")
+
+	if _, err := sqlite.NewMigrator(sqlitetest.Logger(t), openEmpty(t), migrations); err == nil {
+		t.Fatal("new migrator without an up file succeeded")
+	}
+}
+
+func TestMigrationsOfAppRevertCleanly(t *testing.T) {
+	ctx := context.Background()
+	db := openEmpty(t)
+
+	migrations, err := fs.Sub(assets.Assets, "migrations/sqlite")
+	if err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	migrator := newMigrator(t, db, migrations)
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	// The first migration is empty and has nothing to revert.
+	if err := migrator.Down(ctx, len(statuses)-1); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	assertTables(t, db)
+	assertApplied(t, migrator, 1)
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}
+
+// openEmpty connects to an in-memory database without any migrations applied.
+func openEmpty(t *testing.T) *sqlite.DB {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db, err := sqlite.Connect(ctx, sqlitetest.Logger(t), ":memory:?_fk=1")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close(ctx) })
+
+	return db
+}
+
+func newMigrator(t *testing.T, db *sqlite.DB, migrations fs.FS) *sqlite.Migrator {
+	t.Helper()
+
+	migrator, err := sqlite.NewMigrator(sqlitetest.Logger(t), db, migrations)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+
+	return migrator
+}
+
+// assertTables checks the tables of the database, leaving out the one of the migrator.
+func assertTables(t *testing.T, db *sqlite.DB, want ...string) {
+	t.Helper()
+
+	rows, err := db.Query(context.Background(), `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND (name != 'schema_migrations' OR ? > 0)
+		ORDER BY name
+	`, slices.Contains(want, "schema_migrations"))
+	if err != nil {
+		t.Fatalf("select tables: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	got := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan table: %v", err)
+		}
+		got = append(got, name)
+	}
+
+	if want == nil {
+		want = []string{}
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("tables = %v, want %v", got, want)
+	}
+}
+
+func assertApplied(t *testing.T, migrator *sqlite.Migrator, want ...uint64) {
+	t.Helper()
+
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	got := make([]uint64, 0)
+	for _, status := range statuses {
+		if status.Applied {
+			got = append(got, status.Version)
+		}
+	}
+
+	if want == nil {
+		want = []uint64{}
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("applied migrations = %v, want %v", got, want)
+	}
+}
+
+// expectStatuses compares the statuses leaving out the time they were applied at.
+func expectStatuses(t *testing.T, got, want []sqlite.MigrationStatus) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("statuses = %+v, want %+v", got, want)
+	}
+
+	for i := range got {
+		status := got[i]
+		status.AppliedAt = want[i].AppliedAt
+		if status != want[i] {
+			t.Fatalf("status %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
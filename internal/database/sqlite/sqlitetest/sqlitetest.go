@@ -0,0 +1,71 @@
+// Package sqlitetest opens migrated databases for tests, which have to be built with the sqlite_fts5 tag.
+package sqlitetest
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/protomem/gotube/assets"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/pkg/logging"
+	"github.com/protomem/gotube/pkg/logging/std"
+)
+
+// Logger returns a logger which drops everything.
+func Logger(t testing.TB) logging.Logger {
+	t.Helper()
+
+	logger, err := std.New("error", io.Discard)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+
+	return logger
+}
+
+// Open connects to an in-memory database with the migrations applied, closed when the test ends.
+func Open(t testing.TB) *sqlite.DB {
+	t.Helper()
+
+	return open(t, ":memory:?_fk=1")
+}
+
+// OpenFile is Open for a database file in a temporary folder, which several connections share,
+// so concurrent transactions really wait for each other.
+func OpenFile(t testing.TB) *sqlite.DB {
+	t.Helper()
+
+	return open(t, filepath.Join(t.TempDir(), "db.sqlite")+"?_fk=1&_journal=WAL&_timeout=5000")
+}
+
+func open(t testing.TB, dsn string) *sqlite.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	logger := Logger(t)
+
+	db, err := sqlite.Connect(ctx, logger, dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close(ctx) })
+
+	migrations, err := fs.Sub(assets.Assets, "migrations/sqlite")
+	if err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+
+	migrator, err := sqlite.NewMigrator(logger, db, migrations)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return db
+}
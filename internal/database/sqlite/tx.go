@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+type txKey struct{}
+
+// txState is the transaction of a context, depth is the number of savepoints open in it.
+type txState struct {
+	tx    *sql.Tx
+	depth int
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// querier returns the transaction of the context, if any, so the repositories take part in it without knowing.
+func (db *DB) querier(ctx context.Context) querier {
+	if state, ok := ctx.Value(txKey{}).(txState); ok {
+		return state.tx
+	}
+	return db.DB
+}
+
+func (db *DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	const op = "database.WithTx"
+
+	if state, ok := ctx.Value(txKey{}).(txState); ok {
+		return db.withSavepoint(ctx, state, fn)
+	}
+
+	db.logger.WithContext(ctx).Debug("tx begin", "operation", op)
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, txState{tx: tx})); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("%s: rollback: %w", op, rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+	return nil
+}
+
+func (db *DB) withSavepoint(ctx context.Context, state txState, fn func(ctx context.Context) error) error {
+	const op = "database.WithTx"
+
+	state.depth++
+	name := fmt.Sprintf("sp%d", state.depth)
+
+	db.logger.WithContext(ctx).Debug("tx savepoint", "savepoint", name, "operation", op)
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// ROLLBACK TO keeps the savepoint open, it is released in both cases so the name can be used again.
+	rollback := func() error {
+		if _, err := state.tx.ExecContext(ctx, "ROLLBACK TO "+name); err != nil {
+			return err
+		}
+		_, err := state.tx.ExecContext(ctx, "RELEASE "+name)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		if rbErr := rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("%s: rollback: %w", op, rbErr))
+		}
+		return err
+	}
+
+	if _, err := state.tx.ExecContext(ctx, "RELEASE "+name); err != nil {
+		return fmt.Errorf("%s: release: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+//go:build sqlite_fts5
+
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+)
+
+var errTest = errors.New("test")
+
+func TestWithTxRollsBackFailedSavepoint(t *testing.T) {
+	ctx := context.Background()
+	db := newItemsDB(t)
+
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		insertItem(ctx, t, db, 1)
+
+		if err := db.WithTx(ctx, func(ctx context.Context) error {
+			insertItem(ctx, t, db, 2)
+
+			return db.WithTx(ctx, func(ctx context.Context) error {
+				insertItem(ctx, t, db, 3)
+				return errTest
+			})
+		}); !errors.Is(err, errTest) {
+			t.Fatalf("inner WithTx = %v, want %v", err, errTest)
+		}
+
+		// The rolled back savepoints are released, so the next nested unit of work reuses their names.
+		return db.WithTx(ctx, func(ctx context.Context) error {
+			insertItem(ctx, t, db, 4)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx = %v", err)
+	}
+
+	assertItems(t, db, 1, 4)
+}
+
+func TestWithTxKeepsSucceededSavepointOfFailedOne(t *testing.T) {
+	ctx := context.Background()
+	db := newItemsDB(t)
+
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		return db.WithTx(ctx, func(ctx context.Context) error {
+			insertItem(ctx, t, db, 1)
+
+			if err := db.WithTx(ctx, func(ctx context.Context) error {
+				insertItem(ctx, t, db, 2)
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			if err := db.WithTx(ctx, func(ctx context.Context) error {
+				insertItem(ctx, t, db, 3)
+				return errTest
+			}); !errors.Is(err, errTest) {
+				t.Fatalf("inner WithTx = %v, want %v", err, errTest)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx = %v", err)
+	}
+
+	assertItems(t, db, 1, 2)
+}
+
+func TestWithTxRollsBackReleasedSavepointsWithOuter(t *testing.T) {
+	ctx := context.Background()
+	db := newItemsDB(t)
+
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		insertItem(ctx, t, db, 1)
+
+		if err := db.WithTx(ctx, func(ctx context.Context) error {
+			insertItem(ctx, t, db, 2)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		return errTest
+	})
+	if !errors.Is(err, errTest) {
+		t.Fatalf("WithTx = %v, want %v", err, errTest)
+	}
+
+	assertItems(t, db)
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	ctx := context.Background()
+	db := newItemsDB(t)
+
+	func() {
+		defer func() {
+			if p := recover(); p != errTest {
+				t.Fatalf("recovered %v, want %v", p, errTest)
+			}
+		}()
+
+		_ = db.WithTx(ctx, func(ctx context.Context) error {
+			insertItem(ctx, t, db, 1)
+			panic(errTest)
+		})
+	}()
+
+	assertItems(t, db)
+
+	// The connection went back to the pool without a transaction left open on it.
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		insertItem(ctx, t, db, 2)
+		return nil
+	}); err != nil {
+		t.Fatalf("WithTx = %v", err)
+	}
+
+	assertItems(t, db, 2)
+}
+
+func newItemsDB(t *testing.T) *sqlite.DB {
+	t.Helper()
+
+	db := sqlitetest.Open(t)
+	if err := db.Exec(context.Background(), `CREATE TABLE items (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("create items: %v", err)
+	}
+
+	return db
+}
+
+func insertItem(ctx context.Context, t *testing.T, db *sqlite.DB, id int) {
+	t.Helper()
+
+	if err := db.Exec(ctx, `INSERT INTO items (id) VALUES (?)`, id); err != nil {
+		t.Fatalf("insert item %d: %v", id, err)
+	}
+}
+
+func assertItems(t *testing.T, db *sqlite.DB, want ...int) {
+	t.Helper()
+
+	rows, err := db.Query(context.Background(), `SELECT id FROM items ORDER BY id`)
+	if err != nil {
+		t.Fatalf("select items: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	got := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan item: %v", err)
+		}
+		got = append(got, id)
+	}
+
+	if want == nil {
+		want = []int{}
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("items = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/protomem/gotube/internal/ctxstore"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/service"
+	"github.com/protomem/gotube/pkg/httplib"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+type Account struct {
+	logger logging.Logger
+	serv   service.Account
+	urls   *MediaURLs
+}
+
+func NewAccount(logger logging.Logger, serv service.Account, urls *MediaURLs) *Account {
+	return &Account{
+		logger: logger.With("handler", "account"),
+		serv:   serv,
+		urls:   urls,
+	}
+}
+
+func (h *Account) VerifyEmail() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var request struct {
+			Token string `json:"token"`
+		}
+
+		if err := httplib.DecodeJSON(r, &request); err != nil {
+			return err
+		}
+
+		user, err := h.serv.VerifyEmail(r.Context(), request.Token)
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"user": h.urls.User(user)})
+	}, h.errorHandler("handler.Account.VerifyEmail"))
+}
+
+func (h *Account) ResendVerification() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		user := ctxstore.MustUser(r.Context())
+
+		if err := h.serv.RequestEmailVerification(r.Context(), user.ID); err != nil {
+			return err
+		}
+
+		return httplib.SendStatus(w, http.StatusAccepted)
+	}, h.errorHandler("handler.Account.ResendVerification"))
+}
+
+func (h *Account) ForgotPassword() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var request struct {
+			Email string `json:"email"`
+		}
+
+		if err := httplib.DecodeJSON(r, &request); err != nil {
+			return err
+		}
+
+		if err := h.serv.RequestPasswordReset(r.Context(), request.Email); err != nil {
+			return err
+		}
+
+		return httplib.SendStatus(w, http.StatusAccepted)
+	}, h.errorHandler("handler.Account.ForgotPassword"))
+}
+
+func (h *Account) ResetPassword() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var request struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+
+		if err := httplib.DecodeJSON(r, &request); err != nil {
+			return err
+		}
+
+		if err := h.serv.ResetPassword(r.Context(), service.ResetPasswordDTO(request)); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Account.ResetPassword"))
+}
+
+func (h *Account) errorHandler(op string) httplib.ErroHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
+
+		if errors.Is(err, model.ErrUserNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrUserNotFound.Error())
+		}
+		if errors.Is(err, model.ErrUserTokenNotFound) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrUserTokenNotFound.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
+
+		httplib.DefaultErrorHandler(w, r, err)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAccountVerifyEmail(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+
+	ts.expectDenied([]route{{http.MethodPost, "/auth/verify-email/resend"}})
+
+	ts.expect(http.MethodPost, "/auth/verify-email/resend", alice.Token, nil, http.StatusAccepted)
+
+	res := ts.expect(http.MethodPost, "/auth/verify-email", "", map[string]any{
+		"token": ts.mailToken(alice.Email),
+	}, http.StatusOK)
+	if verified, _ := res.field(t, "user", "isVerified").(bool); !verified {
+		t.Fatalf("user is not verified: %s", res.Body)
+	}
+
+	ts.expect(http.MethodPost, "/auth/verify-email", "", map[string]any{"token": "unknown"}, http.StatusBadRequest)
+}
+
+func TestAccountResetPassword(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+
+	ts.expect(http.MethodPost, "/auth/forgot-password", "", map[string]any{"email": alice.Email}, http.StatusAccepted)
+	// Unknown emails are not told apart.
+	ts.expect(http.MethodPost, "/auth/forgot-password", "", map[string]any{"email": "nobody@example.com"}, http.StatusAccepted)
+
+	ts.expect(http.MethodPost, "/auth/reset-password", "", map[string]any{
+		"token":    ts.mailToken(alice.Email),
+		"password": "new password",
+	}, http.StatusNoContent)
+	ts.signIn(alice, "new password")
+
+	ts.expect(http.MethodPost, "/auth/reset-password", "", map[string]any{
+		"token":    "unknown",
+		"password": "new password",
+	}, http.StatusBadRequest)
+}
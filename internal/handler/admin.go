@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/protomem/gotube/internal/mediagc"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/service"
+	"github.com/protomem/gotube/pkg/httplib"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+type MediaCollector interface {
+	Collect(ctx context.Context, dryRun bool) (mediagc.Report, error)
+}
+
+type Admin struct {
+	logger   logging.Logger
+	userServ service.User
+	gc       MediaCollector
+	urls     *MediaURLs
+}
+
+func NewAdmin(logger logging.Logger, userServ service.User, gc MediaCollector, urls *MediaURLs) *Admin {
+	return &Admin{
+		logger:   logger.With("handler", "admin"),
+		userServ: userServ,
+		gc:       gc,
+		urls:     urls,
+	}
+}
+
+func (h *Admin) ListUsers() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		findOpts, err := parseFindOptions(r)
+		if err != nil {
+			return err
+		}
+		if err := checkCursor(findOpts, model.CursorSortLatest); err != nil {
+			return err
+		}
+
+		users, err := h.userServ.Find(r.Context(), findOpts)
+		if err != nil {
+			return err
+		}
+
+		next := nextCursor(findOpts, model.CursorSortLatest, len(users), func(i int) model.Cursor {
+			return model.Cursor{Key: users[i].CreatedAt.Unix(), ID: users[i].ID}
+		})
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"users": h.urls.Users(users), "nextCursor": next})
+	}, h.errorHandler("handler.Admin.ListUsers"))
+}
+
+func (h *Admin) UpdateUser() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		userNickname, ok := mux.Vars(r)["userNickname"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing nickname")
+		}
+
+		var request struct {
+			Role model.Role `json:"role"`
+		}
+
+		if err := httplib.DecodeJSON(r, &request); err != nil {
+			return err
+		}
+
+		user, err := h.userServ.SetRoleByNickname(r.Context(), userNickname, request.Role)
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"user": h.urls.User(user)})
+	}, h.errorHandler("handler.Admin.UpdateUser"))
+}
+
+func (h *Admin) DeleteUser() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		userNickname, ok := mux.Vars(r)["userNickname"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing nickname")
+		}
+
+		if err := h.userServ.DeleteByNickname(r.Context(), userNickname); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Admin.DeleteUser"))
+}
+
+func (h *Admin) CollectMedia() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		dryRun := false
+		if r.URL.Query().Has("dryRun") {
+			value, err := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+			if err != nil {
+				return httplib.NewAPIError(http.StatusBadRequest, "invalid dryRun").WithInternal(err)
+			}
+			dryRun = value
+		}
+
+		report, err := h.gc.Collect(r.Context(), dryRun)
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"report": report})
+	}, h.errorHandler("handler.Admin.CollectMedia"))
+}
+
+func (h *Admin) errorHandler(op string) httplib.ErroHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
+
+		if errors.Is(err, model.ErrUserNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrUserNotFound.Error())
+		}
+		if errors.Is(err, model.ErrInvalidRole) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrInvalidRole.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
+
+		httplib.DefaultErrorHandler(w, r, err)
+	}
+}
@@ -0,0 +1,82 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAdminRequiresRole(t *testing.T) {
+	ts := newTestServer(t)
+	bob := ts.signUp("bob")
+
+	routes := []route{
+		{http.MethodGet, "/admin/users"},
+		{http.MethodPut, "/admin/users/bob"},
+		{http.MethodPatch, "/admin/users/bob"},
+		{http.MethodDelete, "/admin/users/bob"},
+		{http.MethodPost, "/admin/media/gc"},
+	}
+
+	ts.expectDenied(routes)
+	for _, r := range routes {
+		ts.expect(r.method, r.path, bob.Token, nil, http.StatusForbidden)
+	}
+}
+
+func TestAdminListUsers(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.makeAdmin(ts.signUp("admin"))
+	ts.signUp("bob")
+
+	res := ts.expect(http.MethodGet, "/admin/users", admin.Token, nil, http.StatusOK)
+	if n := res.len(t, "users"); n != 2 {
+		t.Fatalf("users = %d, want 2", n)
+	}
+}
+
+func TestAdminUpdateUser(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.makeAdmin(ts.signUp("admin"))
+	bob := ts.signUp("bob")
+
+	res := ts.expect(http.MethodPatch, "/admin/users/bob", admin.Token, map[string]any{"role": "moderator"}, http.StatusOK)
+	if role := res.str(t, "user", "role"); role != "moderator" {
+		t.Fatalf("role = %q, want moderator", role)
+	}
+
+	// Tokens issued before a role change are not accepted.
+	ts.expect(http.MethodGet, "/auth/sessions", bob.Token, nil, http.StatusUnauthorized)
+
+	res = ts.expect(http.MethodPut, "/admin/users/bob", admin.Token, map[string]any{"role": "user"}, http.StatusOK)
+	if role := res.str(t, "user", "role"); role != "user" {
+		t.Fatalf("role = %q, want user", role)
+	}
+
+	ts.expect(http.MethodPatch, "/admin/users/nobody", admin.Token, map[string]any{"role": "user"}, http.StatusNotFound)
+}
+
+func TestAdminDeleteUser(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.makeAdmin(ts.signUp("admin"))
+	ts.signUp("bob")
+
+	ts.expect(http.MethodDelete, "/admin/users/bob", admin.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodGet, "/users/bob", "", nil, http.StatusNotFound)
+
+	ts.expect(http.MethodDelete, "/admin/users/nobody", admin.Token, nil, http.StatusNotFound)
+}
+
+func TestAdminCollectMedia(t *testing.T) {
+	ts := newTestServer(t)
+	admin := ts.makeAdmin(ts.signUp("admin"))
+
+	res := ts.expect(http.MethodPost, "/admin/media/gc?dryRun=true", admin.Token, nil, http.StatusOK)
+	if _, ok := res.field(t, "report").(map[string]any); !ok {
+		t.Fatalf("no report in %s", res.Body)
+	}
+
+	ts.expect(http.MethodPost, "/admin/media/gc", admin.Token, nil, http.StatusOK)
+	ts.expect(http.MethodPost, "/admin/media/gc?dryRun=maybe", admin.Token, nil, http.StatusBadRequest)
+}
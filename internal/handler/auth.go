@@ -4,6 +4,9 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/protomem/gotube/internal/ctxstore"
 	"github.com/protomem/gotube/internal/model"
 	"github.com/protomem/gotube/internal/service"
 	"github.com/protomem/gotube/pkg/httplib"
@@ -13,12 +16,14 @@ import (
 type Auth struct {
 	logger logging.Logger
 	serv   service.Auth
+	urls   *MediaURLs
 }
 
-func NewAuth(logger logging.Logger, serv service.Auth) *Auth {
+func NewAuth(logger logging.Logger, serv service.Auth, urls *MediaURLs) *Auth {
 	return &Auth{
 		logger: logger.With("handler", "auth"),
 		serv:   serv,
+		urls:   urls,
 	}
 }
 
@@ -33,18 +38,103 @@ func (h *Auth) Login() http.HandlerFunc {
 			return err
 		}
 
-		token, user, err := h.serv.Login(r.Context(), service.LoginDTO(request))
+		tokens, user, err := h.serv.Login(r.Context(), service.LoginDTO(request), h.device(r))
 		if err != nil {
 			return err
 		}
 
 		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{
-			"accesssToken": token,
-			"user":         user,
+			"accesssToken": tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+			"user":         h.urls.User(user),
 		})
 	}, h.errorHandler("handler.Auth.Login"))
 }
 
+func (h *Auth) Refresh() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var request struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+
+		if err := httplib.DecodeJSON(r, &request); err != nil {
+			return err
+		}
+
+		tokens, err := h.serv.Refresh(r.Context(), request.RefreshToken, h.device(r))
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{
+			"accesssToken": tokens.AccessToken,
+			"refreshToken": tokens.RefreshToken,
+		})
+	}, h.errorHandler("handler.Auth.Refresh"))
+}
+
+func (h *Auth) Logout() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		session := ctxstore.MustSession(r.Context())
+
+		if err := h.serv.Logout(r.Context(), session.ID); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Auth.Logout"))
+}
+
+func (h *Auth) ListSessions() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		user := ctxstore.MustUser(r.Context())
+		current := ctxstore.MustSession(r.Context())
+
+		sessions, err := h.serv.FindSessions(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{
+			"sessions":       sessions,
+			"currentSession": current.ID,
+		})
+	}, h.errorHandler("handler.Auth.ListSessions"))
+}
+
+func (h *Auth) RevokeSession() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		sessionIDRaw, ok := mux.Vars(r)["sessionId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing session id")
+		}
+
+		sessionID, err := uuid.Parse(sessionIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid session id").WithInternal(err)
+		}
+
+		user := ctxstore.MustUser(r.Context())
+
+		if err := h.serv.RevokeSession(r.Context(), user.ID, sessionID); err != nil {
+			if errors.Is(err, model.ErrSessionNotFound) {
+				return httplib.NewAPIError(http.StatusNotFound, model.ErrSessionNotFound.Error()).WithInternal(err)
+			}
+
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Auth.RevokeSession"))
+}
+
+func (*Auth) device(r *http.Request) service.DeviceDTO {
+	return service.DeviceDTO{
+		UserAgent: r.UserAgent(),
+		IP:        ctxstore.MustClientIP(r.Context()),
+	}
+}
+
 func (h *Auth) errorHandler(op string) httplib.ErroHandler {
 	return func(w http.ResponseWriter, r *http.Request, err error) {
 		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
@@ -52,6 +142,12 @@ func (h *Auth) errorHandler(op string) httplib.ErroHandler {
 		if errors.Is(err, model.ErrUserNotFound) {
 			err = httplib.NewAPIError(http.StatusNotFound, model.ErrUserNotFound.Error())
 		}
+		if errors.Is(err, model.ErrSessionNotFound) {
+			err = httplib.NewAPIError(http.StatusUnauthorized, model.ErrSessionNotFound.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
 
 		httplib.DefaultErrorHandler(w, r, err)
 	}
@@ -0,0 +1,84 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAuthLogin(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+
+	res := ts.expect(http.MethodPost, "/auth/login", "", map[string]any{
+		"email":    alice.Email,
+		"password": "password",
+	}, http.StatusOK)
+	if id := res.str(t, "user", "id"); id != alice.ID {
+		t.Fatalf("id = %s, want %s", id, alice.ID)
+	}
+
+	// A wrong password is answered like an unknown email.
+	ts.expect(http.MethodPost, "/auth/login", "", map[string]any{
+		"email":    alice.Email,
+		"password": "wrong password",
+	}, http.StatusNotFound)
+	ts.expect(http.MethodPost, "/auth/login", "", map[string]any{
+		"email":    "nobody@example.com",
+		"password": "password",
+	}, http.StatusNotFound)
+}
+
+func TestAuthRefresh(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+
+	res := ts.expect(http.MethodPost, "/auth/refresh", "", map[string]any{
+		"refreshToken": alice.RefreshToken,
+	}, http.StatusOK)
+	ts.expect(http.MethodGet, "/auth/sessions", res.str(t, "accesssToken"), nil, http.StatusOK)
+
+	// Refresh tokens are used once.
+	ts.expect(http.MethodPost, "/auth/refresh", "", map[string]any{
+		"refreshToken": alice.RefreshToken,
+	}, http.StatusUnauthorized)
+}
+
+func TestAuthLogout(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+
+	ts.expectDenied([]route{{http.MethodPost, "/auth/logout"}})
+
+	ts.expect(http.MethodPost, "/auth/logout", alice.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodGet, "/auth/sessions", alice.Token, nil, http.StatusUnauthorized)
+}
+
+func TestAuthSessions(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	other := ts.signIn(alice, "password")
+	bob := ts.signUp("bob")
+
+	ts.expectDenied([]route{
+		{http.MethodGet, "/auth/sessions"},
+		{http.MethodDelete, "/auth/sessions/" + missingID},
+	})
+
+	res := ts.expect(http.MethodGet, "/auth/sessions", alice.Token, nil, http.StatusOK)
+	if n := res.len(t, "sessions"); n != 2 {
+		t.Fatalf("sessions = %d, want 2", n)
+	}
+
+	res = ts.expect(http.MethodGet, "/auth/sessions", other.Token, nil, http.StatusOK)
+	otherSession := res.str(t, "currentSession")
+
+	// Sessions of someone else are as good as missing.
+	ts.expect(http.MethodDelete, "/auth/sessions/"+otherSession, bob.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodDelete, "/auth/sessions/"+missingID, alice.Token, nil, http.StatusNotFound)
+
+	ts.expect(http.MethodDelete, "/auth/sessions/"+otherSession, alice.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodGet, "/auth/sessions", other.Token, nil, http.StatusUnauthorized)
+	ts.expect(http.MethodGet, "/auth/sessions", alice.Token, nil, http.StatusOK)
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/protomem/gotube/internal/ctxstore"
+	"github.com/protomem/gotube/internal/model"
 	"github.com/protomem/gotube/internal/service"
 	"github.com/protomem/gotube/pkg/httplib"
 	"github.com/protomem/gotube/pkg/logging"
@@ -15,12 +17,14 @@ import (
 type Comment struct {
 	logger logging.Logger
 	serv   service.Comment
+	urls   *MediaURLs
 }
 
-func NewComment(logger logging.Logger, serv service.Comment) *Comment {
+func NewComment(logger logging.Logger, serv service.Comment, urls *MediaURLs) *Comment {
 	return &Comment{
 		logger: logger.With("handler", "comment"),
 		serv:   serv,
+		urls:   urls,
 	}
 }
 
@@ -36,35 +40,100 @@ func (h *Comment) List() http.HandlerFunc {
 			return httplib.NewAPIError(http.StatusBadRequest, "invalid video id").WithInternal(err)
 		}
 
-		var limit uint64 = _defaultLimit
-		if r.URL.Query().Has("limit") {
-			value, err := strconv.ParseUint(r.URL.Query().Get("limit"), 10, 64)
-			if err != nil {
-				return httplib.NewAPIError(http.StatusBadRequest, "invalid limit").WithInternal(err)
-			}
-			limit = value
+		findOpts, err := parseFindOptions(r)
+		if err != nil {
+			return err
 		}
 
-		var offset uint64 = _defaultOffset
-		if r.URL.Query().Has("offset") {
-			value, err := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
-			if err != nil {
-				return httplib.NewAPIError(http.StatusBadRequest, "invalid offset").WithInternal(err)
-			}
-			offset = value
+		var sortBy string = "newest"
+		if r.URL.Query().Has("sortBy") {
+			sortBy = r.URL.Query().Get("sortBy")
 		}
 
-		findOpts := service.FindOptions{Limit: limit, Offset: offset}
+		var (
+			find       func(ctx context.Context, videoID model.ID, opts service.FindOptions) ([]model.Comment, error)
+			cursorSort model.CursorSort
+			cursorKey  func(model.Comment) int64
+		)
+
+		switch sortBy {
+		case "newest", "latest", "createdAt":
+			find, cursorSort = h.serv.FindByVideo, model.CursorSortLatest
+			cursorKey = func(comment model.Comment) int64 { return comment.CreatedAt.Unix() }
+		case "top", "rating":
+			find, cursorSort = h.serv.FindTopByVideo, model.CursorSortRating
+			cursorKey = func(comment model.Comment) int64 { return comment.Likes - comment.Dislikes }
+		default:
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid sortBy")
+		}
+
+		if err := checkCursor(findOpts, cursorSort); err != nil {
+			return err
+		}
 
-		comments, err := h.serv.FindByVideo(r.Context(), videoID, findOpts)
+		comments, err := find(r.Context(), videoID, findOpts)
 		if err != nil {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"comments": comments})
+		next := nextCursor(findOpts, cursorSort, len(comments), func(i int) model.Cursor {
+			return model.Cursor{Key: cursorKey(comments[i]), ID: comments[i].ID}
+		})
+
+		// The pinned comment heads the first page only, the listings leave it out.
+		var pinned *model.Comment
+		if findOpts.Cursor == nil && findOpts.Offset == 0 {
+			comment, err := h.serv.GetPinned(r.Context(), videoID)
+			if err != nil && !errors.Is(err, model.ErrCommentNotFound) {
+				return err
+			}
+			if err == nil {
+				comment = h.urls.Comment(comment)
+				pinned = &comment
+			}
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{
+			"comments":   h.urls.Comments(comments),
+			"pinned":     pinned,
+			"nextCursor": next,
+		})
 	}, h.errorHandler("handler.Comment.List"))
 }
 
+func (h *Comment) ListReplies() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		findOpts, err := parseFindOptions(r)
+		if err != nil {
+			return err
+		}
+		if err := checkCursor(findOpts, model.CursorSortOldest); err != nil {
+			return err
+		}
+
+		replies, err := h.serv.FindReplies(r.Context(), commentID, findOpts)
+		if err != nil {
+			return err
+		}
+
+		next := nextCursor(findOpts, model.CursorSortOldest, len(replies), func(i int) model.Cursor {
+			return model.Cursor{Key: replies[i].CreatedAt.Unix(), ID: replies[i].ID}
+		})
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"replies": h.urls.Comments(replies), "nextCursor": next})
+	}, h.errorHandler("handler.Comment.ListReplies"))
+}
+
 func (h *Comment) Create() http.HandlerFunc {
 	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
 		videoIDRaw, ok := mux.Vars(r)["videoId"]
@@ -78,7 +147,8 @@ func (h *Comment) Create() http.HandlerFunc {
 		}
 
 		var request struct {
-			Comment string `json:"comment"`
+			Comment  string    `json:"comment"`
+			ParentID *model.ID `json:"parentId"`
 		}
 
 		if err := httplib.DecodeJSON(r, &request); err != nil {
@@ -91,15 +161,110 @@ func (h *Comment) Create() http.HandlerFunc {
 			Message:  request.Comment,
 			VideoID:  videoID,
 			AuthorID: author.ID,
+			ParentID: request.ParentID,
 		})
 		if err != nil {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"comment": comment})
+		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"comment": h.urls.Comment(comment)})
 	}, h.errorHandler("handler.Comment.List"))
 }
 
+func (h *Comment) Update() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		var request struct {
+			Comment *string `json:"comment"`
+		}
+
+		if err := httplib.DecodeJSON(r, &request); err != nil {
+			return err
+		}
+
+		comment, err := h.serv.Update(r.Context(), commentID, service.UpdateCommentDTO{
+			Message: request.Comment,
+		})
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"comment": h.urls.Comment(comment)})
+	}, h.errorHandler("handler.Comment.Update"))
+}
+
+func (h *Comment) ListEdits() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		edits, err := h.serv.FindEdits(r.Context(), commentID)
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"edits": edits})
+	}, h.errorHandler("handler.Comment.ListEdits"))
+}
+
+// Pin pins the comment or, with pinned unset, unpins it.
+func (h *Comment) Pin(pinned bool) http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		if err := h.serv.Pin(r.Context(), commentID, pinned); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Comment.Pin"))
+}
+
+// Heart hearts the comment or, with hearted unset, takes the heart back.
+func (h *Comment) Heart(hearted bool) http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		if err := h.serv.Heart(r.Context(), commentID, hearted); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Comment.Heart"))
+}
+
 func (h *Comment) Delete() http.HandlerFunc {
 	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
 		commentIDRaw, ok := mux.Vars(r)["commentId"]
@@ -123,6 +288,23 @@ func (h *Comment) Delete() http.HandlerFunc {
 func (h *Comment) errorHandler(op string) httplib.ErroHandler {
 	return func(w http.ResponseWriter, r *http.Request, err error) {
 		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
+
+		if errors.Is(err, model.ErrVideoNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrVideoNotFound.Error())
+		}
+		if errors.Is(err, model.ErrCommentNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrCommentNotFound.Error())
+		}
+		if errors.Is(err, model.ErrPinReply) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrPinReply.Error())
+		}
+		if errors.Is(err, model.ErrParentCommentMismatch) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrParentCommentMismatch.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
+
 		httplib.DefaultErrorHandler(w, r, err)
 	}
 }
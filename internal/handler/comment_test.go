@@ -0,0 +1,182 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCommentCreate(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+
+	ts.expectDenied([]route{{http.MethodPost, "/videos/" + videoID + "/comments"}})
+
+	commentID := ts.createComment(bob, videoID, "comment", nil)
+	ts.createComment(alice, videoID, "reply", &commentID)
+
+	res := ts.expect(http.MethodGet, "/videos/"+videoID+"/comments", "", nil, http.StatusOK)
+	if n := res.len(t, "comments"); n != 1 {
+		t.Fatalf("comments = %d, want 1", n)
+	}
+
+	ts.expect(http.MethodPost, "/videos/"+missingID+"/comments", bob.Token, map[string]any{
+		"comment": "comment",
+	}, http.StatusNotFound)
+	ts.expect(http.MethodPost, "/videos/"+videoID+"/comments", bob.Token, map[string]any{
+		"comment":  "reply",
+		"parentId": missingID,
+	}, http.StatusNotFound)
+}
+
+func TestCommentList(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	videoID := ts.createVideo(alice, "video")
+	ts.createComment(alice, videoID, "first", nil)
+	ts.createComment(alice, videoID, "second", nil)
+
+	res := ts.expect(http.MethodGet, "/videos/"+videoID+"/comments", "", nil, http.StatusOK)
+	if n := res.len(t, "comments"); n != 2 {
+		t.Fatalf("comments = %d, want 2", n)
+	}
+
+	ts.expect(http.MethodGet, "/videos/"+missingID+"/comments", "", nil, http.StatusNotFound)
+}
+
+func TestCommentListReplies(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	videoID := ts.createVideo(alice, "video")
+	commentID := ts.createComment(alice, videoID, "comment", nil)
+	ts.createComment(alice, videoID, "reply", &commentID)
+
+	res := ts.expect(http.MethodGet, "/comments/"+commentID+"/replies", "", nil, http.StatusOK)
+	if n := res.len(t, "replies"); n != 1 {
+		t.Fatalf("replies = %d, want 1", n)
+	}
+
+	ts.expect(http.MethodGet, "/comments/"+missingID+"/replies", "", nil, http.StatusNotFound)
+}
+
+func TestCommentUpdate(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+	commentID := ts.createComment(bob, videoID, "comment", nil)
+
+	ts.expectDenied([]route{{http.MethodPatch, "/comments/" + commentID}})
+
+	res := ts.expect(http.MethodPatch, "/comments/"+commentID, bob.Token, map[string]any{
+		"comment": "edited",
+	}, http.StatusOK)
+	if message := res.str(t, "comment", "message"); message != "edited" {
+		t.Fatalf("message = %q, want edited", message)
+	}
+
+	ts.expect(http.MethodPatch, "/comments/"+commentID, alice.Token, map[string]any{"comment": "alice"}, http.StatusForbidden)
+	ts.expect(http.MethodPatch, "/comments/"+missingID, bob.Token, map[string]any{"comment": "missing"}, http.StatusNotFound)
+
+	res = ts.expect(http.MethodGet, "/comments/"+commentID+"/edits", "", nil, http.StatusOK)
+	if n := res.len(t, "edits"); n != 1 {
+		t.Fatalf("edits = %d, want 1", n)
+	}
+
+	ts.expect(http.MethodGet, "/comments/"+missingID+"/edits", "", nil, http.StatusNotFound)
+}
+
+func TestCommentDelete(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+	commentID := ts.createComment(alice, videoID, "comment", nil)
+
+	ts.expectDenied([]route{{http.MethodDelete, "/comments/" + commentID}})
+
+	ts.expect(http.MethodDelete, "/comments/"+commentID, bob.Token, nil, http.StatusForbidden)
+	ts.expect(http.MethodDelete, "/comments/"+missingID, alice.Token, nil, http.StatusNotFound)
+
+	ts.expect(http.MethodDelete, "/comments/"+commentID, alice.Token, nil, http.StatusNoContent)
+
+	res := ts.expect(http.MethodGet, "/videos/"+videoID+"/comments", "", nil, http.StatusOK)
+	if n := res.len(t, "comments"); n != 0 {
+		t.Fatalf("comments = %d, want 0", n)
+	}
+}
+
+func TestCommentPin(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+	commentID := ts.createComment(bob, videoID, "comment", nil)
+	replyID := ts.createComment(bob, videoID, "reply", &commentID)
+	path := "/comments/" + commentID + "/pin"
+
+	ts.expectDenied([]route{
+		{http.MethodPut, path},
+		{http.MethodDelete, path},
+	})
+
+	// Only the author of the video curates its comments.
+	ts.expect(http.MethodPut, path, bob.Token, nil, http.StatusForbidden)
+	ts.expect(http.MethodDelete, path, bob.Token, nil, http.StatusForbidden)
+
+	ts.expect(http.MethodPut, path, alice.Token, nil, http.StatusNoContent)
+
+	res := ts.expect(http.MethodGet, "/videos/"+videoID+"/comments", "", nil, http.StatusOK)
+	if id := res.str(t, "pinned", "id"); id != commentID {
+		t.Fatalf("pinned = %s, want %s", id, commentID)
+	}
+
+	ts.expect(http.MethodDelete, path, alice.Token, nil, http.StatusNoContent)
+
+	res = ts.expect(http.MethodGet, "/videos/"+videoID+"/comments", "", nil, http.StatusOK)
+	if pinned := res.field(t, "pinned"); pinned != nil {
+		t.Fatalf("pinned = %v, want none", pinned)
+	}
+
+	ts.expect(http.MethodPut, "/comments/"+replyID+"/pin", alice.Token, nil, http.StatusBadRequest)
+	ts.expect(http.MethodPut, "/comments/"+missingID+"/pin", alice.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodDelete, "/comments/"+missingID+"/pin", alice.Token, nil, http.StatusNotFound)
+}
+
+func TestCommentHeart(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+	commentID := ts.createComment(bob, videoID, "comment", nil)
+	path := "/comments/" + commentID + "/heart"
+
+	ts.expectDenied([]route{
+		{http.MethodPut, path},
+		{http.MethodDelete, path},
+	})
+
+	ts.expect(http.MethodPut, path, bob.Token, nil, http.StatusForbidden)
+	ts.expect(http.MethodDelete, path, bob.Token, nil, http.StatusForbidden)
+
+	expectHearted := func(want bool) {
+		t.Helper()
+
+		res := ts.expect(http.MethodGet, "/videos/"+videoID+"/comments", "", nil, http.StatusOK)
+		if hearted := res.field(t, "comments").([]any)[0].(map[string]any)["isHearted"]; hearted != want {
+			t.Fatalf("isHearted = %v, want %v", hearted, want)
+		}
+	}
+
+	ts.expect(http.MethodPut, path, alice.Token, nil, http.StatusNoContent)
+	expectHearted(true)
+
+	ts.expect(http.MethodDelete, path, alice.Token, nil, http.StatusNoContent)
+	expectHearted(false)
+
+	ts.expect(http.MethodPut, "/comments/"+missingID+"/heart", alice.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodDelete, "/comments/"+missingID+"/heart", alice.Token, nil, http.StatusNotFound)
+}
@@ -0,0 +1,386 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/protomem/gotube/internal/app"
+	"github.com/protomem/gotube/internal/blobstore/inmem"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+	"github.com/protomem/gotube/internal/mailer/outbox"
+	"github.com/protomem/gotube/pkg/httplib"
+)
+
+// mp4 is the head of an MP4 file, enough for the media type to be detected, the fake executor does not read the rest.
+var mp4 = append([]byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"), make([]byte, 1024)...)
+
+// missingID is a well-formed id nothing has.
+const missingID = "00000000-0000-4000-8000-000000000000"
+
+type testServer struct {
+	t      *testing.T
+	url    string
+	db     *sqlite.DB
+	mailer *outbox.Mailer
+}
+
+type testUser struct {
+	ID           string
+	Nickname     string
+	Email        string
+	Token        string
+	RefreshToken string
+}
+
+type testResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// newTestServer serves the whole app over an in-memory database, blob storage and mailer.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	t.Setenv("APP_MEDIA_URL_SECRET", "test-secret")
+	t.Setenv("APP_PROCESSING_EXECUTOR", "fake")
+	t.Setenv("APP_MEDIA_GC_INTERVAL", "0")
+	t.Setenv("APP_JOBS_POLL_INTERVAL", "10ms")
+
+	logger := sqlitetest.Logger(t)
+	db := sqlitetest.Open(t)
+
+	bstore, err := inmem.New(logger)
+	if err != nil {
+		t.Fatalf("new blob storage: %v", err)
+	}
+
+	mailer, err := outbox.New(logger, "")
+	if err != nil {
+		t.Fatalf("new mailer: %v", err)
+	}
+
+	a, err := app.NewWith(logger, db, bstore, mailer)
+	if err != nil {
+		t.Fatalf("new app: %v", err)
+	}
+
+	server := httptest.NewServer(a.Handler())
+	t.Cleanup(func() {
+		server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := a.Close(ctx); err != nil {
+			t.Errorf("close app: %v", err)
+		}
+	})
+
+	return &testServer{t: t, url: server.URL, db: db, mailer: mailer}
+}
+
+// request sends the body as JSON unless it is nil, the token is left out when empty.
+func (ts *testServer) request(method, path, token string, body any) testResponse {
+	ts.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			ts.t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := ts.newRequest(method, path, token, reader)
+	if body != nil {
+		req.Header.Set(httplib.HeaderContentType, "application/json")
+	}
+
+	return ts.do(req)
+}
+
+// expect is request failing the test unless the response has the status.
+func (ts *testServer) expect(method, path, token string, body any, status int) testResponse {
+	ts.t.Helper()
+
+	res := ts.request(method, path, token, body)
+	res.expectStatus(ts.t, method+" "+path, status)
+
+	return res
+}
+
+func (ts *testServer) newRequest(method, path, token string, body io.Reader) *http.Request {
+	ts.t.Helper()
+
+	req, err := http.NewRequest(method, ts.url+path, body)
+	if err != nil {
+		ts.t.Fatalf("new request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set(httplib.HeaderAuthorization, "Bearer "+token)
+	}
+
+	return req
+}
+
+func (ts *testServer) do(req *http.Request) testResponse {
+	ts.t.Helper()
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		ts.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		ts.t.Fatalf("read body: %v", err)
+	}
+
+	return testResponse{Status: res.StatusCode, Header: res.Header, Body: body}
+}
+
+// signUp creates a user and signs them in.
+func (ts *testServer) signUp(nickname string) testUser {
+	ts.t.Helper()
+
+	user := testUser{Nickname: nickname, Email: nickname + "@example.com"}
+
+	res := ts.expect(http.MethodPost, "/users", "", map[string]any{
+		"nickname": nickname,
+		"email":    user.Email,
+		"password": "password",
+	}, http.StatusCreated)
+	user.ID = res.str(ts.t, "user", "id")
+
+	return ts.signIn(user, "password")
+}
+
+func (ts *testServer) signIn(user testUser, password string) testUser {
+	ts.t.Helper()
+
+	res := ts.expect(http.MethodPost, "/auth/login", "", map[string]any{
+		"email":    user.Email,
+		"password": password,
+	}, http.StatusOK)
+	user.Token = res.str(ts.t, "accesssToken")
+	user.RefreshToken = res.str(ts.t, "refreshToken")
+
+	return user
+}
+
+// makeAdmin grants the user the admin role, which takes a new sign in since it invalidates their tokens.
+func (ts *testServer) makeAdmin(user testUser) testUser {
+	ts.t.Helper()
+
+	if err := ts.db.Exec(context.Background(), `UPDATE users SET role = 'admin' WHERE id = ?`, user.ID); err != nil {
+		ts.t.Fatalf("make admin: %v", err)
+	}
+
+	return ts.signIn(user, "password")
+}
+
+// saveMedia uploads a file of the kind into the folder of the user.
+func (ts *testServer) saveMedia(user testUser, kind string, content []byte) testResponse {
+	ts.t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	if err := form.WriteField("kind", kind); err != nil {
+		ts.t.Fatalf("write kind: %v", err)
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="file"`)
+	header.Set(httplib.HeaderContentType, "application/octet-stream")
+
+	part, err := form.CreatePart(header)
+	if err != nil {
+		ts.t.Fatalf("create file part: %v", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		ts.t.Fatalf("write file part: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		ts.t.Fatalf("close form: %v", err)
+	}
+
+	req := ts.newRequest(http.MethodPost, "/media/"+user.ID+"/file", user.Token, &body)
+	req.Header.Set(httplib.HeaderContentType, form.FormDataContentType())
+
+	return ts.do(req)
+}
+
+// createVideo publishes a video of the user and waits for it to be processed.
+func (ts *testServer) createVideo(user testUser, title string) string {
+	ts.t.Helper()
+
+	res := ts.saveMedia(user, "video", mp4)
+	res.expectStatus(ts.t, "save video media", http.StatusCreated)
+
+	res = ts.expect(http.MethodPost, "/videos", user.Token, map[string]any{
+		"title":        title,
+		"videoMediaId": res.str(ts.t, "media", "id"),
+		"public":       true,
+	}, http.StatusCreated)
+	videoID := res.str(ts.t, "video", "id")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		res := ts.expect(http.MethodGet, "/videos/"+videoID, user.Token, nil, http.StatusOK)
+
+		switch status := res.str(ts.t, "video", "status"); status {
+		case "ready":
+			return videoID
+		case "failed":
+			ts.t.Fatalf("video %s failed to process", videoID)
+		}
+
+		if time.Now().After(deadline) {
+			ts.t.Fatalf("video %s is not processed in time", videoID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (ts *testServer) createComment(user testUser, videoID, message string, parentID *string) string {
+	ts.t.Helper()
+
+	res := ts.expect(http.MethodPost, "/videos/"+videoID+"/comments", user.Token, map[string]any{
+		"comment":  message,
+		"parentId": parentID,
+	}, http.StatusCreated)
+
+	return res.str(ts.t, "comment", "id")
+}
+
+// mailToken returns the token of the link in the last mail sent to the address.
+func (ts *testServer) mailToken(email string) string {
+	ts.t.Helper()
+
+	messages := ts.mailer.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if len(msg.To) == 0 || msg.To[0] != email {
+			continue
+		}
+
+		_, link, ok := strings.Cut(msg.Body, "?token=")
+		if !ok {
+			continue
+		}
+
+		token, err := url.QueryUnescape(strings.Fields(link)[0])
+		if err != nil {
+			ts.t.Fatalf("unescape token: %v", err)
+		}
+
+		return token
+	}
+
+	ts.t.Fatalf("no mail with a token sent to %s", email)
+	return ""
+}
+
+func (res testResponse) expectStatus(t *testing.T, name string, status int) {
+	t.Helper()
+
+	if res.Status != status {
+		t.Fatalf("%s: status = %d, want %d, body = %s", name, res.Status, status, res.Body)
+	}
+}
+
+// field returns the value at the path of keys in the JSON body.
+func (res testResponse) field(t *testing.T, keys ...string) any {
+	t.Helper()
+
+	var value any
+	if err := json.Unmarshal(res.Body, &value); err != nil {
+		t.Fatalf("decode body %s: %v", res.Body, err)
+	}
+
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			t.Fatalf("no %q in %s", key, res.Body)
+		}
+
+		value, ok = object[key]
+		if !ok {
+			t.Fatalf("no %q in %s", key, res.Body)
+		}
+	}
+
+	return value
+}
+
+func (res testResponse) str(t *testing.T, keys ...string) string {
+	t.Helper()
+
+	value, ok := res.field(t, keys...).(string)
+	if !ok {
+		t.Fatalf("%v is not a string in %s", keys, res.Body)
+	}
+
+	return value
+}
+
+func (res testResponse) len(t *testing.T, keys ...string) int {
+	t.Helper()
+
+	value, ok := res.field(t, keys...).([]any)
+	if !ok {
+		t.Fatalf("%v is not a list in %s", keys, res.Body)
+	}
+
+	return len(value)
+}
+
+type route struct {
+	method string
+	path   string
+}
+
+// expectDenied checks the routes refuse requests without a valid token.
+func (ts *testServer) expectDenied(routes []route) {
+	ts.t.Helper()
+
+	for _, r := range routes {
+		// Anonymous requests are not allowed in.
+		ts.expect(r.method, r.path, "", nil, http.StatusForbidden)
+		// A token that does not verify is not an identity.
+		ts.expect(r.method, r.path, "invalid", nil, http.StatusUnauthorized)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	ts := newTestServer(t)
+
+	res := ts.expect(http.MethodGet, "/health", "", nil, http.StatusOK)
+	if status := res.str(t, "status"); status != "OK" {
+		t.Fatalf("status = %q, want OK", status)
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	ts := newTestServer(t)
+
+	ts.expect(http.MethodGet, "/unknown", "", nil, http.StatusNotFound)
+	ts.expect(http.MethodPatch, "/health", "", nil, http.StatusMethodNotAllowed)
+}
@@ -10,22 +10,31 @@ type Handlers struct {
 	*Common
 	*User
 	*Auth
+	*Account
 	*Subscription
 	*Video
 	*Rating
 	*Comment
 	*Media
+	*Upload
+	*Admin
 }
 
-func New(logger logging.Logger, servs *service.Services, bstore blobstore.Storage) *Handlers {
+func New(
+	logger logging.Logger,
+	servs *service.Services, bstore blobstore.Storage, gc MediaCollector, urls *MediaURLs,
+) *Handlers {
 	return &Handlers{
 		Common:       NewCommon(),
-		User:         NewUser(logger, servs.User),
-		Auth:         NewAuth(logger, servs.Auth),
-		Subscription: NewSubscription(logger, servs.Subscription),
-		Video:        NewVideo(logger, servs.Video),
+		User:         NewUser(logger, servs.User, servs.Account, urls),
+		Auth:         NewAuth(logger, servs.Auth, urls),
+		Account:      NewAccount(logger, servs.Account, urls),
+		Subscription: NewSubscription(logger, servs.Subscription, urls),
+		Video:        NewVideo(logger, servs.Video, servs.Rating, urls),
 		Rating:       NewRating(logger, servs.Rating),
-		Comment:      NewComment(logger, servs.Comment),
-		Media:        NewMedia(logger, bstore),
+		Comment:      NewComment(logger, servs.Comment, urls),
+		Media:        NewMedia(logger, servs.Media, bstore, urls),
+		Upload:       NewUpload(logger, servs.Upload),
+		Admin:        NewAdmin(logger, servs.User, gc, urls),
 	}
 }
@@ -2,26 +2,34 @@ package handler
 
 import (
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/service"
 	"github.com/protomem/gotube/pkg/httplib"
 	"github.com/protomem/gotube/pkg/logging"
+	"github.com/protomem/gotube/pkg/urlsign"
 )
 
 const _defaultMediaMaxUploadSize = 1024 * 1024 * 100 // 100MB
 
 type Media struct {
 	logger logging.Logger
+	serv   service.Media
 	bstore blobstore.Storage
+	urls   *MediaURLs
 }
 
-func NewMedia(logger logging.Logger, bstore blobstore.Storage) *Media {
+func NewMedia(logger logging.Logger, serv service.Media, bstore blobstore.Storage, urls *MediaURLs) *Media {
 	return &Media{
 		logger: logger.With("handler", "media"),
+		serv:   serv,
 		bstore: bstore,
+		urls:   urls,
 	}
 }
 
@@ -37,17 +45,52 @@ func (h *Media) Get() http.HandlerFunc {
 			return httplib.WriteJSON(w, http.StatusBadRequest, httplib.JSON{"message": "missing file name"})
 		}
 
-		obj, err := h.bstore.Get(r.Context(), parentName, fileName)
-		if err != nil {
+		// Folders starting with an underscore are internal, like parts of unfinished uploads.
+		if !service.ValidMediaName(parentName) || !service.ValidMediaName(fileName) {
+			return blobstore.ErrObjectNotFound
+		}
+
+		// Links are handed out by the API to those allowed to see the file.
+		if err := h.urls.Verify(parentName, fileName, r.URL.Query()); err != nil {
 			return err
 		}
 
-		w.Header().Set("Content-Type", obj.Type)
+		// The size picks a smaller copy of an image, the signature of the image covers it.
+		if r.URL.Query().Has("size") {
+			size, err := strconv.Atoi(r.URL.Query().Get("size"))
+			if err != nil || size <= 0 {
+				return httplib.NewAPIError(http.StatusBadRequest, "invalid size")
+			}
+
+			fileName, err = h.serv.Variant(r.Context(), parentName, fileName, size)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Storages with direct links serve the file themselves.
+		// The link is signed for GET only, so HEAD is still answered here.
+		if presigner, ok := h.bstore.(blobstore.Presigner); ok && r.Method == http.MethodGet {
+			url, err := presigner.PresignGet(r.Context(), parentName, fileName)
+			if err != nil {
+				return err
+			}
 
-		buff := make([]byte, 1024)
-		if _, err := io.CopyBuffer(w, obj.Body, buff); err != nil {
+			http.Redirect(w, r, url, http.StatusFound)
+			return nil
+		}
+
+		obj, err := h.bstore.Open(r.Context(), parentName, fileName)
+		if err != nil {
 			return err
 		}
+		defer func() { _ = obj.Body.Close() }()
+
+		w.Header().Set(httplib.HeaderContentType, obj.Type)
+		w.Header().Set(httplib.HeaderETag, fmt.Sprintf(`"%x-%x"`, obj.ModTime.UnixNano(), obj.Size))
+
+		// ServeContent handles Range, conditional requests and HEAD.
+		http.ServeContent(w, r, fileName, obj.ModTime, obj.Body)
 
 		return nil
 	}, h.errorHandler("handler.Media.Get"))
@@ -60,8 +103,8 @@ func (h *Media) Save() http.HandlerFunc {
 			return httplib.WriteJSON(w, http.StatusBadRequest, httplib.JSON{"message": "missing parent name"})
 		}
 
-		fileName, ok := mux.Vars(r)["file"]
-		if !ok {
+		// The name is only a hint of the client, media are named after their ID and detected type.
+		if _, ok := mux.Vars(r)["file"]; !ok {
 			return httplib.WriteJSON(w, http.StatusBadRequest, httplib.JSON{"message": "missing file name"})
 		}
 
@@ -76,17 +119,18 @@ func (h *Media) Save() http.HandlerFunc {
 		}
 		defer func() { _ = file.Close() }()
 
-		obj := blobstore.Object{
-			Type: fileHeader.Header.Get("Content-Type"),
-			Size: fileHeader.Size,
-			Body: file,
-		}
-
-		if err := h.bstore.Put(r.Context(), parentName, fileName, obj); err != nil {
+		media, err := h.serv.Save(r.Context(), service.SaveMediaDTO{
+			Parent: parentName,
+			Kind:   model.MediaKind(r.FormValue("kind")),
+			Type:   fileHeader.Header.Get("Content-Type"),
+			Size:   fileHeader.Size,
+			Body:   file,
+		})
+		if err != nil {
 			return err
 		}
 
-		return httplib.SendStatus(w, http.StatusCreated)
+		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"media": media})
 	}, h.errorHandler("handler.Media.Save"))
 }
 
@@ -102,7 +146,7 @@ func (h *Media) Delete() http.HandlerFunc {
 			return httplib.WriteJSON(w, http.StatusBadRequest, httplib.JSON{"message": "missing file name"})
 		}
 
-		if err := h.bstore.Del(r.Context(), parentName, fileName); err != nil {
+		if err := h.serv.Delete(r.Context(), parentName, fileName); err != nil {
 			return err
 		}
 
@@ -117,6 +161,29 @@ func (h *Media) errorHandler(op string) httplib.ErroHandler {
 		if errors.Is(err, blobstore.ErrObjectNotFound) {
 			err = httplib.NewAPIError(http.StatusNotFound, blobstore.ErrObjectNotFound.Error())
 		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
+		if errors.Is(err, model.ErrInvalidMediaKind) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrInvalidMediaKind.Error())
+		}
+		if errors.Is(err, model.ErrInvalidMediaName) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrInvalidMediaName.Error())
+		}
+		if errors.Is(err, model.ErrMediaTypeNotAllowed) {
+			err = httplib.NewAPIError(http.StatusUnsupportedMediaType, model.ErrMediaTypeNotAllowed.Error())
+		}
+		if errors.Is(err, model.ErrMediaTypeMismatch) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaTypeMismatch.Error())
+		}
+		if errors.Is(err, model.ErrMediaTooLarge) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaTooLarge.Error())
+		}
+		if errors.Is(err, urlsign.ErrMissingSignature) ||
+			errors.Is(err, urlsign.ErrInvalidSignature) ||
+			errors.Is(err, urlsign.ErrExpired) {
+			err = httplib.NewAPIError(http.StatusForbidden, err.Error())
+		}
 
 		httplib.DefaultErrorHandler(w, r, err)
 	}
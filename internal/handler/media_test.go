@@ -0,0 +1,73 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMediaSave(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	ts.expectDenied([]route{{http.MethodPost, "/media/" + alice.ID + "/file"}})
+
+	res := ts.saveMedia(alice, "video", mp4)
+	res.expectStatus(t, "save media", http.StatusCreated)
+	if parent := res.str(t, "media", "parent"); parent != alice.ID {
+		t.Fatalf("parent = %s, want %s", parent, alice.ID)
+	}
+
+	// Files go to the folder of their owner only.
+	bob.ID = alice.ID
+	ts.saveMedia(bob, "video", mp4).expectStatus(t, "save media of another user", http.StatusForbidden)
+
+	ts.saveMedia(alice, "unknown", mp4).expectStatus(t, "save media of unknown kind", http.StatusBadRequest)
+}
+
+func TestMediaGet(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	videoID := ts.createVideo(alice, "video")
+
+	res := ts.expect(http.MethodGet, "/videos/"+videoID, "", nil, http.StatusOK)
+	videoPath := res.str(t, "video", "videoPath")
+
+	res = ts.expect(http.MethodGet, videoPath, "", nil, http.StatusOK)
+	if typ := res.Header.Get("Content-Type"); typ != "video/mp4" {
+		t.Fatalf("content type = %q, want video/mp4", typ)
+	}
+	ts.expect(http.MethodHead, videoPath, "", nil, http.StatusOK)
+
+	// Links are only served with the signature the API handed out.
+	unsigned, _, _ := strings.Cut(videoPath, "?")
+	ts.expect(http.MethodGet, unsigned, "", nil, http.StatusForbidden)
+	ts.expect(http.MethodHead, unsigned, "", nil, http.StatusForbidden)
+
+	ts.expect(http.MethodGet, "/media/"+alice.ID+"/_internal", "", nil, http.StatusNotFound)
+
+	// A signed link outlives its file.
+	ts.expect(http.MethodDelete, unsigned, alice.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodGet, videoPath, "", nil, http.StatusNotFound)
+	ts.expect(http.MethodHead, videoPath, "", nil, http.StatusNotFound)
+}
+
+func TestMediaDelete(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	res := ts.saveMedia(alice, "video", mp4)
+	res.expectStatus(t, "save media", http.StatusCreated)
+	path := "/media/" + alice.ID + "/" + res.str(t, "media", "name")
+
+	ts.expectDenied([]route{{http.MethodDelete, path}})
+
+	ts.expect(http.MethodDelete, path, bob.Token, nil, http.StatusForbidden)
+
+	ts.expect(http.MethodDelete, path, alice.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodDelete, path, alice.Token, nil, http.StatusNotFound)
+}
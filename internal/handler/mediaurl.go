@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/pkg/urlsign"
+)
+
+const _mediaPathPrefix = "/media/"
+
+// MediaURLs signs the media paths of responses, Media.Get serves only signed paths.
+// Handlers sign a model once the service has checked the requester may see it.
+type MediaURLs struct {
+	signer *urlsign.Signer
+}
+
+func NewMediaURLs(signer *urlsign.Signer) *MediaURLs {
+	return &MediaURLs{signer: signer}
+}
+
+// Path signs a media path, external URLs are returned unchanged.
+func (m *MediaURLs) Path(path string) string {
+	if !strings.HasPrefix(path, _mediaPathPrefix) {
+		return path
+	}
+	return m.signer.Sign(path, time.Now())
+}
+
+// Verify checks the signature of a request for the media file.
+func (m *MediaURLs) Verify(parent, name string, query url.Values) error {
+	return m.signer.Verify(_mediaPathPrefix+parent+"/"+name, query, time.Now())
+}
+
+func (m *MediaURLs) User(user model.User) model.User {
+	user.AvatarPath = m.Path(user.AvatarPath)
+	return user
+}
+
+func (m *MediaURLs) Users(users []model.User) []model.User {
+	for i := range users {
+		users[i] = m.User(users[i])
+	}
+	return users
+}
+
+func (m *MediaURLs) Video(video model.Video) model.Video {
+	video.ThumbnailPath = m.Path(video.ThumbnailPath)
+	video.VideoPath = m.Path(video.VideoPath)
+	video.Author = m.User(video.Author)
+	return video
+}
+
+func (m *MediaURLs) Videos(videos []model.Video) []model.Video {
+	for i := range videos {
+		videos[i] = m.Video(videos[i])
+	}
+	return videos
+}
+
+func (m *MediaURLs) Subscriptions(subs []model.Subscription) []model.Subscription {
+	for i := range subs {
+		if subs[i].User != nil {
+			user := m.User(*subs[i].User)
+			subs[i].User = &user
+		}
+	}
+	return subs
+}
+
+func (m *MediaURLs) Comment(comment model.Comment) model.Comment {
+	comment.Author = m.User(comment.Author)
+	return comment
+}
+
+func (m *MediaURLs) Comments(comments []model.Comment) []model.Comment {
+	for i := range comments {
+		comments[i] = m.Comment(comments[i])
+	}
+	return comments
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/service"
+	"github.com/protomem/gotube/pkg/httplib"
+)
+
+const (
+	_defaultLimit  = 10
+	_defaultOffset = 0
+)
+
+// parseFindOptions reads the limit and either the offset or the cursor of a listing,
+// the offset is kept for clients which do not use cursors yet.
+func parseFindOptions(r *http.Request) (service.FindOptions, error) {
+	opts := service.FindOptions{Limit: _defaultLimit, Offset: _defaultOffset}
+
+	if r.URL.Query().Has("limit") {
+		value, err := strconv.ParseUint(r.URL.Query().Get("limit"), 10, 64)
+		if err != nil {
+			return service.FindOptions{}, httplib.NewAPIError(http.StatusBadRequest, "invalid limit").WithInternal(err)
+		}
+		opts.Limit = value
+	}
+
+	if r.URL.Query().Has("offset") {
+		value, err := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
+		if err != nil {
+			return service.FindOptions{}, httplib.NewAPIError(http.StatusBadRequest, "invalid offset").WithInternal(err)
+		}
+		opts.Offset = value
+	}
+
+	if r.URL.Query().Has("cursor") {
+		if r.URL.Query().Has("offset") {
+			return service.FindOptions{}, httplib.NewAPIError(http.StatusBadRequest, "cursor and offset are exclusive")
+		}
+
+		cursor, err := model.ParseCursor(r.URL.Query().Get("cursor"))
+		if err != nil {
+			return service.FindOptions{}, httplib.NewAPIError(http.StatusBadRequest, "invalid cursor").WithInternal(err)
+		}
+		opts.Cursor = &cursor
+	}
+
+	return opts, nil
+}
+
+// checkCursor refuses a cursor made for a listing in another order, its key would select unrelated rows.
+func checkCursor(opts service.FindOptions, sort model.CursorSort) error {
+	if opts.Cursor != nil && opts.Cursor.Sort != sort {
+		return httplib.NewAPIError(http.StatusBadRequest, "cursor belongs to another listing")
+	}
+
+	return nil
+}
+
+// nextCursor returns the encoded cursor of the page after a page of n items sorted in the order,
+// or nil when the page is the last one. cursorAt makes the cursor of the last item.
+func nextCursor(opts service.FindOptions, sort model.CursorSort, n int, cursorAt func(i int) model.Cursor) *string {
+	if n == 0 || uint64(n) < opts.Limit {
+		return nil
+	}
+
+	cursor := cursorAt(n - 1)
+	cursor.Sort = sort
+
+	encoded := cursor.String()
+	return &encoded
+}
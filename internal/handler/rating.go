@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/protomem/gotube/internal/ctxstore"
+	"github.com/protomem/gotube/internal/model"
 	"github.com/protomem/gotube/internal/service"
 	"github.com/protomem/gotube/pkg/httplib"
 	"github.com/protomem/gotube/pkg/logging"
@@ -40,10 +42,25 @@ func (h *Rating) Count() http.HandlerFunc {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{
+		resp := httplib.JSON{
 			"likes":    likes,
 			"dislikes": dislikes,
-		})
+		}
+
+		// myRating is only known for signed in users and is null when they have not rated the video.
+		if user, isAuth := ctxstore.User(r.Context()); isAuth {
+			resp["myRating"] = nil
+
+			rating, err := h.serv.Get(r.Context(), service.RatingDTO{UserID: user.ID, VideoID: videoID})
+			if err != nil && !errors.Is(err, model.ErrRatingNotFound) {
+				return err
+			}
+			if err == nil {
+				resp["myRating"] = rating.Value()
+			}
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, resp)
 	}, h.errorHandler("handler.Rating.Count"))
 }
 
@@ -113,9 +130,86 @@ func (h *Rating) Delete() http.HandlerFunc {
 	}, h.errorHandler("handler.Rating.Delete"))
 }
 
+func (h *Rating) LikeComment() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		user := ctxstore.MustUser(r.Context())
+
+		if err := h.serv.LikeComment(r.Context(), service.CommentRatingDTO{UserID: user.ID, CommentID: commentID}); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Rating.LikeComment"))
+}
+
+func (h *Rating) DislikeComment() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		user := ctxstore.MustUser(r.Context())
+
+		if err := h.serv.DislikeComment(r.Context(), service.CommentRatingDTO{UserID: user.ID, CommentID: commentID}); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Rating.DislikeComment"))
+}
+
+func (h *Rating) DeleteComment() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		commentIDRaw, ok := mux.Vars(r)["commentId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing comment id")
+		}
+
+		commentID, err := uuid.Parse(commentIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid comment id").WithInternal(err)
+		}
+
+		user := ctxstore.MustUser(r.Context())
+
+		if err := h.serv.DeleteCommentRating(r.Context(), service.CommentRatingDTO{UserID: user.ID, CommentID: commentID}); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Rating.DeleteComment"))
+}
+
 func (h *Rating) errorHandler(op string) httplib.ErroHandler {
 	return func(w http.ResponseWriter, r *http.Request, err error) {
 		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
+
+		if errors.Is(err, model.ErrVideoNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrVideoNotFound.Error())
+		}
+		if errors.Is(err, model.ErrCommentNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrCommentNotFound.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
+
 		httplib.DefaultErrorHandler(w, r, err)
 	}
 }
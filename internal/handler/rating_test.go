@@ -0,0 +1,101 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRatingVideo(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+	path := "/videos/" + videoID + "/rating"
+
+	ts.expectDenied([]route{
+		{http.MethodPost, path + "/like"},
+		{http.MethodPost, path + "/dislike"},
+		{http.MethodDelete, path},
+	})
+
+	expectRating := func(likes, dislikes float64, myRating any) {
+		t.Helper()
+
+		res := ts.expect(http.MethodGet, path, bob.Token, nil, http.StatusOK)
+		if got := res.field(t, "likes"); got != likes {
+			t.Fatalf("likes = %v, want %v", got, likes)
+		}
+		if got := res.field(t, "dislikes"); got != dislikes {
+			t.Fatalf("dislikes = %v, want %v", got, dislikes)
+		}
+		if got := res.field(t, "myRating"); got != myRating {
+			t.Fatalf("myRating = %v, want %v", got, myRating)
+		}
+	}
+
+	expectRating(0, 0, nil)
+
+	ts.expect(http.MethodPost, path+"/like", bob.Token, nil, http.StatusNoContent)
+	expectRating(1, 0, "like")
+
+	ts.expect(http.MethodPost, path+"/dislike", bob.Token, nil, http.StatusNoContent)
+	expectRating(0, 1, "dislike")
+
+	ts.expect(http.MethodDelete, path, bob.Token, nil, http.StatusNoContent)
+	expectRating(0, 0, nil)
+
+	// myRating is left out for anonymous viewers.
+	res := ts.expect(http.MethodGet, path, "", nil, http.StatusOK)
+	if _, ok := res.field(t).(map[string]any)["myRating"]; ok {
+		t.Fatalf("myRating in anonymous response %s", res.Body)
+	}
+
+	missing := "/videos/" + missingID + "/rating"
+	ts.expect(http.MethodGet, missing, "", nil, http.StatusNotFound)
+	ts.expect(http.MethodPost, missing+"/like", bob.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodPost, missing+"/dislike", bob.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodDelete, missing, bob.Token, nil, http.StatusNotFound)
+}
+
+func TestRatingComment(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+	commentID := ts.createComment(alice, videoID, "comment", nil)
+	path := "/comments/" + commentID + "/rating"
+
+	ts.expectDenied([]route{
+		{http.MethodPost, path + "/like"},
+		{http.MethodPost, path + "/dislike"},
+		{http.MethodDelete, path},
+	})
+
+	expectRating := func(likes, dislikes float64) {
+		t.Helper()
+
+		res := ts.expect(http.MethodGet, "/videos/"+videoID+"/comments", "", nil, http.StatusOK)
+		if got := res.field(t, "comments").([]any)[0].(map[string]any)["likes"]; got != likes {
+			t.Fatalf("likes = %v, want %v", got, likes)
+		}
+		if got := res.field(t, "comments").([]any)[0].(map[string]any)["dislikes"]; got != dislikes {
+			t.Fatalf("dislikes = %v, want %v", got, dislikes)
+		}
+	}
+
+	ts.expect(http.MethodPost, path+"/like", bob.Token, nil, http.StatusNoContent)
+	expectRating(1, 0)
+
+	ts.expect(http.MethodPost, path+"/dislike", bob.Token, nil, http.StatusNoContent)
+	expectRating(0, 1)
+
+	ts.expect(http.MethodDelete, path, bob.Token, nil, http.StatusNoContent)
+	expectRating(0, 0)
+
+	missing := "/comments/" + missingID + "/rating"
+	ts.expect(http.MethodPost, missing+"/like", bob.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodPost, missing+"/dislike", bob.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodDelete, missing, bob.Token, nil, http.StatusNotFound)
+}
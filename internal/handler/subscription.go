@@ -16,12 +16,14 @@ import (
 type Subscription struct {
 	logger logging.Logger
 	serv   service.Subscription
+	urls   *MediaURLs
 }
 
-func NewSubscription(logger logging.Logger, serv service.Subscription) *Subscription {
+func NewSubscription(logger logging.Logger, serv service.Subscription, urls *MediaURLs) *Subscription {
 	return &Subscription{
 		logger: logger.With("handler", "subscription"),
 		serv:   serv,
+		urls:   urls,
 	}
 }
 
@@ -37,12 +39,65 @@ func (h *Subscription) Count() http.HandlerFunc {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusInternalServerError, httplib.JSON{
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{
 			"subscribers": strconv.FormatInt(count, 10),
 		})
 	}, h.errorHandler("handler.Subscription.Count"))
 }
 
+func (h *Subscription) ListSubscriptions() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		findOpts, err := parseFindOptions(r)
+		if err != nil {
+			return err
+		}
+		if err := checkCursor(findOpts, model.CursorSortLatest); err != nil {
+			return err
+		}
+
+		user := ctxstore.MustUser(r.Context())
+
+		subs, err := h.serv.FindSubscriptions(r.Context(), user.Nickname, findOpts)
+		if err != nil {
+			return err
+		}
+
+		next := nextCursor(findOpts, model.CursorSortLatest, len(subs), func(i int) model.Cursor {
+			return model.Cursor{Key: subs[i].CreatedAt.Unix(), ID: subs[i].ID}
+		})
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"subscriptions": h.urls.Subscriptions(subs), "nextCursor": next})
+	}, h.errorHandler("handler.Subscription.ListSubscriptions"))
+}
+
+func (h *Subscription) ListSubscribers() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		userNickname, ok := mux.Vars(r)["userNickname"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing nickname")
+		}
+
+		findOpts, err := parseFindOptions(r)
+		if err != nil {
+			return err
+		}
+		if err := checkCursor(findOpts, model.CursorSortLatest); err != nil {
+			return err
+		}
+
+		subs, err := h.serv.FindSubscribers(r.Context(), userNickname, findOpts)
+		if err != nil {
+			return err
+		}
+
+		next := nextCursor(findOpts, model.CursorSortLatest, len(subs), func(i int) model.Cursor {
+			return model.Cursor{Key: subs[i].CreatedAt.Unix(), ID: subs[i].ID}
+		})
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"subscribers": h.urls.Subscriptions(subs), "nextCursor": next})
+	}, h.errorHandler("handler.Subscription.ListSubscribers"))
+}
+
 func (h *Subscription) Subscribe() http.HandlerFunc {
 	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
 		toUserNickname, ok := mux.Vars(r)["userNickname"]
@@ -90,6 +145,9 @@ func (h *Subscription) errorHandler(op string) httplib.ErroHandler {
 		if errors.Is(err, model.ErrUserNotFound) {
 			err = httplib.NewAPIError(http.StatusNotFound, model.ErrUserNotFound.Error())
 		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
 
 		httplib.DefaultErrorHandler(w, r, err)
 	}
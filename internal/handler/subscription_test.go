@@ -0,0 +1,66 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSubscriptionSubscribe(t *testing.T) {
+	ts := newTestServer(t)
+	ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	ts.expectDenied([]route{
+		{http.MethodGet, "/subs"},
+		{http.MethodPost, "/subs/alice"},
+		{http.MethodDelete, "/subs/alice"},
+	})
+
+	ts.expect(http.MethodPost, "/subs/alice", bob.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodPost, "/subs/nobody", bob.Token, nil, http.StatusNotFound)
+
+	res := ts.expect(http.MethodGet, "/subs", bob.Token, nil, http.StatusOK)
+	if n := res.len(t, "subscriptions"); n != 1 {
+		t.Fatalf("subscriptions = %d, want 1", n)
+	}
+
+	ts.expect(http.MethodDelete, "/subs/alice", bob.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodDelete, "/subs/nobody", bob.Token, nil, http.StatusNotFound)
+
+	res = ts.expect(http.MethodGet, "/subs", bob.Token, nil, http.StatusOK)
+	if n := res.len(t, "subscriptions"); n != 0 {
+		t.Fatalf("subscriptions = %d, want 0", n)
+	}
+}
+
+func TestSubscriptionCount(t *testing.T) {
+	ts := newTestServer(t)
+	ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	ts.expect(http.MethodPost, "/subs/alice", bob.Token, nil, http.StatusNoContent)
+
+	res := ts.expect(http.MethodGet, "/subs/alice", "", nil, http.StatusOK)
+	if count := res.str(t, "subscribers"); count != "1" {
+		t.Fatalf("subscribers = %s, want 1", count)
+	}
+
+	ts.expect(http.MethodGet, "/subs/nobody", "", nil, http.StatusNotFound)
+}
+
+func TestSubscriptionListSubscribers(t *testing.T) {
+	ts := newTestServer(t)
+	ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	ts.expect(http.MethodPost, "/subs/alice", bob.Token, nil, http.StatusNoContent)
+
+	res := ts.expect(http.MethodGet, "/users/alice/subscribers", "", nil, http.StatusOK)
+	if n := res.len(t, "subscribers"); n != 1 {
+		t.Fatalf("subscribers = %d, want 1", n)
+	}
+
+	ts.expect(http.MethodGet, "/users/nobody/subscribers", "", nil, http.StatusNotFound)
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/service"
+	"github.com/protomem/gotube/pkg/httplib"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+type Upload struct {
+	logger logging.Logger
+	serv   service.Upload
+}
+
+func NewUpload(logger logging.Logger, serv service.Upload) *Upload {
+	return &Upload{
+		logger: logger.With("handler", "upload"),
+		serv:   serv,
+	}
+}
+
+func (h *Upload) Create() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		var request struct {
+			Kind   model.MediaKind `json:"kind"`
+			Parent string          `json:"parent"`
+			Name   string          `json:"name"`
+			Type   string          `json:"type"`
+			Size   int64           `json:"size"`
+		}
+
+		if err := httplib.DecodeJSON(r, &request); err != nil {
+			return err
+		}
+
+		upload, err := h.serv.Create(r.Context(), service.CreateUploadDTO(request))
+		if err != nil {
+			return err
+		}
+
+		h.writeProgress(w, upload)
+		w.Header().Set(httplib.HeaderLocation, "/uploads/"+upload.ID.String())
+
+		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"upload": upload})
+	}, h.errorHandler("handler.Upload.Create"))
+}
+
+func (h *Upload) Progress() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		uploadID, err := h.uploadID(r)
+		if err != nil {
+			return err
+		}
+
+		upload, err := h.serv.Get(r.Context(), uploadID)
+		if err != nil {
+			return err
+		}
+
+		h.writeProgress(w, upload)
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"upload": upload})
+	}, h.errorHandler("handler.Upload.Progress"))
+}
+
+func (h *Upload) WriteChunk() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		uploadID, err := h.uploadID(r)
+		if err != nil {
+			return err
+		}
+
+		offset, err := strconv.ParseInt(r.Header.Get(httplib.HeaderUploadOffset), 10, 64)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid upload offset").WithInternal(err)
+		}
+
+		if r.ContentLength <= 0 {
+			return httplib.NewAPIError(http.StatusLengthRequired, "missing content length")
+		}
+
+		upload, err := h.serv.WriteChunk(r.Context(), uploadID, service.WriteChunkDTO{
+			Offset: offset,
+			Size:   r.ContentLength,
+			Body:   http.MaxBytesReader(w, r.Body, r.ContentLength),
+		})
+		if err != nil {
+			return err
+		}
+
+		h.writeProgress(w, upload)
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Upload.WriteChunk"))
+}
+
+func (h *Upload) Complete() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		uploadID, err := h.uploadID(r)
+		if err != nil {
+			return err
+		}
+
+		media, err := h.serv.Complete(r.Context(), uploadID)
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"media": media})
+	}, h.errorHandler("handler.Upload.Complete"))
+}
+
+func (h *Upload) Abort() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		uploadID, err := h.uploadID(r)
+		if err != nil {
+			return err
+		}
+
+		if err := h.serv.Abort(r.Context(), uploadID); err != nil {
+			return err
+		}
+
+		return httplib.NoContent(w)
+	}, h.errorHandler("handler.Upload.Abort"))
+}
+
+func (*Upload) uploadID(r *http.Request) (model.ID, error) {
+	uploadIDRaw, ok := mux.Vars(r)["uploadId"]
+	if !ok {
+		return model.ID{}, httplib.NewAPIError(http.StatusBadRequest, "missing upload id")
+	}
+
+	uploadID, err := uuid.Parse(uploadIDRaw)
+	if err != nil {
+		return model.ID{}, httplib.NewAPIError(http.StatusBadRequest, "invalid upload id").WithInternal(err)
+	}
+
+	return uploadID, nil
+}
+
+func (*Upload) writeProgress(w http.ResponseWriter, upload model.Upload) {
+	w.Header().Set(httplib.HeaderUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set(httplib.HeaderUploadLength, strconv.FormatInt(upload.Size, 10))
+}
+
+func (h *Upload) errorHandler(op string) httplib.ErroHandler {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
+
+		if errors.Is(err, model.ErrUploadNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrUploadNotFound.Error())
+		}
+		if errors.Is(err, model.ErrUploadOffsetMismatch) {
+			err = httplib.NewAPIError(http.StatusConflict, model.ErrUploadOffsetMismatch.Error())
+		}
+		if errors.Is(err, model.ErrUploadIncomplete) {
+			err = httplib.NewAPIError(http.StatusConflict, model.ErrUploadIncomplete.Error())
+		}
+		if errors.Is(err, model.ErrUploadTooLarge) {
+			err = httplib.NewAPIError(http.StatusRequestEntityTooLarge, model.ErrUploadTooLarge.Error())
+		}
+		if errors.Is(err, model.ErrInvalidMediaKind) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrInvalidMediaKind.Error())
+		}
+		if errors.Is(err, model.ErrInvalidMediaName) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrInvalidMediaName.Error())
+		}
+		if errors.Is(err, model.ErrMediaTypeNotAllowed) {
+			err = httplib.NewAPIError(http.StatusUnsupportedMediaType, model.ErrMediaTypeNotAllowed.Error())
+		}
+		if errors.Is(err, model.ErrMediaTypeMismatch) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaTypeMismatch.Error())
+		}
+		if errors.Is(err, model.ErrMediaTooLarge) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaTooLarge.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
+
+		httplib.DefaultErrorHandler(w, r, err)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/protomem/gotube/pkg/httplib"
+)
+
+func TestUpload(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	path := ts.createUpload(alice)
+
+	ts.expectDenied([]route{
+		{http.MethodPost, "/uploads"},
+		{http.MethodGet, path},
+		{http.MethodHead, path},
+		{http.MethodPatch, path},
+		{http.MethodPost, path + "/complete"},
+		{http.MethodDelete, path},
+	})
+
+	// Uploads are finished by their owner only.
+	ts.expect(http.MethodGet, path, bob.Token, nil, http.StatusForbidden)
+	ts.writeChunk(bob, path, 0, mp4).expectStatus(t, "write chunk of another user", http.StatusForbidden)
+
+	half := len(mp4) / 2
+	ts.writeChunk(alice, path, 0, mp4[:half]).expectStatus(t, "write first chunk", http.StatusNoContent)
+
+	// Complete is refused until every byte arrived.
+	ts.expect(http.MethodPost, path+"/complete", alice.Token, nil, http.StatusConflict)
+
+	res := ts.expect(http.MethodHead, path, alice.Token, nil, http.StatusOK)
+	if offset := res.Header.Get(httplib.HeaderUploadOffset); offset != strconv.Itoa(half) {
+		t.Fatalf("offset = %s, want %d", offset, half)
+	}
+
+	// Chunks that do not continue the upload are rejected.
+	ts.writeChunk(alice, path, 0, mp4[:half]).expectStatus(t, "write chunk at stale offset", http.StatusConflict)
+	ts.writeChunk(alice, path, int64(half), mp4[half:]).expectStatus(t, "write last chunk", http.StatusNoContent)
+
+	res = ts.expect(http.MethodGet, path, alice.Token, nil, http.StatusOK)
+	if offset := res.field(t, "upload", "offset"); offset != float64(len(mp4)) {
+		t.Fatalf("offset = %v, want %d", offset, len(mp4))
+	}
+
+	res = ts.expect(http.MethodPost, path+"/complete", alice.Token, nil, http.StatusCreated)
+	if kind := res.str(t, "media", "kind"); kind != "video" {
+		t.Fatalf("kind = %q, want video", kind)
+	}
+
+	// A completed upload is gone.
+	ts.expect(http.MethodGet, path, alice.Token, nil, http.StatusNotFound)
+}
+
+func TestUploadAbort(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	path := ts.createUpload(alice)
+	ts.writeChunk(alice, path, 0, mp4[:10]).expectStatus(t, "write chunk", http.StatusNoContent)
+
+	ts.expect(http.MethodDelete, path, bob.Token, nil, http.StatusForbidden)
+
+	ts.expect(http.MethodDelete, path, alice.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodDelete, path, alice.Token, nil, http.StatusNotFound)
+}
+
+func TestUploadNotFound(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	path := "/uploads/" + missingID
+
+	ts.expect(http.MethodGet, path, alice.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodHead, path, alice.Token, nil, http.StatusNotFound)
+	ts.writeChunk(alice, path, 0, mp4).expectStatus(t, "write chunk", http.StatusNotFound)
+	ts.expect(http.MethodPost, path+"/complete", alice.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodDelete, path, alice.Token, nil, http.StatusNotFound)
+}
+
+// createUpload starts an upload of mp4 into the folder of the user and returns its path.
+func (ts *testServer) createUpload(user testUser) string {
+	ts.t.Helper()
+
+	res := ts.expect(http.MethodPost, "/uploads", user.Token, map[string]any{
+		"kind":   "video",
+		"parent": user.ID,
+		"name":   "video.mp4",
+		"type":   "video/mp4",
+		"size":   len(mp4),
+	}, http.StatusCreated)
+
+	path := res.Header.Get(httplib.HeaderLocation)
+	if path != "/uploads/"+res.str(ts.t, "upload", "id") {
+		ts.t.Fatalf("location = %q, want the upload", path)
+	}
+
+	return path
+}
+
+func (ts *testServer) writeChunk(user testUser, path string, offset int64, chunk []byte) testResponse {
+	ts.t.Helper()
+
+	req := ts.newRequest(http.MethodPatch, path, user.Token, bytes.NewReader(chunk))
+	req.Header.Set(httplib.HeaderUploadOffset, strconv.FormatInt(offset, 10))
+	req.Header.Set(httplib.HeaderContentType, "application/offset+octet-stream")
+
+	return ts.do(req)
+}
@@ -12,14 +12,18 @@ import (
 )
 
 type User struct {
-	logger logging.Logger
-	serv   service.User
+	logger      logging.Logger
+	serv        service.User
+	accountServ service.Account
+	urls        *MediaURLs
 }
 
-func NewUser(logger logging.Logger, serv service.User) *User {
+func NewUser(logger logging.Logger, serv service.User, accountServ service.Account, urls *MediaURLs) *User {
 	return &User{
-		logger: logger.With("handler", "user"),
-		serv:   serv,
+		logger:      logger.With("handler", "user"),
+		serv:        serv,
+		accountServ: accountServ,
+		urls:        urls,
 	}
 }
 
@@ -35,7 +39,7 @@ func (h *User) Get() http.HandlerFunc {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"user": user})
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"user": h.urls.User(user)})
 	}, h.errorHandler("handler.User.Get"))
 }
 
@@ -56,7 +60,9 @@ func (h *User) Create() http.HandlerFunc {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"user": user})
+		h.requestEmailVerification(r, user)
+
+		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"user": h.urls.User(user)})
 	}, h.errorHandler("handler.User.Create"))
 }
 
@@ -68,10 +74,10 @@ func (h *User) Update() http.HandlerFunc {
 		}
 
 		var request struct {
-			Nickname    *string `json:"nickname"`
-			Email       *string `json:"email"`
-			AvatarPath  *string `json:"avatarPath"`
-			Description *string `json:"description"`
+			Nickname      *string   `json:"nickname"`
+			Email         *string   `json:"email"`
+			AvatarMediaID *model.ID `json:"avatarMediaId"`
+			Description   *string   `json:"description"`
 
 			NewPassword *string `json:"newPassword"`
 			OldPassword *string `json:"oldPassword"`
@@ -82,18 +88,22 @@ func (h *User) Update() http.HandlerFunc {
 		}
 
 		user, err := h.serv.UpdateByNickname(r.Context(), userNickname, service.UpdateUserDTO{
-			Nickname:    request.Nickname,
-			Email:       request.Email,
-			AvatarPath:  request.AvatarPath,
-			Description: request.Description,
-			NewPassword: request.NewPassword,
-			OldPassword: request.OldPassword,
+			Nickname:      request.Nickname,
+			Email:         request.Email,
+			AvatarMediaID: request.AvatarMediaID,
+			Description:   request.Description,
+			NewPassword:   request.NewPassword,
+			OldPassword:   request.OldPassword,
 		})
 		if err != nil {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"user": user})
+		if request.Email != nil {
+			h.requestEmailVerification(r, user)
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"user": h.urls.User(user)})
 	}, h.errorHandler("handler.User.Update"))
 }
 
@@ -112,6 +122,13 @@ func (h *User) Delete() http.HandlerFunc {
 	}, h.errorHandler("handler.User.Delete"))
 }
 
+// requestEmailVerification does not fail the request: the user can ask for a new email later.
+func (h *User) requestEmailVerification(r *http.Request, user model.User) {
+	if err := h.accountServ.RequestEmailVerification(r.Context(), user.ID); err != nil {
+		h.logger.WithContext(r.Context()).Warn("failed to request email verification", "userId", user.ID, "err", err)
+	}
+}
+
 func (h *User) errorHandler(op string) httplib.ErroHandler {
 	return func(w http.ResponseWriter, r *http.Request, err error) {
 		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
@@ -122,6 +139,15 @@ func (h *User) errorHandler(op string) httplib.ErroHandler {
 		if errors.Is(err, model.ErrUserExists) {
 			err = httplib.NewAPIError(http.StatusConflict, model.ErrUserExists.Error())
 		}
+		if errors.Is(err, model.ErrMediaNotFound) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaNotFound.Error())
+		}
+		if errors.Is(err, model.ErrMediaKindMismatch) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaKindMismatch.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
 
 		httplib.DefaultErrorHandler(w, r, err)
 	}
@@ -0,0 +1,81 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUserCreate(t *testing.T) {
+	ts := newTestServer(t)
+
+	res := ts.expect(http.MethodPost, "/users", "", map[string]any{
+		"nickname": "alice",
+		"email":    "alice@example.com",
+		"password": "password",
+	}, http.StatusCreated)
+	if nickname := res.str(t, "user", "nickname"); nickname != "alice" {
+		t.Fatalf("nickname = %q, want alice", nickname)
+	}
+
+	ts.expect(http.MethodPost, "/users", "", map[string]any{
+		"nickname": "alice",
+		"email":    "alice2@example.com",
+		"password": "password",
+	}, http.StatusConflict)
+}
+
+func TestUserGet(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+
+	res := ts.expect(http.MethodGet, "/users/alice", "", nil, http.StatusOK)
+	if id := res.str(t, "user", "id"); id != alice.ID {
+		t.Fatalf("id = %s, want %s", id, alice.ID)
+	}
+
+	ts.expect(http.MethodGet, "/users/nobody", "", nil, http.StatusNotFound)
+}
+
+func TestUserUpdate(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	ts.expectDenied([]route{
+		{http.MethodPut, "/users/alice"},
+		{http.MethodPatch, "/users/alice"},
+	})
+
+	res := ts.expect(http.MethodPatch, "/users/alice", alice.Token, map[string]any{
+		"description": "patched",
+	}, http.StatusOK)
+	if description := res.str(t, "user", "description"); description != "patched" {
+		t.Fatalf("description = %q, want patched", description)
+	}
+
+	res = ts.expect(http.MethodPut, "/users/alice", alice.Token, map[string]any{
+		"description": "put",
+	}, http.StatusOK)
+	if description := res.str(t, "user", "description"); description != "put" {
+		t.Fatalf("description = %q, want put", description)
+	}
+
+	ts.expect(http.MethodPatch, "/users/alice", bob.Token, map[string]any{"description": "bob"}, http.StatusForbidden)
+	ts.expect(http.MethodPatch, "/users/nobody", bob.Token, map[string]any{"description": "bob"}, http.StatusNotFound)
+}
+
+func TestUserDelete(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+
+	ts.expectDenied([]route{{http.MethodDelete, "/users/alice"}})
+
+	ts.expect(http.MethodDelete, "/users/alice", bob.Token, nil, http.StatusForbidden)
+	ts.expect(http.MethodDelete, "/users/nobody", bob.Token, nil, http.StatusNotFound)
+
+	ts.expect(http.MethodDelete, "/users/alice", alice.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodGet, "/users/alice", "", nil, http.StatusNotFound)
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -15,45 +18,29 @@ import (
 	"github.com/samber/lo"
 )
 
-const (
-	_defaultLimit  = 10
-	_defaultOffset = 0
-)
-
 type Video struct {
-	logger logging.Logger
-	serv   service.Video
+	logger  logging.Logger
+	serv    service.Video
+	ratings service.Rating
+	urls    *MediaURLs
 }
 
-func NewVideo(logger logging.Logger, serv service.Video) *Video {
+func NewVideo(logger logging.Logger, serv service.Video, ratings service.Rating, urls *MediaURLs) *Video {
 	return &Video{
-		logger: logger.With("handler", "video"),
-		serv:   serv,
+		logger:  logger.With("handler", "video"),
+		serv:    serv,
+		ratings: ratings,
+		urls:    urls,
 	}
 }
 
 func (h *Video) List() http.HandlerFunc {
 	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
-		var limit uint64 = _defaultLimit
-		if r.URL.Query().Has("limit") {
-			value, err := strconv.ParseUint(r.URL.Query().Get("limit"), 10, 64)
-			if err != nil {
-				return httplib.NewAPIError(http.StatusBadRequest, "invalid limit").WithInternal(err)
-			}
-			limit = value
-		}
-
-		var offset uint64 = _defaultOffset
-		if r.URL.Query().Has("offset") {
-			value, err := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
-			if err != nil {
-				return httplib.NewAPIError(http.StatusBadRequest, "invalid offset").WithInternal(err)
-			}
-			offset = value
+		findOpts, err := parseFindOptions(r)
+		if err != nil {
+			return err
 		}
 
-		findOpts := service.FindOptions{Limit: limit, Offset: offset}
-
 		var sortBy string = "latest"
 		if r.URL.Query().Has("sortBy") {
 			sortBy = r.URL.Query().Get("sortBy")
@@ -65,19 +52,54 @@ func (h *Video) List() http.HandlerFunc {
 		requester, isAuth := ctxstore.User(r.Context())
 
 		var (
-			err    error
 			videos []model.Video
+			// cursorKey is the key the videos are sorted by, nil for search results which are sorted by relevance.
+			cursorKey  func(model.Video) int64
+			cursorSort model.CursorSort
 		)
 
+		createdAtKey := func(video model.Video) int64 { return video.CreatedAt.Unix() }
+
 		if searchTermOk {
-			videos, err = h.serv.Search(r.Context(), searchTerm, findOpts)
+			if findOpts.Cursor != nil {
+				return httplib.NewAPIError(http.StatusBadRequest, "search results are paged by offset")
+			}
+
+			var dto service.SearchVideoDTO
+			dto, err = parseSearchFilters(r)
+			if err != nil {
+				return err
+			}
+			dto.Term = searchTerm
+			if authorNicknameOk {
+				dto.AuthorNickname = &authorNickname
+			}
+
+			videos, err = h.serv.Search(r.Context(), dto, findOpts)
 		} else if authorNicknameOk {
+			cursorSort, cursorKey = model.CursorSortLatest, createdAtKey
+			if err := checkCursor(findOpts, cursorSort); err != nil {
+				return err
+			}
+
 			videos, err = h.serv.FindByAuthor(r.Context(), authorNickname, findOpts)
 		} else {
 			switch sortBy {
 			case "latest", "news", "createdAt":
+				cursorSort, cursorKey = model.CursorSortLatest, createdAtKey
+				if err := checkCursor(findOpts, cursorSort); err != nil {
+					return err
+				}
+
 				videos, err = h.serv.FindLatest(r.Context(), findOpts)
 			case "popular", "trends", "views":
+				// Paging by views is best-effort, they change between pages.
+				cursorSort = model.CursorSortViews
+				cursorKey = func(video model.Video) int64 { return video.Views }
+				if err := checkCursor(findOpts, cursorSort); err != nil {
+					return err
+				}
+
 				videos, err = h.serv.FindPopular(r.Context(), findOpts)
 			default:
 				return httplib.NewAPIError(http.StatusBadRequest, "invalid sortBy")
@@ -88,6 +110,14 @@ func (h *Video) List() http.HandlerFunc {
 			return err
 		}
 
+		// The cursor is taken before the filter, so hidden videos are skipped rather than ending the listing.
+		var next *string
+		if cursorKey != nil {
+			next = nextCursor(findOpts, cursorSort, len(videos), func(i int) model.Cursor {
+				return model.Cursor{Key: cursorKey(videos[i]), ID: videos[i].ID}
+			})
+		}
+
 		// TODO: is it worth using?
 		videos = lo.Filter(videos, func(video model.Video, _ int) bool {
 			if video.Public {
@@ -101,10 +131,117 @@ func (h *Video) List() http.HandlerFunc {
 			return false
 		})
 
-		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"videos": videos})
+		if err := h.setMyRatings(r, videos); err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"videos": h.urls.Videos(videos), "nextCursor": next})
 	}, h.errorHandler("handler.Video.List"))
 }
 
+// parseSearchFilters reads the filters of a search, dates are either RFC 3339 timestamps or days and durations are in seconds.
+func parseSearchFilters(r *http.Request) (service.SearchVideoDTO, error) {
+	var dto service.SearchVideoDTO
+
+	for _, filter := range []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"uploadedAfter", &dto.UploadedAfter},
+		{"uploadedBefore", &dto.UploadedBefore},
+	} {
+		if !r.URL.Query().Has(filter.name) {
+			continue
+		}
+
+		value, err := parseDate(r.URL.Query().Get(filter.name))
+		if err != nil {
+			return service.SearchVideoDTO{}, httplib.NewAPIError(http.StatusBadRequest, "invalid "+filter.name).WithInternal(err)
+		}
+		*filter.dst = &value
+	}
+
+	for _, filter := range []struct {
+		name string
+		dst  **int64
+	}{
+		{"minDuration", &dto.MinDuration},
+		{"maxDuration", &dto.MaxDuration},
+	} {
+		if !r.URL.Query().Has(filter.name) {
+			continue
+		}
+
+		value, err := strconv.ParseUint(r.URL.Query().Get(filter.name), 10, 63)
+		if err != nil {
+			return service.SearchVideoDTO{}, httplib.NewAPIError(http.StatusBadRequest, "invalid "+filter.name).WithInternal(err)
+		}
+		*filter.dst = lo.ToPtr(int64(value))
+	}
+
+	return dto, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func (h *Video) Feed() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		findOpts, err := parseFindOptions(r)
+		if err != nil {
+			return err
+		}
+		if err := checkCursor(findOpts, model.CursorSortLatest); err != nil {
+			return err
+		}
+
+		user := ctxstore.MustUser(r.Context())
+
+		videos, err := h.serv.Feed(r.Context(), user.ID, findOpts)
+		if err != nil {
+			return err
+		}
+
+		next := nextCursor(findOpts, model.CursorSortLatest, len(videos), func(i int) model.Cursor {
+			return model.Cursor{Key: videos[i].CreatedAt.Unix(), ID: videos[i].ID}
+		})
+
+		if err := h.setMyRatings(r, videos); err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"videos": h.urls.Videos(videos), "nextCursor": next})
+	}, h.errorHandler("handler.Video.Feed"))
+}
+
+// setMyRatings sets the ratings of the signed in user on the videos with a single query.
+func (h *Video) setMyRatings(r *http.Request, videos []model.Video) error {
+	user, isAuth := ctxstore.User(r.Context())
+	if !isAuth || len(videos) == 0 {
+		return nil
+	}
+
+	videoIDs := lo.Map(videos, func(video model.Video, _ int) model.ID { return video.ID })
+
+	ratings, err := h.ratings.FindByUserAndVideos(r.Context(), user.ID, videoIDs)
+	if err != nil {
+		return err
+	}
+
+	values := lo.SliceToMap(ratings, func(rating model.Rating) (model.ID, model.RatingValue) {
+		return rating.VideoID, rating.Value()
+	})
+	for i := range videos {
+		videos[i].MyRating = values[videos[i].ID]
+	}
+
+	return nil
+}
+
 func (h *Video) Get() http.HandlerFunc {
 	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
 		videoIDRaw, ok := mux.Vars(r)["videoId"]
@@ -123,22 +260,27 @@ func (h *Video) Get() http.HandlerFunc {
 		}
 
 		author, isAuth := ctxstore.User(r.Context())
-		if video.Public || (!video.Public && isAuth && author.ID == video.Author.ID) {
-			return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"video": video})
+		if !video.Public && (!isAuth || author.ID != video.Author.ID) {
+			return model.ErrVideoNotFound
+		}
+
+		videos := []model.Video{video}
+		if err := h.setMyRatings(r, videos); err != nil {
+			return err
 		}
 
-		return model.ErrVideoNotFound
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"video": h.urls.Video(videos[0])})
 	}, h.errorHandler("handler.Video.Get"))
 }
 
 func (h *Video) Creaate() http.HandlerFunc {
 	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
 		var request struct {
-			Title         string
-			Description   *string
-			ThumbnailPath string
-			VideoPath     string
-			Public        *bool
+			Title            string
+			Description      *string
+			ThumbnailMediaID *model.ID `json:"thumbnailMediaId"`
+			VideoMediaID     model.ID  `json:"videoMediaId"`
+			Public           *bool
 		}
 
 		if err := httplib.DecodeJSON(r, &request); err != nil {
@@ -148,18 +290,18 @@ func (h *Video) Creaate() http.HandlerFunc {
 		author := ctxstore.MustUser(r.Context())
 
 		video, err := h.serv.Create(r.Context(), service.CreateVideoDTO{
-			Title:         request.Title,
-			Description:   request.Description,
-			ThumbnailPath: request.ThumbnailPath,
-			VideoPath:     request.VideoPath,
-			AuthorID:      author.ID,
-			Public:        request.Public,
+			Title:            request.Title,
+			Description:      request.Description,
+			ThumbnailMediaID: request.ThumbnailMediaID,
+			VideoMediaID:     request.VideoMediaID,
+			AuthorID:         author.ID,
+			Public:           request.Public,
 		})
 		if err != nil {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"video": video})
+		return httplib.WriteJSON(w, http.StatusCreated, httplib.JSON{"video": h.urls.Video(video)})
 	}, h.errorHandler("handler.Video.Create"))
 }
 
@@ -176,11 +318,11 @@ func (h *Video) Update() http.HandlerFunc {
 		}
 
 		var request struct {
-			Title         *string `json:"title"`
-			Description   *string `json:"description"`
-			ThumbnailPath *string `json:"thumbnailPath"`
-			VideoPath     *string `json:"videoPath"`
-			Public        *bool   `json:"isPublic"`
+			Title            *string   `json:"title"`
+			Description      *string   `json:"description"`
+			ThumbnailMediaID *model.ID `json:"thumbnailMediaId"`
+			VideoMediaID     *model.ID `json:"videoMediaId"`
+			Public           *bool     `json:"isPublic"`
 		}
 
 		if err := httplib.DecodeJSON(r, &request); err != nil {
@@ -192,7 +334,7 @@ func (h *Video) Update() http.HandlerFunc {
 			return err
 		}
 
-		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"video": video})
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"video": h.urls.Video(video)})
 	}, h.errorHandler("handler.Video.Update"))
 }
 
@@ -216,6 +358,80 @@ func (h *Video) Delete() http.HandlerFunc {
 	}, h.errorHandler("handler.Video.Delete"))
 }
 
+func (h *Video) RecordView() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		videoIDRaw, ok := mux.Vars(r)["videoId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing video id")
+		}
+
+		videoID, err := uuid.Parse(videoIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid video id").WithInternal(err)
+		}
+
+		dto := service.RecordViewDTO{
+			VideoID: videoID,
+			IP:      ctxstore.MustClientIP(r.Context()),
+		}
+		if viewer, isAuth := ctxstore.User(r.Context()); isAuth {
+			dto.UserID = &viewer.ID
+		}
+
+		counted, err := h.serv.RecordView(r.Context(), dto)
+		if err != nil {
+			return err
+		}
+
+		return httplib.WriteJSON(w, http.StatusOK, httplib.JSON{"counted": counted})
+	}, h.errorHandler("handler.Video.RecordView"))
+}
+
+func (h *Video) Stream() http.HandlerFunc {
+	return httplib.NewEndpointWithErroHandler(func(w http.ResponseWriter, r *http.Request) error {
+		videoIDRaw, ok := mux.Vars(r)["videoId"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing video id")
+		}
+
+		videoID, err := uuid.Parse(videoIDRaw)
+		if err != nil {
+			return httplib.NewAPIError(http.StatusBadRequest, "invalid video id").WithInternal(err)
+		}
+
+		fileName, ok := mux.Vars(r)["file"]
+		if !ok {
+			return httplib.NewAPIError(http.StatusBadRequest, "missing file name")
+		}
+
+		// Rendition playlists and segments live one level below the master playlist.
+		if rendition, ok := mux.Vars(r)["rendition"]; ok {
+			fileName = rendition + "/" + fileName
+		}
+
+		dto := service.OpenStreamDTO{
+			VideoID: videoID,
+			Name:    fileName,
+		}
+		if viewer, isAuth := ctxstore.User(r.Context()); isAuth {
+			dto.UserID = &viewer.ID
+		}
+
+		obj, err := h.serv.OpenStream(r.Context(), dto)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = obj.Body.Close() }()
+
+		w.Header().Set(httplib.HeaderContentType, obj.Type)
+		w.Header().Set(httplib.HeaderETag, fmt.Sprintf(`"%x-%x"`, obj.ModTime.UnixNano(), obj.Size))
+
+		http.ServeContent(w, r, path.Base(fileName), obj.ModTime, obj.Body)
+
+		return nil
+	}, h.errorHandler("handler.Video.Stream"))
+}
+
 func (h *Video) errorHandler(op string) httplib.ErroHandler {
 	return func(w http.ResponseWriter, r *http.Request, err error) {
 		h.logger.WithContext(r.Context()).Error("failed to handle request", "operation", op, "err", err)
@@ -223,12 +439,24 @@ func (h *Video) errorHandler(op string) httplib.ErroHandler {
 		if errors.Is(err, model.ErrVideoNotFound) {
 			err = httplib.NewAPIError(http.StatusNotFound, model.ErrVideoNotFound.Error())
 		}
+		if errors.Is(err, model.ErrStreamNotFound) {
+			err = httplib.NewAPIError(http.StatusNotFound, model.ErrStreamNotFound.Error())
+		}
 		if errors.Is(err, model.ErrVideoExists) {
 			err = httplib.NewAPIError(http.StatusConflict, model.ErrVideoExists.Error())
 		}
 		if errors.Is(err, model.ErrUserNotFound) {
 			err = httplib.NewAPIError(http.StatusNotFound, model.ErrUserNotFound.Error())
 		}
+		if errors.Is(err, model.ErrMediaNotFound) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaNotFound.Error())
+		}
+		if errors.Is(err, model.ErrMediaKindMismatch) {
+			err = httplib.NewAPIError(http.StatusBadRequest, model.ErrMediaKindMismatch.Error())
+		}
+		if errors.Is(err, model.ErrForbidden) {
+			err = httplib.NewAPIError(http.StatusForbidden, model.ErrForbidden.Error())
+		}
 
 		httplib.DefaultErrorHandler(w, r, err)
 	}
@@ -0,0 +1,174 @@
+//go:build sqlite_fts5
+
+package handler_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVideoCreate(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+
+	ts.expectDenied([]route{{http.MethodPost, "/videos"}})
+
+	videoID := ts.createVideo(alice, "first video")
+
+	res := ts.expect(http.MethodGet, "/videos/"+videoID, "", nil, http.StatusOK)
+	if title := res.str(t, "video", "title"); title != "first video" {
+		t.Fatalf("title = %q, want first video", title)
+	}
+
+	// The media has to be uploaded first.
+	ts.expect(http.MethodPost, "/videos", alice.Token, map[string]any{
+		"title":        "no media",
+		"videoMediaId": missingID,
+	}, http.StatusBadRequest)
+}
+
+func TestVideoGet(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+
+	ts.expect(http.MethodGet, "/videos/"+videoID, "", nil, http.StatusOK)
+	ts.expect(http.MethodGet, "/videos/"+missingID, "", nil, http.StatusNotFound)
+
+	// Private videos are only found by their author.
+	ts.expect(http.MethodPatch, "/videos/"+videoID, alice.Token, map[string]any{"isPublic": false}, http.StatusOK)
+	ts.expect(http.MethodGet, "/videos/"+videoID, bob.Token, nil, http.StatusNotFound)
+	ts.expect(http.MethodGet, "/videos/"+videoID, alice.Token, nil, http.StatusOK)
+}
+
+func TestVideoList(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	ts.createVideo(alice, "cats")
+	ts.createVideo(alice, "dogs")
+
+	res := ts.expect(http.MethodGet, "/videos", "", nil, http.StatusOK)
+	if n := res.len(t, "videos"); n != 2 {
+		t.Fatalf("videos = %d, want 2", n)
+	}
+
+	res = ts.expect(http.MethodGet, "/videos?q=cats", "", nil, http.StatusOK)
+	if n := res.len(t, "videos"); n != 1 {
+		t.Fatalf("videos = %d, want 1", n)
+	}
+
+	ts.expect(http.MethodGet, "/videos?author=nobody", "", nil, http.StatusNotFound)
+}
+
+func TestVideoFeed(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	ts.createVideo(alice, "video")
+
+	ts.expectDenied([]route{{http.MethodGet, "/feed"}})
+
+	res := ts.expect(http.MethodGet, "/feed", bob.Token, nil, http.StatusOK)
+	if n := res.len(t, "videos"); n != 0 {
+		t.Fatalf("videos = %d, want 0", n)
+	}
+
+	ts.expect(http.MethodPost, "/subs/alice", bob.Token, nil, http.StatusNoContent)
+
+	res = ts.expect(http.MethodGet, "/feed", bob.Token, nil, http.StatusOK)
+	if n := res.len(t, "videos"); n != 1 {
+		t.Fatalf("videos = %d, want 1", n)
+	}
+}
+
+func TestVideoUpdate(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+
+	ts.expectDenied([]route{
+		{http.MethodPut, "/videos/" + videoID},
+		{http.MethodPatch, "/videos/" + videoID},
+	})
+
+	res := ts.expect(http.MethodPatch, "/videos/"+videoID, alice.Token, map[string]any{"title": "patched"}, http.StatusOK)
+	if title := res.str(t, "video", "title"); title != "patched" {
+		t.Fatalf("title = %q, want patched", title)
+	}
+
+	res = ts.expect(http.MethodPut, "/videos/"+videoID, alice.Token, map[string]any{"title": "put"}, http.StatusOK)
+	if title := res.str(t, "video", "title"); title != "put" {
+		t.Fatalf("title = %q, want put", title)
+	}
+
+	ts.expect(http.MethodPatch, "/videos/"+videoID, bob.Token, map[string]any{"title": "bob"}, http.StatusForbidden)
+	ts.expect(http.MethodPatch, "/videos/"+missingID, alice.Token, map[string]any{"title": "missing"}, http.StatusNotFound)
+}
+
+func TestVideoDelete(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	bob := ts.signUp("bob")
+	videoID := ts.createVideo(alice, "video")
+
+	ts.expectDenied([]route{{http.MethodDelete, "/videos/" + videoID}})
+
+	ts.expect(http.MethodDelete, "/videos/"+videoID, bob.Token, nil, http.StatusForbidden)
+	ts.expect(http.MethodDelete, "/videos/"+missingID, alice.Token, nil, http.StatusNotFound)
+
+	ts.expect(http.MethodDelete, "/videos/"+videoID, alice.Token, nil, http.StatusNoContent)
+	ts.expect(http.MethodGet, "/videos/"+videoID, alice.Token, nil, http.StatusNotFound)
+}
+
+func TestVideoRecordView(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	videoID := ts.createVideo(alice, "video")
+
+	res := ts.expect(http.MethodPost, "/videos/"+videoID+"/views", "", nil, http.StatusOK)
+	if counted, _ := res.field(t, "counted").(bool); !counted {
+		t.Fatalf("first view is not counted: %s", res.Body)
+	}
+
+	// Views of the same viewer within the window count once.
+	res = ts.expect(http.MethodPost, "/videos/"+videoID+"/views", "", nil, http.StatusOK)
+	if counted, _ := res.field(t, "counted").(bool); counted {
+		t.Fatalf("repeated view is counted: %s", res.Body)
+	}
+
+	ts.expect(http.MethodPost, "/videos/"+missingID+"/views", "", nil, http.StatusNotFound)
+}
+
+func TestVideoStream(t *testing.T) {
+	ts := newTestServer(t)
+	alice := ts.signUp("alice")
+	videoID := ts.createVideo(alice, "video")
+
+	res := ts.expect(http.MethodGet, "/videos/"+videoID, "", nil, http.StatusOK)
+	streamPath := res.str(t, "video", "streamPath")
+
+	res = ts.expect(http.MethodGet, streamPath, "", nil, http.StatusOK)
+	ts.expect(http.MethodHead, streamPath, "", nil, http.StatusOK)
+
+	// The master playlist links the rendition playlists relative to itself.
+	var rendition string
+	for _, line := range strings.Split(string(res.Body), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			rendition = line
+			break
+		}
+	}
+	if rendition == "" {
+		t.Fatalf("no rendition in master playlist %s", res.Body)
+	}
+
+	ts.expect(http.MethodGet, "/videos/"+videoID+"/stream/"+rendition, "", nil, http.StatusOK)
+	ts.expect(http.MethodHead, "/videos/"+videoID+"/stream/"+rendition, "", nil, http.StatusOK)
+
+	ts.expect(http.MethodGet, "/videos/"+videoID+"/stream/missing.m3u8", "", nil, http.StatusNotFound)
+	ts.expect(http.MethodGet, "/videos/"+videoID+"/stream/missing/index.m3u8", "", nil, http.StatusNotFound)
+	ts.expect(http.MethodGet, "/videos/"+missingID+"/stream/master.m3u8", "", nil, http.StatusNotFound)
+}
@@ -0,0 +1,307 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+type Store interface {
+	Create(ctx context.Context, dto repository.CreateJobDTO) (model.ID, error)
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (model.Job, error)
+	Update(ctx context.Context, id model.ID, dto repository.UpdateJobDTO) error
+}
+
+// Handler processes jobs of one kind.
+type Handler struct {
+	// Run performs the job, a returned error schedules a retry.
+	Run func(ctx context.Context, payload []byte) error
+	// Fail is called once the job has run out of attempts. Optional.
+	Fail func(ctx context.Context, payload []byte, err error) error
+}
+
+type Options struct {
+	// Workers is the number of jobs processed concurrently.
+	Workers int
+	// PollInterval is how often idle workers look for due jobs.
+	PollInterval time.Duration
+	// MaxAttempts is how many times a job is run before it is marked as failed.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, it doubles with every next attempt.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+	// Lease is how long a running job is kept from other workers without a heartbeat.
+	// Jobs of a crashed worker are taken over once their lease expires.
+	Lease time.Duration
+}
+
+// Queue runs persisted jobs on a pool of workers, retrying failed ones with exponential backoff.
+type Queue struct {
+	logger logging.Logger
+	store  Store
+	opts   Options
+
+	mux      sync.RWMutex
+	handlers map[string]Handler
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wakeCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func New(logger logging.Logger, store Store, opts Options) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Queue{
+		logger:   logger.With("component", "jobqueue"),
+		store:    store,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+		ctx:      ctx,
+		cancel:   cancel,
+		wakeCh:   make(chan struct{}, 1),
+	}
+}
+
+func (q *Queue) Register(kind string, handler Handler) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	q.handlers[kind] = handler
+}
+
+// Enqueue stores a job with the JSON encoded payload and wakes up an idle worker.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any) error {
+	const op = "jobqueue.Enqueue"
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := q.store.Create(ctx, repository.CreateJobDTO{
+		Kind:        kind,
+		Payload:     data,
+		MaxAttempts: max(q.opts.MaxAttempts, 1),
+		RunAt:       time.Now(),
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Start launches the workers.
+func (q *Queue) Start(ctx context.Context) error {
+	for i := 0; i < max(q.opts.Workers, 1); i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	return nil
+}
+
+// Close stops the workers and waits for the running jobs to return.
+// Interrupted jobs are put back to the queue without spending an attempt.
+func (q *Queue) Close(ctx context.Context) error {
+	q.cancel()
+
+	doneCh := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobqueue.Close: %w", ctx.Err())
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before going idle.
+		for q.ctx.Err() == nil {
+			ok, err := q.runNext()
+			if err != nil && q.ctx.Err() == nil {
+				q.logger.Error("failed to run job", "err", err)
+			}
+			if !ok {
+				break
+			}
+		}
+
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wakeCh:
+		}
+	}
+}
+
+// runNext claims and runs a due job, reporting whether there was one.
+func (q *Queue) runNext() (bool, error) {
+	job, err := q.store.Claim(q.ctx, time.Now(), q.opts.Lease)
+	if err != nil {
+		if errors.Is(err, model.ErrJobNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	logger := q.logger.With("jobId", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+
+	q.mux.RLock()
+	handler, ok := q.handlers[job.Kind]
+	q.mux.RUnlock()
+
+	switch {
+	case job.Attempts > job.MaxAttempts:
+		// The last attempt took down its worker, the job is not run again.
+		err = errors.New("lease expired")
+	case !ok:
+		err = fmt.Errorf("no handler for job kind %q", job.Kind)
+	default:
+		err = q.run(handler, job)
+	}
+
+	// The job was interrupted by shutdown, not failed.
+	if err != nil && q.ctx.Err() != nil {
+		logger.Info("job interrupted")
+		return true, q.release(job)
+	}
+
+	if err == nil {
+		logger.Debug("job done")
+		return true, q.finish(job)
+	}
+
+	if ok && job.Attempts < job.MaxAttempts {
+		delay := q.backoff(job.Attempts)
+		logger.Warn("job failed, retrying", "err", err, "delay", delay)
+		return true, q.retry(job, err, delay)
+	}
+
+	logger.Error("job failed", "err", err)
+
+	if ok && handler.Fail != nil {
+		if err := handler.Fail(context.Background(), job.Payload, err); err != nil {
+			logger.Error("failed to handle job failure", "err", err)
+		}
+	}
+
+	return true, q.fail(job, err)
+}
+
+func (q *Queue) run(handler Handler, job model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go q.heartbeat(job, stopCh)
+
+	return handler.Run(q.ctx, job.Payload)
+}
+
+// heartbeat renews the lease of the running job until stopCh is closed.
+func (q *Queue) heartbeat(job model.Job, stopCh <-chan struct{}) {
+	// Timestamps are stored in seconds, renewing more often does not help.
+	ticker := time.NewTicker(max(q.opts.Lease/3, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			// An empty update only moves updated_at forward.
+			if err := q.store.Update(context.Background(), job.ID, repository.UpdateJobDTO{}); err != nil {
+				q.logger.Error("failed to renew job lease", "jobId", job.ID, "err", err)
+			}
+		}
+	}
+}
+
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.opts.Backoff
+	for i := 1; i < attempt && delay < q.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if q.opts.MaxBackoff > 0 {
+		delay = min(delay, q.opts.MaxBackoff)
+	}
+
+	return delay
+}
+
+// State changes use a fresh context, so they are saved even while the queue is stopping.
+
+func (q *Queue) finish(job model.Job) error {
+	status := model.JobStatusDone
+	lastError := ""
+
+	return q.store.Update(context.Background(), job.ID, repository.UpdateJobDTO{
+		Status:    &status,
+		LastError: &lastError,
+	})
+}
+
+func (q *Queue) retry(job model.Job, cause error, delay time.Duration) error {
+	status := model.JobStatusPending
+	lastError := cause.Error()
+	runAt := time.Now().Add(delay)
+
+	return q.store.Update(context.Background(), job.ID, repository.UpdateJobDTO{
+		Status:    &status,
+		LastError: &lastError,
+		RunAt:     &runAt,
+	})
+}
+
+func (q *Queue) fail(job model.Job, cause error) error {
+	status := model.JobStatusFailed
+	lastError := cause.Error()
+
+	return q.store.Update(context.Background(), job.ID, repository.UpdateJobDTO{
+		Status:    &status,
+		LastError: &lastError,
+	})
+}
+
+func (q *Queue) release(job model.Job) error {
+	status := model.JobStatusPending
+	attempts := job.Attempts - 1
+
+	return q.store.Update(context.Background(), job.ID, repository.UpdateJobDTO{
+		Status:   &status,
+		Attempts: &attempts,
+	})
+}
@@ -0,0 +1,17 @@
+package mailer
+
+import (
+	"context"
+)
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+
+	Close(ctx context.Context) error
+}
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
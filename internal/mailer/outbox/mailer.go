@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/mailer"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ mailer.Mailer = (*Mailer)(nil)
+
+// maxMessages is how many of the latest messages are kept in memory.
+const maxMessages = 100
+
+// Mailer keeps the latest sent messages in memory and, when a folder is given, also writes
+// every message into it as a separate file. Nothing leaves the process.
+type Mailer struct {
+	logger logging.Logger
+	folder string
+
+	mux  sync.RWMutex
+	msgs []mailer.Message
+}
+
+func New(logger logging.Logger, folder string) (*Mailer, error) {
+	const op = "mailer.New"
+
+	if folder != "" {
+		absFolder, err := filepath.Abs(folder)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		folder = absFolder
+
+		if err := os.MkdirAll(folder, 0700); err != nil {
+			return nil, fmt.Errorf("%s: init folder: %w", op, err)
+		}
+	}
+
+	return &Mailer{
+		logger: logger.With("component", "outbox/mailer"),
+		folder: folder,
+		msgs:   make([]mailer.Message, 0),
+	}, nil
+}
+
+func (m *Mailer) Send(ctx context.Context, msg mailer.Message) error {
+	const op = "mailer.Send"
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.logger.WithContext(ctx).Debug("send message", "to", msg.To, "subject", msg.Subject)
+
+	m.msgs = append(m.msgs, msg)
+	if len(m.msgs) > maxMessages {
+		m.msgs = m.msgs[len(m.msgs)-maxMessages:]
+	}
+
+	if m.folder == "" {
+		return nil
+	}
+
+	filename := filepath.Join(m.folder, fmt.Sprintf("%d-%s.txt", time.Now().UnixNano(), uuid.NewString()))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Messages returns a copy of the latest messages sent, oldest first.
+func (m *Mailer) Messages() []mailer.Message {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	msgs := make([]mailer.Message, len(m.msgs))
+	copy(msgs, m.msgs)
+
+	return msgs
+}
+
+func (m *Mailer) Close(_ context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.msgs = make([]mailer.Message, 0)
+
+	return nil
+}
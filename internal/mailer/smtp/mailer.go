@@ -0,0 +1,82 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protomem/gotube/internal/mailer"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ mailer.Mailer = (*Mailer)(nil)
+
+type Options struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type Mailer struct {
+	logger logging.Logger
+	opts   Options
+	addr   string
+	auth   smtp.Auth
+}
+
+func New(logger logging.Logger, opts Options) (*Mailer, error) {
+	const op = "mailer.New"
+
+	if opts.Host == "" || opts.From == "" {
+		return nil, fmt.Errorf("%s: host and sender are required", op)
+	}
+
+	var auth smtp.Auth
+	if opts.Username != "" {
+		auth = smtp.PlainAuth("", opts.Username, opts.Password, opts.Host)
+	}
+
+	return &Mailer{
+		logger: logger.With("component", "smtp/mailer"),
+		opts:   opts,
+		addr:   net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port)),
+		auth:   auth,
+	}, nil
+}
+
+func (m *Mailer) Send(ctx context.Context, msg mailer.Message) error {
+	const op = "mailer.Send"
+
+	m.logger.WithContext(ctx).Debug("send message", "to", msg.To, "subject", msg.Subject)
+
+	if err := smtp.SendMail(m.addr, m.auth, m.opts.From, msg.To, m.format(msg)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (m *Mailer) Close(_ context.Context) error {
+	return nil
+}
+
+func (m *Mailer) format(msg mailer.Message) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", m.opts.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(&buf, "\r\n%s\r\n", msg.Body)
+
+	return buf.Bytes()
+}
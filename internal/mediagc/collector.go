@@ -0,0 +1,212 @@
+package mediagc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+type Store interface {
+	// Referenced reports whether a row still refers to the object.
+	Referenced(ctx context.Context, parent, name string) (bool, error)
+	// Forget drops what is recorded about a removed object.
+	Forget(ctx context.Context, parent, name string) error
+	// Expire removes the rows that are abandoned, such as stale uploads, and returns how many.
+	Expire(ctx context.Context) (int, error)
+}
+
+type Options struct {
+	// Interval is how often the collector runs in the background, zero disables the background runs.
+	Interval time.Duration
+	// GracePeriod keeps objects younger than it, they may be uploaded but not yet linked.
+	GracePeriod time.Duration
+	// DryRun only reports orphaned objects instead of removing them.
+	DryRun bool
+}
+
+// Report describes a single run of the collector.
+type Report struct {
+	DryRun   bool     `json:"dryRun"`
+	Scanned  int      `json:"scanned"`
+	Orphaned []Object `json:"orphaned"`
+	// Removed is the number of orphaned objects deleted, it is zero on a dry run.
+	Removed int   `json:"removed"`
+	Bytes   int64 `json:"bytes"`
+	// Expired is the number of abandoned rows removed before the walk, it is zero on a dry run.
+	Expired int `json:"expired"`
+}
+
+type Object struct {
+	Parent  string    `json:"parent"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// Collector removes blobs no longer referenced by any row, such as the files
+// of deleted videos and users, which cascading deletes leave behind.
+type Collector struct {
+	logger logging.Logger
+	bstore blobstore.Storage
+	store  Store
+	opts   Options
+
+	// runMux keeps scheduled and manual runs from overlapping.
+	runMux sync.Mutex
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func New(logger logging.Logger, bstore blobstore.Storage, store Store, opts Options) *Collector {
+	return &Collector{
+		logger: logger.With("component", "mediagc"),
+		bstore: bstore,
+		store:  store,
+		opts:   opts,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+// Collect finds orphaned objects older than the grace period and removes them unless dryRun is set
+// or the collector is configured as a dry run.
+func (c *Collector) Collect(ctx context.Context, dryRun bool) (Report, error) {
+	const op = "mediagc.Collect"
+
+	c.runMux.Lock()
+	defer c.runMux.Unlock()
+
+	report := Report{DryRun: dryRun || c.opts.DryRun, Orphaned: make([]Object, 0)}
+	deadline := time.Now().Add(-c.opts.GracePeriod)
+
+	// Expired rows go first, so the objects they kept are orphaned in the same run.
+	if !report.DryRun {
+		expired, err := c.store.Expire(ctx)
+		if err != nil {
+			return Report{}, fmt.Errorf("%s: %w", op, err)
+		}
+		report.Expired = expired
+	}
+
+	// Objects are removed once the walk is over, so the storage is not changed while it is listed.
+	if err := c.bstore.Walk(ctx, func(info blobstore.ObjectInfo) error {
+		report.Scanned++
+
+		if info.ModTime.After(deadline) {
+			return nil
+		}
+
+		referenced, err := c.store.Referenced(ctx, info.Parent, info.Name)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			return nil
+		}
+
+		report.Orphaned = append(report.Orphaned, Object(info))
+		report.Bytes += info.Size
+
+		return nil
+	}); err != nil {
+		return Report{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if report.DryRun {
+		return report, nil
+	}
+
+	for _, obj := range report.Orphaned {
+		if err := c.remove(ctx, obj); err != nil {
+			return report, fmt.Errorf("%s: %w", op, err)
+		}
+		report.Removed++
+	}
+
+	return report, nil
+}
+
+func (c *Collector) Start() {
+	if c.opts.Interval <= 0 {
+		close(c.doneCh)
+		return
+	}
+
+	go c.loop()
+}
+
+// Close stops the background runs, waiting for a running one to finish.
+func (c *Collector) Close(ctx context.Context) error {
+	close(c.stopCh)
+
+	select {
+	case <-c.doneCh:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("mediagc.Close: %w", ctx.Err())
+	}
+}
+
+func (c *Collector) loop() {
+	defer close(c.doneCh)
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		c.run()
+	}
+}
+
+// run collects with a context canceled on Close, so shutdown does not wait for a full walk.
+func (c *Collector) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	report, err := c.Collect(ctx, false)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		c.logger.Error("failed to collect orphaned media", "err", err)
+	}
+
+	if len(report.Orphaned) > 0 || report.Expired > 0 {
+		c.logger.Info(
+			"orphaned media collected",
+			"dryRun", report.DryRun, "scanned", report.Scanned, "expired", report.Expired,
+			"orphaned", len(report.Orphaned), "removed", report.Removed, "bytes", report.Bytes,
+		)
+	}
+}
+
+func (c *Collector) remove(ctx context.Context, obj Object) error {
+	if err := c.bstore.Del(ctx, obj.Parent, obj.Name); err != nil && !errors.Is(err, blobstore.ErrObjectNotFound) {
+		return err
+	}
+
+	if err := c.store.Forget(ctx, obj.Parent, obj.Name); err != nil {
+		return err
+	}
+
+	c.logger.Debug("orphaned media removed", "parent", obj.Parent, "name", obj.Name, "size", obj.Size)
+
+	return nil
+}
@@ -2,10 +2,12 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/protomem/gotube/internal/ctxstore"
+	"github.com/protomem/gotube/internal/model"
 	"github.com/protomem/gotube/internal/service"
 	"github.com/protomem/gotube/pkg/httplib"
 	"github.com/protomem/gotube/pkg/logging"
@@ -41,14 +43,14 @@ func (m *Auth) Authenticate() mux.MiddlewareFunc {
 			}
 
 			token := headerParts[1]
-			user, err := m.serv.Verify(r.Context(), token)
+			user, session, err := m.serv.Verify(r.Context(), token)
 			if err != nil {
 				m.logger.Error("invalid token")
 				_ = httplib.WriteJSON(w, http.StatusUnauthorized, httplib.JSON{"message": "invalid token"})
 				return
 			}
 
-			wr := ctxstore.RequestWithUser(r, user)
+			wr := ctxstore.RequestWithSession(ctxstore.RequestWithUser(r, user), session)
 
 			next(w, wr)
 		}
@@ -68,3 +70,17 @@ func (m *Auth) Protect() mux.MiddlewareFunc {
 		}
 	}))
 }
+
+func (m *Auth) RequireRole(roles ...model.Role) mux.MiddlewareFunc {
+	return mux.MiddlewareFunc(httplib.NewMiddlewareFunc(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			role, ok := ctxstore.Role(r.Context())
+			if !ok || !slices.Contains(roles, role) {
+				_ = httplib.WriteJSON(w, http.StatusForbidden, httplib.JSON{"message": "access denied"})
+				return
+			}
+
+			next(w, r)
+		}
+	}))
+}
@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -11,10 +15,48 @@ import (
 	"github.com/tomasen/realip"
 )
 
-type Common struct{}
+type Common struct {
+	trustedProxies []netip.Prefix
+}
 
-func NewCommon() *Common {
-	return &Common{}
+func NewCommon(trustedProxies []netip.Prefix) *Common {
+	return &Common{
+		trustedProxies: trustedProxies,
+	}
+}
+
+// ParseTrustedProxies reads addresses and CIDR ranges of proxies.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// ClientIP stores the address of the client in the context. It is the address of the peer,
+// unless the peer is a trusted proxy, then the forwarding headers tell the address.
+func (m *Common) ClientIP() mux.MiddlewareFunc {
+	return mux.MiddlewareFunc(httplib.NewMiddlewareFunc(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			next(w, ctxstore.RequestWithClientIP(r, m.clientIP(r)))
+		}
+	}))
 }
 
 func (m *Common) TraceID() mux.MiddlewareFunc {
@@ -31,7 +73,7 @@ func (*Common) LogAccess(logger logging.Logger) mux.MiddlewareFunc {
 	return mux.MiddlewareFunc(httplib.NewMiddlewareFunc(func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			var (
-				ip     = realip.FromRequest(r)
+				ip     = ctxstore.MustClientIP(r.Context())
 				method = r.Method
 				url    = r.URL.String()
 				proto  = r.Proto
@@ -68,6 +110,26 @@ func (*Common) Recovery(logger logging.Logger) mux.MiddlewareFunc {
 	}))
 }
 
+func (m *Common) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+
+	for _, proxy := range m.trustedProxies {
+		if proxy.Contains(addr.Unmap()) {
+			return realip.FromRequest(r)
+		}
+	}
+
+	return addr.Unmap().String()
+}
+
 func (*Common) generateTraceID() string {
 	id, _ := uuid.NewRandom()
 	return id.String()
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"net/netip"
+
 	"github.com/protomem/gotube/internal/service"
 	"github.com/protomem/gotube/pkg/logging"
 )
@@ -10,9 +12,9 @@ type Middlewares struct {
 	*Auth
 }
 
-func New(logger logging.Logger, servs *service.Services) *Middlewares {
+func New(logger logging.Logger, servs *service.Services, trustedProxies []netip.Prefix) *Middlewares {
 	return &Middlewares{
-		Common: NewCommon(),
+		Common: NewCommon(trustedProxies),
 		Auth:   NewAuth(logger, servs.Auth),
 	}
 }
@@ -1,7 +1,11 @@
 package model
 
 import (
+	"encoding/base64"
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,17 +13,93 @@ import (
 
 type ID = uuid.UUID
 
+var ErrForbidden = errors.New("forbidden")
+
 type Model struct {
 	ID        ID        `json:"id"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorSort is the order of a listing a cursor was made for, its key means nothing in another order.
+type CursorSort string
+
+const (
+	CursorSortLatest CursorSort = "latest"
+	CursorSortOldest CursorSort = "oldest"
+	CursorSortViews  CursorSort = "views"
+	CursorSortRating CursorSort = "rating"
+)
+
+// Cursor points at the last row of a page of a listing sorted by a key, such as the creation time, and the ID.
+// Clients get it encoded by String and send it back to get the rows after it.
+type Cursor struct {
+	Sort CursorSort
+	Key  int64
+	ID   ID
+}
+
+func (c Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(
+		string(c.Sort) + ":" + strconv.FormatInt(c.Key, 10) + ":" + c.ID.String(),
+	))
+}
+
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	sortRaw, rest, ok := strings.Cut(string(raw), ":")
+	if !ok || sortRaw == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	keyRaw, idRaw, ok := strings.Cut(rest, ":")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	key, err := strconv.ParseInt(keyRaw, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(idRaw)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{Sort: CursorSort(sortRaw), Key: key, ID: id}, nil
+}
+
 var (
 	ErrUserNotFound = errors.New("user not found")
 	ErrUserExists   = errors.New("user already exists")
 )
 
+var ErrInvalidRole = errors.New("invalid role")
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
 type User struct {
 	Model
 
@@ -31,6 +111,40 @@ type User struct {
 
 	AvatarPath  string `json:"avatarPath"`
 	Description string `json:"description"`
+
+	Role Role `json:"role"`
+}
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type Session struct {
+	Model
+
+	UserID ID `json:"userId"`
+
+	UserAgent string `json:"userAgent"`
+	IP        string `json:"ip"`
+
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+var ErrUserTokenNotFound = errors.New("token is invalid or expired")
+
+type UserTokenKind string
+
+const (
+	UserTokenVerifyEmail   UserTokenKind = "verify_email"
+	UserTokenResetPassword UserTokenKind = "reset_password"
+)
+
+type UserToken struct {
+	Model
+
+	UserID ID            `json:"userId"`
+	Kind   UserTokenKind `json:"kind"`
+	Email  string        `json:"email"`
+
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 var (
@@ -43,6 +157,10 @@ type Subscription struct {
 
 	FromUserID ID `json:"fromUser"`
 	ToUserID   ID `json:"toUser"`
+
+	// User is the other side of the subscription when subscriptions are listed,
+	// the channel among the subscriptions of a user and the follower among the subscribers of a channel.
+	User *User `json:"user,omitempty"`
 }
 
 var (
@@ -50,6 +168,17 @@ var (
 	ErrVideoExists   = errors.New("video already exists")
 )
 
+var ErrStreamNotFound = errors.New("stream not found")
+
+type VideoStatus string
+
+const (
+	VideoStatusUploading  VideoStatus = "uploading"
+	VideoStatusProcessing VideoStatus = "processing"
+	VideoStatusReady      VideoStatus = "ready"
+	VideoStatusFailed     VideoStatus = "failed"
+)
+
 type Video struct {
 	Model
 
@@ -58,11 +187,32 @@ type Video struct {
 
 	ThumbnailPath string `json:"thumbnailPath"`
 	VideoPath     string `json:"videoPath"`
+	// StreamPath is the HLS master playlist, empty until the video has been processed.
+	StreamPath string `json:"streamPath"`
 
 	Author User `json:"author"`
 
 	Public bool  `json:"isPublic"`
 	Views  int64 `json:"views"`
+
+	Status VideoStatus `json:"status"`
+	// Duration is the length of the video in seconds, known once it has been processed.
+	Duration int64 `json:"duration"`
+
+	Likes    int64 `json:"likes"`
+	Dislikes int64 `json:"dislikes"`
+	// MyRating is the rating of the signed in user, empty when the user has not rated the video.
+	MyRating RatingValue `json:"myRating,omitempty"`
+
+	// Highlight is set on search results only.
+	Highlight *VideoHighlight `json:"highlight,omitempty"`
+}
+
+// VideoHighlight is the HTML escaped text of a search result with the matched words wrapped in <mark> tags.
+type VideoHighlight struct {
+	Title string `json:"title"`
+	// Description is a snippet of the description around the matches.
+	Description string `json:"description"`
 }
 
 var (
@@ -70,6 +220,13 @@ var (
 	ErrRatingExists   = errors.New("rating already exists")
 )
 
+type RatingValue string
+
+const (
+	RatingLike    RatingValue = "like"
+	RatingDislike RatingValue = "dislike"
+)
+
 type Rating struct {
 	Model
 
@@ -79,7 +236,28 @@ type Rating struct {
 	Like bool `json:"isLike"`
 }
 
-var ErrCommentNotFound = errors.New("comment not found")
+func (r Rating) Value() RatingValue {
+	if r.Like {
+		return RatingLike
+	}
+
+	return RatingDislike
+}
+
+type CommentRating struct {
+	Model
+
+	UserID    ID `json:"userId"`
+	CommentID ID `json:"commentId"`
+
+	Like bool `json:"isLike"`
+}
+
+var (
+	ErrCommentNotFound       = errors.New("comment not found")
+	ErrParentCommentMismatch = errors.New("parent comment belongs to another video")
+	ErrPinReply              = errors.New("replies cannot be pinned")
+)
 
 type Comment struct {
 	Model
@@ -88,4 +266,153 @@ type Comment struct {
 
 	VideoID ID   `json:"videoId"`
 	Author  User `json:"author"`
+
+	// ParentID is the comment replied to, threads are one level deep so it is always a top level comment.
+	ParentID *ID   `json:"parentId"`
+	Replies  int64 `json:"replyCount"`
+
+	Edited bool `json:"isEdited"`
+	// Deleted comments are kept while they have replies, with their text replaced.
+	Deleted bool `json:"isDeleted"`
+
+	Likes    int64 `json:"likes"`
+	Dislikes int64 `json:"dislikes"`
+
+	// Pinned and Hearted are set by the author of the video.
+	Pinned  bool `json:"isPinned"`
+	Hearted bool `json:"isHearted"`
+}
+
+// DeletedCommentMessage replaces the text of a deleted comment kept for its replies.
+const DeletedCommentMessage = "[deleted]"
+
+// CommentEdit is the text a comment had before an edit.
+type CommentEdit struct {
+	ID        int64     `json:"id"`
+	CommentID ID        `json:"commentId"`
+	Message   string    `json:"message"`
+	EditedAt  time.Time `json:"editedAt"`
+}
+
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadIncomplete     = errors.New("upload incomplete")
+	ErrUploadTooLarge       = errors.New("upload too large")
+	ErrInvalidMediaName     = errors.New("invalid media name")
+)
+
+type Upload struct {
+	Model
+
+	OwnerID ID        `json:"ownerId"`
+	Kind    MediaKind `json:"kind"`
+
+	Parent string `json:"parent"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+
+	Size   int64 `json:"size"`
+	Offset int64 `json:"offset"`
+	// Parts names the stored chunks in order.
+	Parts []string `json:"-"`
+}
+
+var (
+	ErrMediaNotFound     = errors.New("media not found")
+	ErrInvalidMediaKind  = errors.New("invalid media kind")
+	ErrMediaKindMismatch = errors.New("media kind mismatch")
+
+	ErrMediaTypeNotAllowed = errors.New("media type not allowed")
+	ErrMediaTypeMismatch   = errors.New("media content does not match its type")
+	ErrMediaTooLarge       = errors.New("media dimensions exceed the limit")
+)
+
+type MediaKind string
+
+const (
+	MediaKindAvatar    MediaKind = "avatar"
+	MediaKindThumbnail MediaKind = "thumbnail"
+	MediaKindVideo     MediaKind = "video"
+)
+
+func (k MediaKind) Valid() bool {
+	switch k {
+	case MediaKindAvatar, MediaKindThumbnail, MediaKindVideo:
+		return true
+	default:
+		return false
+	}
+}
+
+var _mediaKindTypes = map[MediaKind][]string{
+	MediaKindAvatar:    {"image/jpeg", "image/png", "image/gif", "image/webp"},
+	MediaKindThumbnail: {"image/jpeg", "image/png", "image/gif", "image/webp"},
+	MediaKindVideo:     {"video/mp4", "video/quicktime", "video/webm", "video/x-matroska", "video/x-msvideo"},
+}
+
+// Allows reports whether media of the kind may have the content type.
+func (k MediaKind) Allows(contentType string) bool {
+	return slices.Contains(_mediaKindTypes[k], contentType)
+}
+
+// Media is a file stored in the blobstore on behalf of a user.
+type Media struct {
+	Model
+
+	OwnerID ID        `json:"ownerId"`
+	Kind    MediaKind `json:"kind"`
+
+	Parent string `json:"parent"`
+	Name   string `json:"name"`
+
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+	// Checksum is the hex SHA-256 of the content.
+	Checksum string `json:"checksum"`
+
+	// Variants are smaller copies of images, ordered by size.
+	Variants []MediaVariant `json:"variants"`
+}
+
+// MediaVariant is a smaller copy of an image, stored next to it.
+type MediaVariant struct {
+	// Size bounds the longer side of the variant.
+	Size   int    `json:"size"`
+	Name   string `json:"-"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+
+	ContentType string `json:"contentType"`
+	Bytes       int64  `json:"bytes"`
+}
+
+// Path is where the media handler serves the file.
+func (m Media) Path() string {
+	return "/media/" + m.Parent + "/" + m.Name
+}
+
+var ErrJobNotFound = errors.New("job not found")
+
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "pending"
+	JobStatusRunning JobStatus = "running"
+	JobStatusDone    JobStatus = "done"
+	JobStatusFailed  JobStatus = "failed"
+)
+
+type Job struct {
+	Model
+
+	Kind    string `json:"kind"`
+	Payload []byte `json:"-"`
+
+	Status      JobStatus `json:"status"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"maxAttempts"`
+	LastError   string    `json:"lastError"`
+
+	RunAt time.Time `json:"runAt"`
 }
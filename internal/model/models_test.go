@@ -0,0 +1,55 @@
+package model_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/model"
+)
+
+func TestParseCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+
+	cursors := []model.Cursor{
+		{Sort: model.CursorSortLatest, Key: 1700000000, ID: id},
+		{Sort: model.CursorSortOldest, Key: 0, ID: id},
+		{Sort: model.CursorSortViews, Key: math.MaxInt64, ID: id},
+		{Sort: model.CursorSortRating, Key: -42, ID: id},
+		{Sort: model.CursorSortRating, Key: math.MinInt64, ID: uuid.Nil},
+	}
+
+	for _, want := range cursors {
+		got, err := model.ParseCursor(want.String())
+		if err != nil {
+			t.Fatalf("parse %+v: %v", want, err)
+		}
+		if got != want {
+			t.Fatalf("parsed %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestParseCursorRejectsInvalid(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	id := uuid.NewString()
+
+	tests := map[string]string{
+		"empty":        "",
+		"not base64":   "not a cursor!",
+		"no separator": encode("latest"),
+		"no sort":      encode(":1:" + id),
+		"no id":        encode("latest:1"),
+		"invalid key":  encode("latest:one:" + id),
+		"key overflow": encode("latest:9223372036854775808:" + id),
+		"invalid id":   encode("latest:1:not-an-id"),
+	}
+
+	for name, s := range tests {
+		if _, err := model.ParseCursor(s); !errors.Is(err, model.ErrInvalidCursor) {
+			t.Errorf("%s: parse %q = %v, want %v", name, s, err, model.ErrInvalidCursor)
+		}
+	}
+}
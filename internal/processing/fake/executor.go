@@ -0,0 +1,147 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/protomem/gotube/internal/processing"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ processing.Executor = (*Executor)(nil)
+
+var ErrEmptyInput = errors.New("empty input")
+
+// Duration is reported for every probed input.
+const Duration = 10 * time.Second
+
+// _hlsSegments is the number of segments the input is split into.
+const _hlsSegments = 3
+
+// Executor pretends to process media without any external tools: it reports fixed
+// metadata, draws a plain thumbnail, copies the input as the transcoded video and
+// slices it into equal parts as HLS segments.
+type Executor struct {
+	logger logging.Logger
+}
+
+func New(logger logging.Logger) *Executor {
+	return &Executor{
+		logger: logger.With("component", "fake/executor"),
+	}
+}
+
+func (e *Executor) Probe(ctx context.Context, input string) (processing.Metadata, error) {
+	const op = "executor.Probe"
+
+	stat, err := os.Stat(input)
+	if err != nil {
+		return processing.Metadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if stat.Size() == 0 {
+		return processing.Metadata{}, fmt.Errorf("%s: %w", op, ErrEmptyInput)
+	}
+
+	e.logger.WithContext(ctx).Debug("probe", "input", input)
+
+	return processing.Metadata{
+		Duration:   Duration,
+		Width:      1280,
+		Height:     720,
+		VideoCodec: "h264",
+		AudioCodec: "aac",
+	}, nil
+}
+
+func (e *Executor) Thumbnail(ctx context.Context, input, output string, at time.Duration) error {
+	const op = "executor.Thumbnail"
+
+	e.logger.WithContext(ctx).Debug("thumbnail", "input", input, "output", output, "at", at)
+
+	img := image.NewRGBA(image.Rect(0, 0, 320, 180))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.Gray{Y: 0x40}), image.Point{}, draw.Src)
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if err := jpeg.Encode(file, img, nil); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return file.Close()
+}
+
+func (e *Executor) Transcode(ctx context.Context, input, output string) error {
+	const op = "executor.Transcode"
+
+	e.logger.WithContext(ctx).Debug("transcode", "input", input, "output", output)
+
+	src, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = dst.Close() }()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return dst.Close()
+}
+
+func (e *Executor) PackageHLS(ctx context.Context, input, outputDir string, rendition processing.Rendition) error {
+	const op = "executor.PackageHLS"
+
+	e.logger.WithContext(ctx).Debug("package hls", "input", input, "output", outputDir, "rendition", rendition.Name)
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	segmentDuration := Duration.Seconds() / _hlsSegments
+	segmentSize := (len(data) + _hlsSegments - 1) / _hlsSegments
+
+	var playlist strings.Builder
+	fmt.Fprintf(&playlist, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n", int(segmentDuration+1))
+	playlist.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+
+	for i := 0; i < _hlsSegments; i++ {
+		segment := data[min(i*segmentSize, len(data)):min((i+1)*segmentSize, len(data))]
+		name := fmt.Sprintf("segment_%03d.ts", i)
+
+		if err := os.WriteFile(filepath.Join(outputDir, name), segment, 0600); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		fmt.Fprintf(&playlist, "#EXTINF:%.3f,\n%s\n", segmentDuration, name)
+	}
+
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+
+	if err := os.WriteFile(filepath.Join(outputDir, processing.HLSRenditionPlaylist), []byte(playlist.String()), 0600); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/protomem/gotube/internal/processing"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ processing.Executor = (*Executor)(nil)
+
+type Options struct {
+	FFmpegPath  string
+	FFprobePath string
+}
+
+// Available reports whether both ffmpeg and ffprobe can be found.
+func Available(opts Options) bool {
+	if _, err := exec.LookPath(opts.FFmpegPath); err != nil {
+		return false
+	}
+	if _, err := exec.LookPath(opts.FFprobePath); err != nil {
+		return false
+	}
+	return true
+}
+
+// Executor runs ffprobe and ffmpeg as child processes.
+type Executor struct {
+	logger logging.Logger
+	opts   Options
+}
+
+func New(logger logging.Logger, opts Options) *Executor {
+	return &Executor{
+		logger: logger.With("component", "ffmpeg/executor"),
+		opts:   opts,
+	}
+}
+
+func (e *Executor) Probe(ctx context.Context, input string) (processing.Metadata, error) {
+	const op = "executor.Probe"
+
+	out, err := e.run(ctx, e.opts.FFprobePath,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		input,
+	)
+	if err != nil {
+		return processing.Metadata{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return processing.Metadata{}, fmt.Errorf("%s: decode output: %w", op, err)
+	}
+
+	var meta processing.Metadata
+
+	if seconds, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		meta.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			if meta.VideoCodec == "" {
+				meta.VideoCodec = stream.CodecName
+				meta.Width = stream.Width
+				meta.Height = stream.Height
+			}
+		case "audio":
+			if meta.AudioCodec == "" {
+				meta.AudioCodec = stream.CodecName
+			}
+		}
+	}
+
+	if meta.VideoCodec == "" {
+		return processing.Metadata{}, fmt.Errorf("%s: no video stream", op)
+	}
+
+	return meta, nil
+}
+
+func (e *Executor) Thumbnail(ctx context.Context, input, output string, at time.Duration) error {
+	const op = "executor.Thumbnail"
+
+	if _, err := e.run(ctx, e.opts.FFmpegPath,
+		"-y", "-v", "error",
+		"-ss", strconv.FormatFloat(at.Seconds(), 'f', 3, 64),
+		"-i", input,
+		"-frames:v", "1",
+		"-vf", "scale=1280:-2",
+		output,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (e *Executor) Transcode(ctx context.Context, input, output string) error {
+	const op = "executor.Transcode"
+
+	if _, err := e.run(ctx, e.opts.FFmpegPath,
+		"-y", "-v", "error",
+		"-i", input,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k",
+		"-movflags", "+faststart",
+		output,
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (e *Executor) PackageHLS(ctx context.Context, input, outputDir string, rendition processing.Rendition) error {
+	const op = "executor.PackageHLS"
+
+	videoBitrate := strconv.Itoa(rendition.VideoBitrate) + "k"
+
+	if _, err := e.run(ctx, e.opts.FFmpegPath,
+		"-y", "-v", "error",
+		"-i", input,
+		"-vf", "scale=-2:"+strconv.Itoa(rendition.Height),
+		"-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", "yuv420p",
+		"-b:v", videoBitrate, "-maxrate", videoBitrate, "-bufsize", strconv.Itoa(rendition.VideoBitrate*2)+"k",
+		// Keyframes every two seconds let segments be cut evenly.
+		"-force_key_frames", "expr:gte(t,n_forced*2)",
+		"-c:a", "aac", "-b:a", strconv.Itoa(rendition.AudioBitrate)+"k",
+		"-f", "hls",
+		"-hls_time", "6",
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.ts"),
+		filepath.Join(outputDir, processing.HLSRenditionPlaylist),
+	); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (e *Executor) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	e.logger.WithContext(ctx).Debug("run", "cmd", name, "args", args)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,318 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Output names produced by the default steps.
+const (
+	OutputThumbnail = "thumbnail"
+	OutputVideo     = "video"
+	// OutputHLS prefixes the HLS files, followed by their path relative to the master playlist.
+	OutputHLS = "hls/"
+)
+
+const (
+	HLSMasterPlaylist    = "master.m3u8"
+	HLSRenditionPlaylist = "index.m3u8"
+)
+
+type Metadata struct {
+	Duration   time.Duration
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string
+}
+
+// ErrLimitExceeded fails inputs which are too long or too large, retrying them does not help.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Limits bound the inputs a pipeline accepts, zero values are not checked.
+type Limits struct {
+	MaxDuration  time.Duration
+	MaxDimension int
+}
+
+type Rendition struct {
+	Name   string
+	Height int
+	// Bitrates in kbit/s.
+	VideoBitrate int
+	AudioBitrate int
+}
+
+func DefaultRenditions() []Rendition {
+	return []Rendition{
+		{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+		{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+		{Name: "480p", Height: 480, VideoBitrate: 1400, AudioBitrate: 128},
+		{Name: "360p", Height: 360, VideoBitrate: 800, AudioBitrate: 96},
+	}
+}
+
+// Executor does the actual media work on local files.
+type Executor interface {
+	Probe(ctx context.Context, input string) (Metadata, error)
+	Thumbnail(ctx context.Context, input, output string, at time.Duration) error
+	Transcode(ctx context.Context, input, output string) error
+	// PackageHLS writes the rendition playlist named HLSRenditionPlaylist and its segments into outputDir.
+	PackageHLS(ctx context.Context, input, outputDir string, rendition Rendition) error
+}
+
+type Output struct {
+	Path string
+	Type string
+}
+
+// Task is the state shared by the steps of a single pipeline run.
+type Task struct {
+	// WorkDir is a scratch folder owned by the task, steps write their outputs into it.
+	WorkDir string
+	Input   string
+
+	Metadata Metadata
+	Outputs  map[string]Output
+}
+
+func NewTask(workDir, input string) *Task {
+	return &Task{
+		WorkDir: workDir,
+		Input:   input,
+		Outputs: make(map[string]Output),
+	}
+}
+
+type Step interface {
+	Name() string
+	Run(ctx context.Context, exec Executor, task *Task) error
+}
+
+type Pipeline struct {
+	exec  Executor
+	steps []Step
+}
+
+func New(exec Executor, steps ...Step) *Pipeline {
+	if len(steps) == 0 {
+		steps = DefaultSteps(Limits{})
+	}
+
+	return &Pipeline{
+		exec:  exec,
+		steps: steps,
+	}
+}
+
+// Run executes the steps in order and stops at the first failure.
+func (p *Pipeline) Run(ctx context.Context, task *Task) error {
+	const op = "processing.Pipeline.Run"
+
+	for _, step := range p.steps {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := step.Run(ctx, p.exec, task); err != nil {
+			return fmt.Errorf("%s: step %s: %w", op, step.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func DefaultSteps(limits Limits) []Step {
+	return []Step{ProbeStep(), LimitsStep(limits), ThumbnailStep(), TranscodeStep(), HLSStep(DefaultRenditions()...)}
+}
+
+type stepFunc struct {
+	name string
+	run  func(ctx context.Context, exec Executor, task *Task) error
+}
+
+func (s stepFunc) Name() string {
+	return s.name
+}
+
+func (s stepFunc) Run(ctx context.Context, exec Executor, task *Task) error {
+	return s.run(ctx, exec, task)
+}
+
+// ProbeStep reads the metadata of the input.
+func ProbeStep() Step {
+	return stepFunc{name: "probe", run: func(ctx context.Context, exec Executor, task *Task) error {
+		meta, err := exec.Probe(ctx, task.Input)
+		if err != nil {
+			return err
+		}
+
+		task.Metadata = meta
+
+		return nil
+	}}
+}
+
+// LimitsStep checks the probed metadata, so oversized inputs fail before any encoding is done.
+func LimitsStep(limits Limits) Step {
+	return stepFunc{name: "limits", run: func(_ context.Context, _ Executor, task *Task) error {
+		meta := task.Metadata
+
+		if limits.MaxDuration > 0 && meta.Duration > limits.MaxDuration {
+			return fmt.Errorf("%w: duration %s is longer than %s", ErrLimitExceeded, meta.Duration, limits.MaxDuration)
+		}
+
+		if limits.MaxDimension > 0 && (meta.Width > limits.MaxDimension || meta.Height > limits.MaxDimension) {
+			return fmt.Errorf(
+				"%w: resolution %dx%d is larger than %d",
+				ErrLimitExceeded, meta.Width, meta.Height, limits.MaxDimension,
+			)
+		}
+
+		return nil
+	}}
+}
+
+// ThumbnailStep grabs a frame a tenth into the video, skipping the often black first second.
+// A clip shorter than that gets the frame from its middle.
+func ThumbnailStep() Step {
+	return stepFunc{name: "thumbnail", run: func(ctx context.Context, exec Executor, task *Task) error {
+		at := max(task.Metadata.Duration/10, time.Second)
+		if at >= task.Metadata.Duration {
+			at = task.Metadata.Duration / 2
+		}
+		output := filepath.Join(task.WorkDir, "thumbnail.jpg")
+
+		if err := exec.Thumbnail(ctx, task.Input, output, at); err != nil {
+			return err
+		}
+
+		task.Outputs[OutputThumbnail] = Output{Path: output, Type: "image/jpeg"}
+
+		return nil
+	}}
+}
+
+// TranscodeStep converts the input into a streamable MP4.
+func TranscodeStep() Step {
+	return stepFunc{name: "transcode", run: func(ctx context.Context, exec Executor, task *Task) error {
+		output := filepath.Join(task.WorkDir, "video.mp4")
+
+		if err := exec.Transcode(ctx, task.Input, output); err != nil {
+			return err
+		}
+
+		task.Outputs[OutputVideo] = Output{Path: output, Type: "video/mp4"}
+
+		return nil
+	}}
+}
+
+// HLSStep packages the renditions that do not upscale the source and writes the master playlist.
+func HLSStep(renditions ...Rendition) Step {
+	return stepFunc{name: "hls", run: func(ctx context.Context, exec Executor, task *Task) error {
+		selected := selectRenditions(renditions, task.Metadata.Height)
+		if len(selected) == 0 {
+			return fmt.Errorf("no renditions")
+		}
+
+		hlsDir := filepath.Join(task.WorkDir, "hls")
+
+		var master strings.Builder
+		master.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+		for _, rendition := range selected {
+			outputDir := filepath.Join(hlsDir, rendition.Name)
+			if err := os.MkdirAll(outputDir, 0700); err != nil {
+				return err
+			}
+
+			if err := exec.PackageHLS(ctx, task.Input, outputDir, rendition); err != nil {
+				return fmt.Errorf("rendition %s: %w", rendition.Name, err)
+			}
+
+			if err := addOutputs(task, hlsDir, outputDir); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(&master, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s/%s\n",
+				(rendition.VideoBitrate+rendition.AudioBitrate)*1000,
+				scaledWidth(task.Metadata, rendition.Height), rendition.Height,
+				rendition.Name, HLSRenditionPlaylist,
+			)
+		}
+
+		masterPath := filepath.Join(hlsDir, HLSMasterPlaylist)
+		if err := os.WriteFile(masterPath, []byte(master.String()), 0600); err != nil {
+			return err
+		}
+
+		task.Outputs[OutputHLS+HLSMasterPlaylist] = Output{Path: masterPath, Type: hlsType(masterPath)}
+
+		return nil
+	}}
+}
+
+// selectRenditions keeps the renditions not taller than the source, or the smallest one for tiny sources.
+func selectRenditions(renditions []Rendition, height int) []Rendition {
+	selected := make([]Rendition, 0, len(renditions))
+	smallest := -1
+
+	for i, rendition := range renditions {
+		if height == 0 || rendition.Height <= height {
+			selected = append(selected, rendition)
+		}
+		if smallest < 0 || rendition.Height < renditions[smallest].Height {
+			smallest = i
+		}
+	}
+
+	if len(selected) == 0 && smallest >= 0 {
+		selected = append(selected, renditions[smallest])
+	}
+
+	return selected
+}
+
+// scaledWidth keeps the aspect ratio of the source, rounded to an even number as encoders require.
+func scaledWidth(meta Metadata, height int) int {
+	if meta.Width == 0 || meta.Height == 0 {
+		return height * 16 / 9 &^ 1
+	}
+	return height * meta.Width / meta.Height &^ 1
+}
+
+func addOutputs(task *Task, hlsDir, outputDir string) error {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(outputDir, entry.Name())
+		rel, err := filepath.Rel(hlsDir, path)
+		if err != nil {
+			return err
+		}
+
+		task.Outputs[OutputHLS+filepath.ToSlash(rel)] = Output{Path: path, Type: hlsType(path)}
+	}
+
+	return nil
+}
+
+func hlsType(path string) string {
+	if filepath.Ext(path) == ".m3u8" {
+		return "application/vnd.apple.mpegurl"
+	}
+	return "video/mp2t"
+}
@@ -11,12 +11,33 @@ type (
 		Message  string
 		VideoID  model.ID
 		AuthorID model.ID
+		ParentID *model.ID
+	}
+
+	UpdateCommentDTO struct {
+		Message *string
 	}
 )
 
 type Comment interface {
+	// FindByVideo lists the top level comments of a video, newest first, without the pinned one.
 	FindByVideo(ctx context.Context, videoID model.ID, opts FindOptions) ([]model.Comment, error)
+	// FindByVideoSortByRating lists the top level comments of a video by likes less dislikes, without the pinned one.
+	FindByVideoSortByRating(ctx context.Context, videoID model.ID, opts FindOptions) ([]model.Comment, error)
+	GetPinnedByVideo(ctx context.Context, videoID model.ID) (model.Comment, error)
+	// FindByParent lists the replies to a comment, oldest first.
+	FindByParent(ctx context.Context, parentID model.ID, opts FindOptions) ([]model.Comment, error)
 	Get(ctx context.Context, id model.ID) (model.Comment, error)
 	Create(ctx context.Context, dto CreateCommentDTO) (model.ID, error)
+	// Update marks the comment as edited, the previous text is kept in its edit history.
+	Update(ctx context.Context, id model.ID, dto UpdateCommentDTO) error
+	// FindEdits lists the edit history of a comment, newest first.
+	FindEdits(ctx context.Context, id model.ID) ([]model.CommentEdit, error)
+	// Pin pins the comment and unpins the one pinned before, a video has a single pinned comment.
+	Pin(ctx context.Context, videoID, id model.ID) error
+	Unpin(ctx context.Context, id model.ID) error
+	SetHearted(ctx context.Context, id model.ID, hearted bool) error
+	// MarkDeleted replaces the text of the comment and drops its edit history, keeping the row for its replies.
+	MarkDeleted(ctx context.Context, id model.ID) error
 	Delete(ctx context.Context, id model.ID) error
 }
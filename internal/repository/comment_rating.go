@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/protomem/gotube/internal/model"
+)
+
+type (
+	CommentRatingDTO struct {
+		UserID    model.ID
+		CommentID model.ID
+	}
+
+	CreateCommentRatingDTO struct {
+		CommentRatingDTO
+		Like bool
+	}
+)
+
+// CommentRating keeps the likes and dislikes of comments, their counts are stored on the comments.
+type CommentRating interface {
+	Get(ctx context.Context, dto CommentRatingDTO) (model.CommentRating, error)
+	Create(ctx context.Context, dto CreateCommentRatingDTO) (model.ID, error)
+	Delete(ctx context.Context, dto CommentRatingDTO) error
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/protomem/gotube/internal/model"
+)
+
+type (
+	CreateJobDTO struct {
+		Kind        string
+		Payload     []byte
+		MaxAttempts int
+		RunAt       time.Time
+	}
+
+	UpdateJobDTO struct {
+		Status    *model.JobStatus
+		Attempts  *int
+		LastError *string
+		RunAt     *time.Time
+	}
+)
+
+type Job interface {
+	Get(ctx context.Context, id model.ID) (model.Job, error)
+	Create(ctx context.Context, dto CreateJobDTO) (model.ID, error)
+	// Claim marks the earliest pending job due at the given time, or a running job not updated
+	// within the lease, as running and counts the attempt.
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (model.Job, error)
+	Update(ctx context.Context, id model.ID, dto UpdateJobDTO) error
+}
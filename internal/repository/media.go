@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/protomem/gotube/internal/model"
+)
+
+type CreateMediaDTO struct {
+	// ID is chosen by the caller, as the stored object is named after it.
+	ID          model.ID
+	OwnerID     model.ID
+	Kind        model.MediaKind
+	Parent      string
+	Name        string
+	Size        int64
+	ContentType string
+	Checksum    string
+	Variants    []CreateMediaVariantDTO
+}
+
+type CreateMediaVariantDTO struct {
+	Size        int
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Bytes       int64
+}
+
+type Media interface {
+	Get(ctx context.Context, id model.ID) (model.Media, error)
+	GetByPath(ctx context.Context, parent, name string) (model.Media, error)
+	// GetByVariant returns the media a variant stored at the path was made of.
+	GetByVariant(ctx context.Context, parent, name string) (model.Media, error)
+	Create(ctx context.Context, dto CreateMediaDTO) error
+	Delete(ctx context.Context, id model.ID) error
+	// DeleteByPath forgets the media or the variant stored at the path.
+	DeleteByPath(ctx context.Context, parent, name string) error
+	// IsLinked reports whether a video or a user refers to the media path.
+	IsLinked(ctx context.Context, path string) (bool, error)
+}
@@ -19,8 +19,7 @@ type (
 )
 
 type Rating interface {
-	CountLikes(ctx context.Context, videoID model.ID) (int64, error)
-	CountDislikes(ctx context.Context, videoID model.ID) (int64, error)
+	FindByUserAndVideos(ctx context.Context, userID model.ID, videoIDs []model.ID) ([]model.Rating, error)
 	Get(ctx context.Context, dto RatingDTO) (model.Rating, error)
 	Create(ctx context.Context, dto CreateRatingDTO) (model.ID, error)
 	Delete(ctx context.Context, dto RatingDTO) error
@@ -1,9 +1,24 @@
 package repository
 
+import "context"
+
+// Transactor runs a unit of work in a transaction, the repositories called with the context given to fn take part in it.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type Repositories struct {
+	Transactor
+
 	User
+	Session
+	UserToken
 	Subscription
 	Video
 	Rating
+	CommentRating
 	Comment
+	Upload
+	Media
+	Job
 }
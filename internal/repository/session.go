@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/protomem/gotube/internal/model"
+)
+
+type (
+	CreateSessionDTO struct {
+		UserID       model.ID
+		RefreshToken string
+		UserAgent    string
+		IP           string
+		ExpiresAt    time.Time
+	}
+
+	UpdateSessionDTO struct {
+		RefreshToken string
+		UserAgent    string
+		IP           string
+		ExpiresAt    time.Time
+	}
+)
+
+type Session interface {
+	FindByUser(ctx context.Context, userID model.ID) ([]model.Session, error)
+	Get(ctx context.Context, id model.ID) (model.Session, error)
+	GetByRefreshToken(ctx context.Context, refreshToken string) (model.Session, error)
+	GetByPreviousRefreshToken(ctx context.Context, refreshToken string) (model.Session, error)
+	Create(ctx context.Context, dto CreateSessionDTO) (model.ID, error)
+	// Rotate replaces the refresh token of the session only while it is still oldRefreshToken,
+	// so of two refreshes with the same token only one succeeds.
+	Rotate(ctx context.Context, id model.ID, oldRefreshToken string, dto UpdateSessionDTO) error
+	Delete(ctx context.Context, id model.ID) error
+	DeleteByUser(ctx context.Context, userID model.ID) error
+}
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -22,9 +23,30 @@ type commentEntry struct {
 	Message   string
 	VideoID   string
 	AuthorID  string
+	ParentID  sql.NullString
+	Edited    bool
+	Deleted   bool
+	Likes     int64
+	Dislikes  int64
+	Pinned    bool
+	Hearted   bool
 	Author    userEntry
+	Replies   int64
 }
 
+type commentEditEntry struct {
+	ID        int64
+	CommentID string
+	Message   string
+	EditedAt  int64
+}
+
+// _commentColumns selects a comment with its author and the number of its replies.
+const _commentColumns = `
+	comments.*, authors.*,
+	(SELECT COUNT(*) FROM comments AS replies WHERE replies.parent_id = comments.id)
+`
+
 type Comment struct {
 	logger logging.Logger
 	db     database.DB
@@ -40,14 +62,118 @@ func NewComment(logger logging.Logger, db database.DB) *Comment {
 func (r *Comment) FindByVideo(ctx context.Context, videoID model.ID, opts repository.FindOptions) ([]model.Comment, error) {
 	const op = "repository.Comment.FindByVideo"
 
+	after, afterArgs := afterCursor("comments.created_at", "comments.id", opts.Cursor)
+
+	query := `
+		SELECT ` + _commentColumns + ` FROM comments
+		JOIN users AS authors ON comments.author_id = authors.id
+		WHERE comments.video_id = ? AND comments.parent_id IS NULL AND comments.is_pinned = 0 AND ` + after + `
+		ORDER BY comments.created_at DESC, comments.id DESC
+		LIMIT ? OFFSET ?
+	`
+	args := append([]any{videoID.String()}, afterArgs...)
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return []model.Comment{}, nil
+		}
+
+		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	comments := make([]model.Comment, 0, opts.Limit)
+	for rows.Next() {
+		comment, err := r.scan(rows)
+		if err != nil {
+			return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+func (r *Comment) FindByVideoSortByRating(ctx context.Context, videoID model.ID, opts repository.FindOptions) ([]model.Comment, error) {
+	const op = "repository.Comment.FindByVideoSortByRating"
+
+	after, afterArgs := afterCursor("(comments.likes - comments.dislikes)", "comments.id", opts.Cursor)
+
 	query := `
-		SELECT comments.*, authors.* FROM comments
+		SELECT ` + _commentColumns + ` FROM comments
 		JOIN users AS authors ON comments.author_id = authors.id
-		WHERE comments.video_id = ?
-		ORDER BY comments.created_at DESC
+		WHERE comments.video_id = ? AND comments.parent_id IS NULL AND comments.is_pinned = 0 AND ` + after + `
+		ORDER BY (comments.likes - comments.dislikes) DESC, comments.id DESC
+		LIMIT ? OFFSET ?
+	`
+	args := append([]any{videoID.String()}, afterArgs...)
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return []model.Comment{}, nil
+		}
+
+		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	comments := make([]model.Comment, 0, opts.Limit)
+	for rows.Next() {
+		comment, err := r.scan(rows)
+		if err != nil {
+			return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+func (r *Comment) GetPinnedByVideo(ctx context.Context, videoID model.ID) (model.Comment, error) {
+	const op = "repository.Comment.GetPinnedByVideo"
+
+	query := `
+		SELECT ` + _commentColumns + ` FROM comments
+		JOIN users AS authors ON comments.author_id = authors.id
+		WHERE comments.video_id = ? AND comments.is_pinned > 0
+		LIMIT 1
 	`
 	args := []any{videoID.String()}
 
+	row := r.db.QueryRow(ctx, query, args...)
+	comment, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Comment{}, fmt.Errorf("%s: %w", op, model.ErrCommentNotFound)
+		}
+
+		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comment, nil
+}
+
+func (r *Comment) FindByParent(ctx context.Context, parentID model.ID, opts repository.FindOptions) ([]model.Comment, error) {
+	const op = "repository.Comment.FindByParent"
+
+	after, afterArgs := afterCursorAsc("comments.created_at", "comments.id", opts.Cursor)
+
+	query := `
+		SELECT ` + _commentColumns + ` FROM comments
+		JOIN users AS authors ON comments.author_id = authors.id
+		WHERE comments.parent_id = ? AND ` + after + `
+		ORDER BY comments.created_at, comments.id
+		LIMIT ? OFFSET ?
+	`
+	args := append([]any{parentID.String()}, afterArgs...)
+	args = append(args, opts.Limit, opts.Offset)
+
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		if sqlite.IsNoRows(err) {
@@ -75,7 +201,7 @@ func (r *Comment) Get(ctx context.Context, id model.ID) (model.Comment, error) {
 	const op = "repository.Comment.Find"
 
 	query := `
-		SELECT comments.*, authors.* FROM comments
+		SELECT ` + _commentColumns + ` FROM comments
 		JOIN users AS authors ON comments.author_id = authors.id
 		WHERE comments.id = ?
 		LIMIT 1
@@ -104,8 +230,13 @@ func (r *Comment) Create(ctx context.Context, dto repository.CreateCommentDTO) (
 	}
 	now := time.Now()
 
-	query := `INSERT INTO comments (id, created_at, updated_at, message, video_id, author_id) VALUES (?, ?, ?, ?, ?, ?)`
-	args := []any{id.String(), now.Unix(), now.Unix(), dto.Message, dto.VideoID.String(), dto.AuthorID.String()}
+	var parentID sql.NullString
+	if dto.ParentID != nil {
+		parentID = sql.NullString{String: dto.ParentID.String(), Valid: true}
+	}
+
+	query := `INSERT INTO comments (id, created_at, updated_at, message, video_id, author_id, parent_id) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	args := []any{id.String(), now.Unix(), now.Unix(), dto.Message, dto.VideoID.String(), dto.AuthorID.String(), parentID}
 
 	if err := r.db.Exec(ctx, query, args...); err != nil {
 		return model.ID{}, fmt.Errorf("%s: %w", op, err)
@@ -114,6 +245,128 @@ func (r *Comment) Create(ctx context.Context, dto repository.CreateCommentDTO) (
 	return id, nil
 }
 
+func (r *Comment) Update(ctx context.Context, id model.ID, dto repository.UpdateCommentDTO) error {
+	const op = "repository.Comment.Update"
+
+	now := time.Now()
+
+	query := `UPDATE comments SET updated_at = ?`
+	args := []any{now.Unix()}
+
+	if dto.Message != nil {
+		query += `, message = ?, is_edited = 1`
+		args = append(args, *dto.Message)
+	}
+
+	query += ` WHERE id = ?`
+	args = append(args, id.String())
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Comment) FindEdits(ctx context.Context, id model.ID) ([]model.CommentEdit, error) {
+	const op = "repository.Comment.FindEdits"
+
+	query := `SELECT * FROM comment_edits WHERE comment_id = ? ORDER BY edited_at DESC, id DESC`
+	args := []any{id.String()}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return []model.CommentEdit{}, nil
+		}
+
+		return []model.CommentEdit{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	edits := make([]model.CommentEdit, 0)
+	for rows.Next() {
+		var entry commentEditEntry
+		if err := rows.Scan(&entry.ID, &entry.CommentID, &entry.Message, &entry.EditedAt); err != nil {
+			return []model.CommentEdit{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		commentID, err := uuid.Parse(entry.CommentID)
+		if err != nil {
+			return []model.CommentEdit{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		edits = append(edits, model.CommentEdit{
+			ID:        entry.ID,
+			CommentID: commentID,
+			Message:   entry.Message,
+			EditedAt:  time.Unix(entry.EditedAt, 0),
+		})
+	}
+
+	return edits, nil
+}
+
+func (r *Comment) Pin(ctx context.Context, videoID, id model.ID) error {
+	const op = "repository.Comment.Pin"
+
+	// A single statement, so the video never has two pinned comments.
+	query := `UPDATE comments SET is_pinned = (id = ?) WHERE video_id = ? AND (is_pinned > 0 OR id = ?)`
+	args := []any{id.String(), videoID.String(), id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Comment) Unpin(ctx context.Context, id model.ID) error {
+	const op = "repository.Comment.Unpin"
+
+	query := `UPDATE comments SET is_pinned = 0 WHERE id = ?`
+	args := []any{id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Comment) SetHearted(ctx context.Context, id model.ID, hearted bool) error {
+	const op = "repository.Comment.SetHearted"
+
+	query := `UPDATE comments SET is_hearted = ? WHERE id = ?`
+	args := []any{hearted, id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Comment) MarkDeleted(ctx context.Context, id model.ID) error {
+	const op = "repository.Comment.MarkDeleted"
+
+	query := `UPDATE comments SET updated_at = ?, message = ?, is_deleted = 1, is_pinned = 0, is_hearted = 0 WHERE id = ?`
+	args := []any{time.Now().Unix(), model.DeletedCommentMessage, id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `DELETE FROM comment_edits WHERE comment_id = ?`
+	args = []any{id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (r *Comment) Delete(ctx context.Context, id model.ID) error {
 	const op = "repository.Comment.Delete"
 
@@ -132,11 +385,18 @@ func (r *Comment) scan(s database.Scanner) (model.Comment, error) {
 	if err := s.Scan(
 		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
 		&entry.Message, &entry.VideoID, &entry.AuthorID,
+		&entry.ParentID,
+		&entry.Edited, &entry.Deleted,
+		&entry.Likes, &entry.Dislikes,
+		&entry.Pinned, &entry.Hearted,
 
 		&entry.Author.ID, &entry.Author.CreatedAt, &entry.Author.UpdatedAt,
 		&entry.Author.Nickname, &entry.Author.Password,
 		&entry.Author.Email, &entry.Author.Verified,
 		&entry.Author.AvatarPath, &entry.Author.Description,
+		&entry.Author.Role,
+
+		&entry.Replies,
 	); err != nil {
 		return model.Comment{}, err
 	}
@@ -154,6 +414,15 @@ func (r *Comment) scan(s database.Scanner) (model.Comment, error) {
 		return model.Comment{}, err
 	}
 
+	var parentID *model.ID
+	if entry.ParentID.Valid {
+		id, err := uuid.Parse(entry.ParentID.String)
+		if err != nil {
+			return model.Comment{}, err
+		}
+		parentID = &id
+	}
+
 	authorID, err := uuid.Parse(entry.Author.ID)
 	if err != nil {
 		return model.Comment{}, err
@@ -182,6 +451,15 @@ func (r *Comment) scan(s database.Scanner) (model.Comment, error) {
 			Verified:    entry.Author.Verified,
 			AvatarPath:  entry.Author.AvatarPath,
 			Description: entry.Author.Description,
+			Role:        model.Role(entry.Author.Role),
 		},
+		ParentID: parentID,
+		Replies:  entry.Replies,
+		Edited:   entry.Edited,
+		Deleted:  entry.Deleted,
+		Likes:    entry.Likes,
+		Dislikes: entry.Dislikes,
+		Pinned:   entry.Pinned,
+		Hearted:  entry.Hearted,
 	}, nil
 }
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/database"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ repository.CommentRating = (*CommentRating)(nil)
+
+type commentRatingEntry struct {
+	ID        string
+	CreatedAt int64
+	UpdatedAt int64
+	CommentID string
+	UserID    string
+	Like      bool
+}
+
+type CommentRating struct {
+	logger logging.Logger
+	db     database.DB
+}
+
+func NewCommentRating(logger logging.Logger, db database.DB) *CommentRating {
+	return &CommentRating{
+		logger: logger.With("repository", "sqlite/comment_rating"),
+		db:     db,
+	}
+}
+
+func (r *CommentRating) Get(ctx context.Context, dto repository.CommentRatingDTO) (model.CommentRating, error) {
+	const op = "repository.CommentRating.Get"
+
+	query := `SELECT * FROM comment_ratings WHERE comment_id = ? AND user_id = ? LIMIT 1`
+	args := []any{dto.CommentID.String(), dto.UserID.String()}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	rating, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.CommentRating{}, fmt.Errorf("%s: %w", op, model.ErrRatingNotFound)
+		}
+
+		return model.CommentRating{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rating, nil
+}
+
+func (r *CommentRating) Create(ctx context.Context, dto repository.CreateCommentRatingDTO) (model.ID, error) {
+	const op = "repository.CommentRating.Create"
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+
+	query := `INSERT INTO comment_ratings (id, created_at, updated_at, comment_id, user_id, is_like) VALUES (?, ?, ?, ?, ?, ?)`
+	args := []any{id.String(), now.Unix(), now.Unix(), dto.CommentID.String(), dto.UserID.String(), dto.Like}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		if sqlite.IsKeyConflict(err) {
+			return model.ID{}, fmt.Errorf("%s: %w", op, model.ErrRatingExists)
+		}
+
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (r *CommentRating) Delete(ctx context.Context, dto repository.CommentRatingDTO) error {
+	const op = "repository.CommentRating.Delete"
+
+	query := `DELETE FROM comment_ratings WHERE comment_id = ? AND user_id = ?`
+	args := []any{dto.CommentID.String(), dto.UserID.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *CommentRating) scan(s database.Scanner) (model.CommentRating, error) {
+	var entry commentRatingEntry
+	if err := s.Scan(
+		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
+		&entry.UserID, &entry.CommentID,
+		&entry.Like,
+	); err != nil {
+		return model.CommentRating{}, err
+	}
+
+	id, err := uuid.Parse(entry.ID)
+	if err != nil {
+		return model.CommentRating{}, err
+	}
+
+	createdAt := time.Unix(entry.CreatedAt, 0)
+	updatedAt := time.Unix(entry.UpdatedAt, 0)
+
+	userID, err := uuid.Parse(entry.UserID)
+	if err != nil {
+		return model.CommentRating{}, err
+	}
+
+	commentID, err := uuid.Parse(entry.CommentID)
+	if err != nil {
+		return model.CommentRating{}, err
+	}
+
+	return model.CommentRating{
+		Model: model.Model{
+			ID:        model.ID(id),
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		},
+		UserID:    model.ID(userID),
+		CommentID: model.ID(commentID),
+		Like:      entry.Like,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/database"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ repository.Job = (*Job)(nil)
+
+type jobEntry struct {
+	ID          string
+	CreatedAt   int64
+	UpdatedAt   int64
+	Kind        string
+	Payload     string
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	RunAt       int64
+}
+
+type Job struct {
+	logger logging.Logger
+	db     database.DB
+}
+
+func NewJob(logger logging.Logger, db database.DB) *Job {
+	return &Job{
+		logger: logger.With("repository", "sqlite/job"),
+		db:     db,
+	}
+}
+
+func (r *Job) Get(ctx context.Context, id model.ID) (model.Job, error) {
+	const op = "repository.Job.Get"
+
+	query := `SELECT * FROM jobs WHERE id = ? LIMIT 1`
+	args := []any{id.String()}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	job, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Job{}, fmt.Errorf("%s: %w", op, model.ErrJobNotFound)
+		}
+
+		return model.Job{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return job, nil
+}
+
+func (r *Job) Create(ctx context.Context, dto repository.CreateJobDTO) (model.ID, error) {
+	const op = "repository.Job.Create"
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+
+	query := `
+		INSERT INTO jobs (id, created_at, updated_at, kind, payload, status, max_attempts, run_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	args := []any{
+		id.String(), now.Unix(), now.Unix(),
+		dto.Kind, string(dto.Payload), model.JobStatusPending, dto.MaxAttempts, dto.RunAt.Unix(),
+	}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (r *Job) Claim(ctx context.Context, now time.Time, lease time.Duration) (model.Job, error) {
+	const op = "repository.Job.Claim"
+
+	// A single statement keeps concurrent workers from claiming the same job.
+	query := `
+		UPDATE jobs SET status = ?, attempts = attempts + 1, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE (status = ? AND run_at <= ?) OR (status = ? AND updated_at < ?)
+			ORDER BY run_at, created_at
+			LIMIT 1
+		)
+		RETURNING *
+	`
+	args := []any{
+		model.JobStatusRunning, now.Unix(),
+		model.JobStatusPending, now.Unix(),
+		model.JobStatusRunning, now.Add(-lease).Unix(),
+	}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	job, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Job{}, fmt.Errorf("%s: %w", op, model.ErrJobNotFound)
+		}
+
+		return model.Job{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return job, nil
+}
+
+func (r *Job) Update(ctx context.Context, id model.ID, dto repository.UpdateJobDTO) error {
+	const op = "repository.Job.Update"
+
+	now := time.Now()
+
+	query := `UPDATE jobs SET updated_at = ?`
+	args := []any{now.Unix()}
+
+	if dto.Status != nil {
+		query += `, status = ?`
+		args = append(args, *dto.Status)
+	}
+	if dto.Attempts != nil {
+		query += `, attempts = ?`
+		args = append(args, *dto.Attempts)
+	}
+	if dto.LastError != nil {
+		query += `, last_error = ?`
+		args = append(args, *dto.LastError)
+	}
+	if dto.RunAt != nil {
+		query += `, run_at = ?`
+		args = append(args, dto.RunAt.Unix())
+	}
+
+	query += ` WHERE id = ?`
+	args = append(args, id.String())
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Job) scan(s database.Scanner) (model.Job, error) {
+	var entry jobEntry
+	if err := s.Scan(
+		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
+		&entry.Kind, &entry.Payload,
+		&entry.Status, &entry.Attempts, &entry.MaxAttempts, &entry.LastError,
+		&entry.RunAt,
+	); err != nil {
+		return model.Job{}, err
+	}
+
+	id, err := uuid.Parse(entry.ID)
+	if err != nil {
+		return model.Job{}, err
+	}
+
+	createdAt := time.Unix(entry.CreatedAt, 0)
+	updatedAt := time.Unix(entry.UpdatedAt, 0)
+	runAt := time.Unix(entry.RunAt, 0)
+
+	return model.Job{
+		Model: model.Model{
+			ID:        id,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		},
+		Kind:        entry.Kind,
+		Payload:     []byte(entry.Payload),
+		Status:      model.JobStatus(entry.Status),
+		Attempts:    entry.Attempts,
+		MaxAttempts: entry.MaxAttempts,
+		LastError:   entry.LastError,
+		RunAt:       runAt,
+	}, nil
+}
@@ -0,0 +1,54 @@
+//go:build sqlite_fts5
+
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
+)
+
+func TestJobClaimTakesOverExpiredLease(t *testing.T) {
+	const lease = time.Minute
+
+	ctx := context.Background()
+	logger := sqlitetest.Logger(t)
+	jobs := sqliterepo.NewJob(logger, sqlitetest.Open(t))
+
+	now := time.Now()
+	id, err := jobs.Create(ctx, repository.CreateJobDTO{Kind: "test", MaxAttempts: 3, RunAt: now})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	job, err := jobs.Claim(ctx, now, lease)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if job.ID != id || job.Attempts != 1 {
+		t.Fatalf("claimed %s at attempt %d, want %s at attempt 1", job.ID, job.Attempts, id)
+	}
+
+	// A running job is left to its worker while the lease holds.
+	if _, err := jobs.Claim(ctx, now.Add(lease/2), lease); !errors.Is(err, model.ErrJobNotFound) {
+		t.Fatalf("claim within lease = %v, want %v", err, model.ErrJobNotFound)
+	}
+
+	// The attempt of the lost worker is kept, so a job crashing its workers runs out of attempts.
+	job, err = jobs.Claim(ctx, now.Add(2*lease), lease)
+	if err != nil {
+		t.Fatalf("claim after lease: %v", err)
+	}
+	if job.ID != id || job.Attempts != 2 {
+		t.Fatalf("claimed %s at attempt %d, want %s at attempt 2", job.ID, job.Attempts, id)
+	}
+	if job.Status != model.JobStatusRunning {
+		t.Fatalf("status = %s, want %s", job.Status, model.JobStatusRunning)
+	}
+}
@@ -0,0 +1,284 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/database"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ repository.Media = (*Media)(nil)
+
+type mediaEntry struct {
+	ID          string
+	CreatedAt   int64
+	UpdatedAt   int64
+	OwnerID     string
+	Kind        string
+	Parent      string
+	Name        string
+	Size        int64
+	ContentType string
+	Checksum    string
+}
+
+type Media struct {
+	logger logging.Logger
+	db     database.DB
+}
+
+func NewMedia(logger logging.Logger, db database.DB) *Media {
+	return &Media{
+		logger: logger.With("repository", "sqlite/media"),
+		db:     db,
+	}
+}
+
+func (r *Media) Get(ctx context.Context, id model.ID) (model.Media, error) {
+	const op = "repository.Media.Get"
+
+	query := `SELECT * FROM media WHERE id = ? LIMIT 1`
+	args := []any{id.String()}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	media, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Media{}, fmt.Errorf("%s: %w", op, model.ErrMediaNotFound)
+		}
+
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	media.Variants, err = r.variants(ctx, media.ID)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return media, nil
+}
+
+func (r *Media) GetByPath(ctx context.Context, parent, name string) (model.Media, error) {
+	const op = "repository.Media.GetByPath"
+
+	query := `SELECT * FROM media WHERE parent = ? AND name = ? LIMIT 1`
+	args := []any{parent, name}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	media, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Media{}, fmt.Errorf("%s: %w", op, model.ErrMediaNotFound)
+		}
+
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	media.Variants, err = r.variants(ctx, media.ID)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return media, nil
+}
+
+func (r *Media) GetByVariant(ctx context.Context, parent, name string) (model.Media, error) {
+	const op = "repository.Media.GetByVariant"
+
+	query := `
+		SELECT media.* FROM media
+		JOIN media_variants ON media_variants.media_id = media.id
+		WHERE media.parent = ? AND media_variants.name = ?
+		LIMIT 1
+	`
+	args := []any{parent, name}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	media, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Media{}, fmt.Errorf("%s: %w", op, model.ErrMediaNotFound)
+		}
+
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	media.Variants, err = r.variants(ctx, media.ID)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return media, nil
+}
+
+func (r *Media) Create(ctx context.Context, dto repository.CreateMediaDTO) error {
+	const op = "repository.Media.Create"
+
+	now := time.Now()
+
+	query := `
+		INSERT INTO media (id, created_at, updated_at, owner_id, kind, parent, name, size, content_type, checksum)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	args := []any{
+		dto.ID.String(), now.Unix(), now.Unix(),
+		dto.OwnerID.String(), string(dto.Kind),
+		dto.Parent, dto.Name,
+		dto.Size, dto.ContentType, dto.Checksum,
+	}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, variant := range dto.Variants {
+		query := `
+			INSERT INTO media_variants (media_id, size, name, width, height, content_type, bytes)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`
+		args := []any{
+			dto.ID.String(), variant.Size, variant.Name,
+			variant.Width, variant.Height,
+			variant.ContentType, variant.Bytes,
+		}
+
+		if err := r.db.Exec(ctx, query, args...); err != nil {
+			// Variants are removed with the media.
+			_ = r.Delete(ctx, dto.ID)
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Media) Delete(ctx context.Context, id model.ID) error {
+	const op = "repository.Media.Delete"
+
+	query := `DELETE FROM media WHERE id = ?`
+	args := []any{id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Media) DeleteByPath(ctx context.Context, parent, name string) error {
+	const op = "repository.Media.DeleteByPath"
+
+	query := `DELETE FROM media WHERE parent = ? AND name = ?`
+	args := []any{parent, name}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `
+		DELETE FROM media_variants
+		WHERE name = ? AND media_id IN (SELECT id FROM media WHERE parent = ?)
+	`
+	args = []any{name, parent}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Media) IsLinked(ctx context.Context, path string) (bool, error) {
+	const op = "repository.Media.IsLinked"
+
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM videos WHERE video_path = ? OR thumbnail_path = ?)
+			OR EXISTS (SELECT 1 FROM users WHERE avatar_path = ?)
+	`
+	args := []any{path, path, path}
+
+	var linked bool
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&linked); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return linked, nil
+}
+
+func (r *Media) variants(ctx context.Context, mediaID model.ID) ([]model.MediaVariant, error) {
+	query := `
+		SELECT size, name, width, height, content_type, bytes FROM media_variants
+		WHERE media_id = ?
+		ORDER BY size
+	`
+	args := []any{mediaID.String()}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	variants := make([]model.MediaVariant, 0)
+	for rows.Next() {
+		var variant model.MediaVariant
+		if err := rows.Scan(
+			&variant.Size, &variant.Name,
+			&variant.Width, &variant.Height,
+			&variant.ContentType, &variant.Bytes,
+		); err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, variant)
+	}
+
+	return variants, nil
+}
+
+func (r *Media) scan(s database.Scanner) (model.Media, error) {
+	var entry mediaEntry
+	if err := s.Scan(
+		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
+		&entry.OwnerID, &entry.Kind,
+		&entry.Parent, &entry.Name,
+		&entry.Size, &entry.ContentType, &entry.Checksum,
+	); err != nil {
+		return model.Media{}, err
+	}
+
+	id, err := uuid.Parse(entry.ID)
+	if err != nil {
+		return model.Media{}, err
+	}
+
+	createdAt := time.Unix(entry.CreatedAt, 0)
+	updatedAt := time.Unix(entry.UpdatedAt, 0)
+
+	ownerID, err := uuid.Parse(entry.OwnerID)
+	if err != nil {
+		return model.Media{}, err
+	}
+
+	return model.Media{
+		Model: model.Model{
+			ID:        id,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		},
+		OwnerID:     ownerID,
+		Kind:        model.MediaKind(entry.Kind),
+		Parent:      entry.Parent,
+		Name:        entry.Name,
+		Size:        entry.Size,
+		ContentType: entry.ContentType,
+		Checksum:    entry.Checksum,
+	}, nil
+}
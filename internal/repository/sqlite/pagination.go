@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"github.com/protomem/gotube/internal/model"
+)
+
+// afterCursor is the condition selecting the rows after the cursor of a listing sorted by the key
+// and then the id column, both descending. Without a cursor it selects every row.
+func afterCursor(key, id string, cursor *model.Cursor) (string, []any) {
+	if cursor == nil {
+		return "1", nil
+	}
+
+	cond := "(" + key + " < ? OR (" + key + " = ? AND " + id + " < ?))"
+	return cond, []any{cursor.Key, cursor.Key, cursor.ID.String()}
+}
+
+// afterCursorAsc is afterCursor for a listing sorted in ascending order.
+func afterCursorAsc(key, id string, cursor *model.Cursor) (string, []any) {
+	if cursor == nil {
+		return "1", nil
+	}
+
+	cond := "(" + key + " > ? OR (" + key + " = ? AND " + id + " > ?))"
+	return cond, []any{cursor.Key, cursor.Key, cursor.ID.String()}
+}
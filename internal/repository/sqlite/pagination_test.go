@@ -0,0 +1,190 @@
+//go:build sqlite_fts5
+
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
+)
+
+func TestPaginationWithEqualKeys(t *testing.T) {
+	const (
+		rows  = 23
+		limit = 4
+	)
+
+	ctx := context.Background()
+	db := sqlitetest.Open(t)
+	repos := sqliterepo.New(sqlitetest.Logger(t), db)
+
+	author := createUser(t, repos, "author")
+	users := []model.ID{author.ID}
+	for i := 1; i < rows; i++ {
+		users = append(users, createUser(t, repos, fmt.Sprintf("user%d", i)).ID)
+	}
+
+	videos := make([]model.ID, 0, rows)
+	for i := 0; i < rows; i++ {
+		videos = append(videos, createVideo(t, repos, author.ID, fmt.Sprintf("video %d", i)))
+	}
+
+	createComment := func(parentID *model.ID) model.ID {
+		t.Helper()
+
+		id, err := repos.Comment.Create(ctx, repository.CreateCommentDTO{
+			Message:  "comment",
+			VideoID:  videos[0],
+			AuthorID: author.ID,
+			ParentID: parentID,
+		})
+		if err != nil {
+			t.Fatalf("create comment: %v", err)
+		}
+
+		return id
+	}
+
+	comments := make([]model.ID, 0, rows)
+	for i := 0; i < rows; i++ {
+		comments = append(comments, createComment(nil))
+	}
+
+	replies := make([]model.ID, 0, rows)
+	for i := 0; i < rows; i++ {
+		replies = append(replies, createComment(&comments[0]))
+	}
+
+	// Every row is made in the same second, and the counters repeat, so the pages split runs of equal keys.
+	for _, query := range []string{
+		`UPDATE users SET created_at = 1700000000`,
+		`UPDATE videos SET created_at = 1700000000, views = rowid % 3`,
+		`UPDATE comments SET created_at = 1700000000, likes = rowid % 3, dislikes = rowid % 2`,
+	} {
+		if err := db.Exec(ctx, query); err != nil {
+			t.Fatalf("equalize keys: %v", err)
+		}
+	}
+
+	createdAt := func(m model.Model) int64 { return m.CreatedAt.Unix() }
+
+	t.Run("users", func(t *testing.T) {
+		pageThrough(t, users, limit, true, repos.User.Find,
+			func(user model.User) (int64, model.ID) { return createdAt(user.Model), user.ID })
+	})
+
+	t.Run("latest videos", func(t *testing.T) {
+		pageThrough(t, videos, limit, true, repos.Video.FindSortByCreatedAtWherePublic,
+			func(video model.Video) (int64, model.ID) { return createdAt(video.Model), video.ID })
+	})
+
+	t.Run("most viewed videos", func(t *testing.T) {
+		pageThrough(t, videos, limit, true, repos.Video.FindSortByViewsWherePublic,
+			func(video model.Video) (int64, model.ID) { return video.Views, video.ID })
+	})
+
+	t.Run("videos of author", func(t *testing.T) {
+		find := func(ctx context.Context, opts repository.FindOptions) ([]model.Video, error) {
+			return repos.Video.FindByAuthorSortByCreatedAt(ctx, author.ID, opts)
+		}
+		pageThrough(t, videos, limit, true, find,
+			func(video model.Video) (int64, model.ID) { return createdAt(video.Model), video.ID })
+	})
+
+	t.Run("latest comments", func(t *testing.T) {
+		find := func(ctx context.Context, opts repository.FindOptions) ([]model.Comment, error) {
+			return repos.Comment.FindByVideo(ctx, videos[0], opts)
+		}
+		pageThrough(t, comments, limit, true, find,
+			func(comment model.Comment) (int64, model.ID) { return createdAt(comment.Model), comment.ID })
+	})
+
+	t.Run("top comments", func(t *testing.T) {
+		find := func(ctx context.Context, opts repository.FindOptions) ([]model.Comment, error) {
+			return repos.Comment.FindByVideoSortByRating(ctx, videos[0], opts)
+		}
+		pageThrough(t, comments, limit, true, find,
+			func(comment model.Comment) (int64, model.ID) { return comment.Likes - comment.Dislikes, comment.ID })
+	})
+
+	t.Run("replies", func(t *testing.T) {
+		find := func(ctx context.Context, opts repository.FindOptions) ([]model.Comment, error) {
+			return repos.Comment.FindByParent(ctx, comments[0], opts)
+		}
+		pageThrough(t, replies, limit, false, find,
+			func(comment model.Comment) (int64, model.ID) { return createdAt(comment.Model), comment.ID })
+	})
+}
+
+// pageThrough follows the cursors of a listing to its end and checks that it returns every row
+// of want exactly once, sorted by the key and then the id.
+func pageThrough[T any](
+	t *testing.T, want []model.ID, limit uint64, desc bool,
+	find func(ctx context.Context, opts repository.FindOptions) ([]T, error),
+	keyOf func(row T) (int64, model.ID),
+) {
+	t.Helper()
+
+	type entry struct {
+		key int64
+		id  string
+	}
+
+	var (
+		cursor *model.Cursor
+		seen   = make(map[model.ID]bool, len(want))
+		prev   *entry
+	)
+
+	for page := 0; ; page++ {
+		if page > len(want) {
+			t.Fatalf("listing does not end after %d pages", page)
+		}
+
+		rows, err := find(context.Background(), repository.FindOptions{Limit: limit, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+
+		for _, row := range rows {
+			key, id := keyOf(row)
+			if seen[id] {
+				t.Fatalf("page %d: %s listed twice", page, id)
+			}
+			seen[id] = true
+
+			cur := entry{key: key, id: id.String()}
+			if prev != nil {
+				inOrder := cur.key < prev.key || (cur.key == prev.key && cur.id < prev.id)
+				if !desc {
+					inOrder = cur.key > prev.key || (cur.key == prev.key && cur.id > prev.id)
+				}
+				if !inOrder {
+					t.Fatalf("page %d: %+v listed after %+v", page, cur, *prev)
+				}
+			}
+			prev = &cur
+		}
+
+		if len(rows) < int(limit) {
+			break
+		}
+
+		key, id := keyOf(rows[len(rows)-1])
+		cursor = &model.Cursor{Key: key, ID: id}
+	}
+
+	if len(seen) != len(want) {
+		t.Fatalf("listed %d rows, want %d", len(seen), len(want))
+	}
+	for _, id := range want {
+		if !seen[id] {
+			t.Fatalf("%s not listed", id)
+		}
+	}
+}
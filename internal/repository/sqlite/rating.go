@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -36,44 +37,41 @@ func NewRating(logger logging.Logger, db database.DB) *Rating {
 	}
 }
 
-func (r *Rating) CountLikes(ctx context.Context, videoID model.ID) (int64, error) {
-	const op = "repository.Rating.CountLikes"
+func (r *Rating) FindByUserAndVideos(ctx context.Context, userID model.ID, videoIDs []model.ID) ([]model.Rating, error) {
+	const op = "repository.Rating.FindByUserAndVideos"
 
-	query := `SELECT COUNT(id) FROM ratings WHERE video_id = ? AND is_like > 0`
-	args := []any{videoID.String()}
+	if len(videoIDs) == 0 {
+		return []model.Rating{}, nil
+	}
 
-	var count int64
+	query := `SELECT * FROM ratings WHERE user_id = ? AND video_id IN (?` + strings.Repeat(", ?", len(videoIDs)-1) + `)`
+	args := make([]any, 0, len(videoIDs)+1)
+	args = append(args, userID.String())
+	for _, videoID := range videoIDs {
+		args = append(args, videoID.String())
+	}
 
-	row := r.db.QueryRow(ctx, query, args...)
-	if err := row.Scan(&count); err != nil {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
 		if sqlite.IsNoRows(err) {
-			return 0, nil
+			return []model.Rating{}, nil
 		}
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return []model.Rating{}, fmt.Errorf("%s: %w", op, err)
 	}
+	defer func() { _ = rows.Close() }()
 
-	return count, nil
-}
-
-func (r *Rating) CountDislikes(ctx context.Context, videoID model.ID) (int64, error) {
-	const op = "repository.Rating.CountDislikes"
-
-	query := `SELECT COUNT(id) FROM ratings WHERE video_id = ? AND is_like <= 0`
-	args := []any{videoID.String()}
-
-	var count int64
-
-	row := r.db.QueryRow(ctx, query, args...)
-	if err := row.Scan(&count); err != nil {
-		if sqlite.IsNoRows(err) {
-			return 0, nil
+	ratings := make([]model.Rating, 0, len(videoIDs))
+	for rows.Next() {
+		rating, err := r.scan(rows)
+		if err != nil {
+			return []model.Rating{}, fmt.Errorf("%s: %w", op, err)
 		}
 
-		return 0, fmt.Errorf("%s: %w", op, err)
+		ratings = append(ratings, rating)
 	}
 
-	return count, nil
+	return ratings, nil
 }
 
 func (r *Rating) Get(ctx context.Context, dto repository.RatingDTO) (model.Rating, error) {
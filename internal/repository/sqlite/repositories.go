@@ -8,10 +8,18 @@ import (
 
 func New(logger logging.Logger, db database.DB) *repository.Repositories {
 	return &repository.Repositories{
-		User:         NewUser(logger, db),
-		Subscription: NewSubscription(logger, db),
-		Video:        NewVideo(logger, db),
-		Rating:       NewRating(logger, db),
-		Comment:      NewComment(logger, db),
+		Transactor: db,
+
+		User:          NewUser(logger, db),
+		Session:       NewSession(logger, db),
+		UserToken:     NewUserToken(logger, db),
+		Subscription:  NewSubscription(logger, db),
+		Video:         NewVideo(logger, db),
+		Rating:        NewRating(logger, db),
+		CommentRating: NewCommentRating(logger, db),
+		Comment:       NewComment(logger, db),
+		Upload:        NewUpload(logger, db),
+		Media:         NewMedia(logger, db),
+		Job:           NewJob(logger, db),
 	}
 }
@@ -0,0 +1,249 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/database"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ repository.Session = (*Session)(nil)
+
+type sessionEntry struct {
+	ID           string
+	CreatedAt    int64
+	UpdatedAt    int64
+	UserID       string
+	RefreshToken string
+	UserAgent    string
+	IP           string
+	ExpiresAt    int64
+
+	PreviousRefreshToken sql.NullString
+}
+
+type Session struct {
+	logger logging.Logger
+	db     database.DB
+}
+
+func NewSession(logger logging.Logger, db database.DB) *Session {
+	return &Session{
+		logger: logger.With("repository", "sqlite/session"),
+		db:     db,
+	}
+}
+
+func (r *Session) FindByUser(ctx context.Context, userID model.ID) ([]model.Session, error) {
+	const op = "repository.Session.FindByUser"
+
+	query := `SELECT * FROM sessions WHERE user_id = ? ORDER BY updated_at DESC`
+	args := []any{userID.String()}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return []model.Session{}, nil
+		}
+
+		return []model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	sessions := make([]model.Session, 0)
+	for rows.Next() {
+		session, err := r.scan(rows)
+		if err != nil {
+			return []model.Session{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (r *Session) Get(ctx context.Context, id model.ID) (model.Session, error) {
+	const op = "repository.Session.Get"
+
+	query := `SELECT * FROM sessions WHERE id = ? LIMIT 1`
+	args := []any{id.String()}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	session, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Session{}, fmt.Errorf("%s: %w", op, model.ErrSessionNotFound)
+		}
+
+		return model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+func (r *Session) GetByRefreshToken(ctx context.Context, refreshToken string) (model.Session, error) {
+	const op = "repository.Session.GetByRefreshToken"
+
+	query := `SELECT * FROM sessions WHERE refresh_token = ? LIMIT 1`
+	args := []any{refreshToken}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	session, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Session{}, fmt.Errorf("%s: %w", op, model.ErrSessionNotFound)
+		}
+
+		return model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+func (r *Session) GetByPreviousRefreshToken(ctx context.Context, refreshToken string) (model.Session, error) {
+	const op = "repository.Session.GetByPreviousRefreshToken"
+
+	query := `SELECT * FROM sessions WHERE previous_refresh_token = ? LIMIT 1`
+	args := []any{refreshToken}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	session, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Session{}, fmt.Errorf("%s: %w", op, model.ErrSessionNotFound)
+		}
+
+		return model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return session, nil
+}
+
+func (r *Session) Create(ctx context.Context, dto repository.CreateSessionDTO) (model.ID, error) {
+	const op = "repository.Session.Create"
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+
+	query := `
+		INSERT INTO sessions (id, created_at, updated_at, user_id, refresh_token, user_agent, ip, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	args := []any{
+		id.String(), now.Unix(), now.Unix(),
+		dto.UserID.String(), dto.RefreshToken,
+		dto.UserAgent, dto.IP,
+		dto.ExpiresAt.Unix(),
+	}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (r *Session) Rotate(ctx context.Context, id model.ID, oldRefreshToken string, dto repository.UpdateSessionDTO) error {
+	const op = "repository.Session.Rotate"
+
+	now := time.Now()
+
+	query := `
+		UPDATE sessions SET
+			updated_at = ?, refresh_token = ?, previous_refresh_token = refresh_token,
+			user_agent = ?, ip = ?, expires_at = ?
+		WHERE id = ? AND refresh_token = ?
+		RETURNING id
+	`
+	args := []any{
+		now.Unix(), dto.RefreshToken,
+		dto.UserAgent, dto.IP, dto.ExpiresAt.Unix(),
+		id.String(), oldRefreshToken,
+	}
+
+	var rotatedID string
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&rotatedID); err != nil {
+		if sqlite.IsNoRows(err) {
+			return fmt.Errorf("%s: %w", op, model.ErrSessionNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Session) Delete(ctx context.Context, id model.ID) error {
+	const op = "repository.Session.Delete"
+
+	query := `DELETE FROM sessions WHERE id = ?`
+	args := []any{id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Session) DeleteByUser(ctx context.Context, userID model.ID) error {
+	const op = "repository.Session.DeleteByUser"
+
+	query := `DELETE FROM sessions WHERE user_id = ?`
+	args := []any{userID.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Session) scan(s database.Scanner) (model.Session, error) {
+	var entry sessionEntry
+	if err := s.Scan(
+		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
+		&entry.UserID, &entry.RefreshToken,
+		&entry.UserAgent, &entry.IP,
+		&entry.ExpiresAt,
+		&entry.PreviousRefreshToken,
+	); err != nil {
+		return model.Session{}, err
+	}
+
+	id, err := uuid.Parse(entry.ID)
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	createdAt := time.Unix(entry.CreatedAt, 0)
+	updatedAt := time.Unix(entry.UpdatedAt, 0)
+
+	userID, err := uuid.Parse(entry.UserID)
+	if err != nil {
+		return model.Session{}, err
+	}
+
+	return model.Session{
+		Model: model.Model{
+			ID:        id,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		},
+		UserID:    userID,
+		UserAgent: entry.UserAgent,
+		IP:        entry.IP,
+		ExpiresAt: time.Unix(entry.ExpiresAt, 0),
+	}, nil
+}
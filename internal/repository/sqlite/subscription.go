@@ -80,6 +80,56 @@ func (r *Subscription) CountByToUser(ctx context.Context, toUserID model.ID) (in
 	return count, nil
 }
 
+func (r *Subscription) FindByFromUserSortByCreatedAt(
+	ctx context.Context, fromUserID model.ID, opts repository.FindOptions,
+) ([]model.Subscription, error) {
+	const op = "repository.Subscription.FindByFromUserSortByCreatedAt"
+
+	after, afterArgs := afterCursor("subscriptions.created_at", "subscriptions.id", opts.Cursor)
+
+	query := `
+		SELECT subscriptions.*, users.* FROM subscriptions
+		JOIN users ON subscriptions.to_user_id = users.id
+		WHERE subscriptions.from_user_id = ? AND ` + after + `
+		ORDER BY subscriptions.created_at DESC, subscriptions.id DESC
+		LIMIT ? OFFSET ?
+	`
+	args := append([]any{fromUserID.String()}, afterArgs...)
+	args = append(args, opts.Limit, opts.Offset)
+
+	subs, err := r.findWithUser(ctx, query, args, opts)
+	if err != nil {
+		return []model.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subs, nil
+}
+
+func (r *Subscription) FindByToUserSortByCreatedAt(
+	ctx context.Context, toUserID model.ID, opts repository.FindOptions,
+) ([]model.Subscription, error) {
+	const op = "repository.Subscription.FindByToUserSortByCreatedAt"
+
+	after, afterArgs := afterCursor("subscriptions.created_at", "subscriptions.id", opts.Cursor)
+
+	query := `
+		SELECT subscriptions.*, users.* FROM subscriptions
+		JOIN users ON subscriptions.from_user_id = users.id
+		WHERE subscriptions.to_user_id = ? AND ` + after + `
+		ORDER BY subscriptions.created_at DESC, subscriptions.id DESC
+		LIMIT ? OFFSET ?
+	`
+	args := append([]any{toUserID.String()}, afterArgs...)
+	args = append(args, opts.Limit, opts.Offset)
+
+	subs, err := r.findWithUser(ctx, query, args, opts)
+	if err != nil {
+		return []model.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subs, nil
+}
+
 func (r *Subscription) Create(ctx context.Context, dto repository.CreateSubscriptionDTO) (model.ID, error) {
 	const op = "repository.Subscription.Create"
 
@@ -119,6 +169,30 @@ func (r *Subscription) Delete(ctx context.Context, id model.ID) error {
 	return nil
 }
 
+func (r *Subscription) findWithUser(ctx context.Context, query string, args []any, opts repository.FindOptions) ([]model.Subscription, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return []model.Subscription{}, nil
+		}
+
+		return []model.Subscription{}, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	subs := make([]model.Subscription, 0, opts.Limit)
+	for rows.Next() {
+		sub, err := r.scanWithUser(rows)
+		if err != nil {
+			return []model.Subscription{}, err
+		}
+
+		subs = append(subs, sub)
+	}
+
+	return subs, nil
+}
+
 func (r *Subscription) scan(s database.Scanner) (model.Subscription, error) {
 	var entry subscriptionEntry
 	if err := s.Scan(
@@ -128,6 +202,57 @@ func (r *Subscription) scan(s database.Scanner) (model.Subscription, error) {
 		return model.Subscription{}, err
 	}
 
+	return r.fromEntry(entry)
+}
+
+// scanWithUser reads a subscription followed by the user on its other side.
+func (r *Subscription) scanWithUser(s database.Scanner) (model.Subscription, error) {
+	var (
+		entry subscriptionEntry
+		user  userEntry
+	)
+	if err := s.Scan(
+		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
+		&entry.FromUserID, &entry.ToUserID,
+
+		&user.ID, &user.CreatedAt, &user.UpdatedAt,
+		&user.Nickname, &user.Password,
+		&user.Email, &user.Verified,
+		&user.AvatarPath, &user.Description,
+		&user.Role,
+	); err != nil {
+		return model.Subscription{}, err
+	}
+
+	sub, err := r.fromEntry(entry)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	userID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return model.Subscription{}, err
+	}
+
+	sub.User = &model.User{
+		Model: model.Model{
+			ID:        userID,
+			CreatedAt: time.Unix(user.CreatedAt, 0),
+			UpdatedAt: time.Unix(user.UpdatedAt, 0),
+		},
+		Nickname:    user.Nickname,
+		Password:    user.Password,
+		Email:       user.Email,
+		Verified:    user.Verified,
+		AvatarPath:  user.AvatarPath,
+		Description: user.Description,
+		Role:        model.Role(user.Role),
+	}
+
+	return sub, nil
+}
+
+func (*Subscription) fromEntry(entry subscriptionEntry) (model.Subscription, error) {
 	id, err := uuid.Parse(entry.ID)
 	if err != nil {
 		return model.Subscription{}, err
@@ -0,0 +1,192 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/database"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ repository.Upload = (*Upload)(nil)
+
+type uploadEntry struct {
+	ID        string
+	CreatedAt int64
+	UpdatedAt int64
+	OwnerID   string
+	Parent    string
+	Name      string
+	Type      string
+	Size      int64
+	Received  int64
+	Kind      string
+	PartNames string
+}
+
+type Upload struct {
+	logger logging.Logger
+	db     database.DB
+}
+
+func NewUpload(logger logging.Logger, db database.DB) *Upload {
+	return &Upload{
+		logger: logger.With("repository", "sqlite/upload"),
+		db:     db,
+	}
+}
+
+func (r *Upload) Get(ctx context.Context, id model.ID) (model.Upload, error) {
+	const op = "repository.Upload.Get"
+
+	query := `SELECT * FROM uploads WHERE id = ? LIMIT 1`
+	args := []any{id.String()}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	upload, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.Upload{}, fmt.Errorf("%s: %w", op, model.ErrUploadNotFound)
+		}
+
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return upload, nil
+}
+
+func (r *Upload) Create(ctx context.Context, dto repository.CreateUploadDTO) (model.ID, error) {
+	const op = "repository.Upload.Create"
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+
+	query := `
+		INSERT INTO uploads (id, created_at, updated_at, owner_id, parent, name, content_type, size, kind)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	args := []any{
+		id.String(), now.Unix(), now.Unix(),
+		dto.OwnerID.String(), dto.Parent, dto.Name, dto.Type, dto.Size, string(dto.Kind),
+	}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (r *Upload) UpdateProgress(ctx context.Context, id model.ID, dto repository.UpdateUploadProgressDTO) error {
+	const op = "repository.Upload.UpdateProgress"
+
+	now := time.Now()
+
+	query := `
+		UPDATE uploads SET updated_at = ?, received = ?, part_names = part_names || ? || char(10)
+		WHERE id = ? AND received = ?
+		RETURNING id
+	`
+	args := []any{now.Unix(), dto.Offset, dto.Part, id.String(), dto.From}
+
+	var updatedID string
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&updatedID); err != nil {
+		if sqlite.IsNoRows(err) {
+			return fmt.Errorf("%s: %w", op, model.ErrUploadOffsetMismatch)
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Upload) Delete(ctx context.Context, id model.ID) error {
+	const op = "repository.Upload.Delete"
+
+	query := `DELETE FROM uploads WHERE id = ?`
+	args := []any{id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *Upload) DeleteUpdatedBefore(ctx context.Context, before time.Time) (int, error) {
+	const op = "repository.Upload.DeleteUpdatedBefore"
+
+	query := `DELETE FROM uploads WHERE updated_at < ? RETURNING id`
+	args := []any{before.Unix()}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	deleted := 0
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (r *Upload) scan(s database.Scanner) (model.Upload, error) {
+	var entry uploadEntry
+	if err := s.Scan(
+		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
+		&entry.OwnerID,
+		&entry.Parent, &entry.Name, &entry.Type,
+		&entry.Size, &entry.Received,
+		&entry.Kind,
+		&entry.PartNames,
+	); err != nil {
+		return model.Upload{}, err
+	}
+
+	id, err := uuid.Parse(entry.ID)
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	createdAt := time.Unix(entry.CreatedAt, 0)
+	updatedAt := time.Unix(entry.UpdatedAt, 0)
+
+	ownerID, err := uuid.Parse(entry.OwnerID)
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	return model.Upload{
+		Model: model.Model{
+			ID:        id,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		},
+		OwnerID: ownerID,
+		Kind:    model.MediaKind(entry.Kind),
+		Parent:  entry.Parent,
+		Name:    entry.Name,
+		Type:    entry.Type,
+		Size:    entry.Size,
+		Offset:  entry.Received,
+		Parts:   strings.Fields(entry.PartNames),
+	}, nil
+}
@@ -25,6 +25,7 @@ type userEntry struct {
 	Verified    bool
 	AvatarPath  string
 	Description string
+	Role        string
 }
 
 type User struct {
@@ -39,6 +40,37 @@ func NewUser(logger logging.Logger, db database.DB) *User {
 	}
 }
 
+func (r *User) Find(ctx context.Context, opts repository.FindOptions) ([]model.User, error) {
+	const op = "repository.User.Find"
+
+	after, afterArgs := afterCursor("created_at", "id", opts.Cursor)
+
+	query := `SELECT * FROM users WHERE ` + after + ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	args := append(afterArgs, opts.Limit, opts.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return []model.User{}, nil
+		}
+
+		return []model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	users := make([]model.User, 0, opts.Limit)
+	for rows.Next() {
+		user, err := r.scan(rows)
+		if err != nil {
+			return []model.User{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
 func (r *User) Get(ctx context.Context, id model.ID) (model.User, error) {
 	const op = "repository.User.Get"
 
@@ -154,6 +186,10 @@ func (r *User) Update(ctx context.Context, id model.ID, dto repository.UpdateUse
 		query += `, description = ?`
 		args = append(args, *dto.Description)
 	}
+	if dto.Role != nil {
+		query += `, role = ?`
+		args = append(args, string(*dto.Role))
+	}
 
 	query += ` WHERE id = ?`
 	args = append(args, id.String())
@@ -185,6 +221,7 @@ func (*User) scan(s database.Scanner) (model.User, error) {
 		&entry.Nickname, &entry.Password,
 		&entry.Email, &entry.Verified,
 		&entry.AvatarPath, &entry.Description,
+		&entry.Role,
 	); err != nil {
 		return model.User{}, err
 	}
@@ -209,5 +246,6 @@ func (*User) scan(s database.Scanner) (model.User, error) {
 		Verified:    entry.Verified,
 		AvatarPath:  entry.AvatarPath,
 		Description: entry.Description,
+		Role:        model.Role(entry.Role),
 	}, nil
 }
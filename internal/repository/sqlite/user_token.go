@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/database"
+	"github.com/protomem/gotube/internal/database/sqlite"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+var _ repository.UserToken = (*UserToken)(nil)
+
+type userTokenEntry struct {
+	ID        string
+	CreatedAt int64
+	UpdatedAt int64
+	UserID    string
+	Kind      string
+	Token     string
+	Email     string
+	ExpiresAt int64
+}
+
+type UserToken struct {
+	logger logging.Logger
+	db     database.DB
+}
+
+func NewUserToken(logger logging.Logger, db database.DB) *UserToken {
+	return &UserToken{
+		logger: logger.With("repository", "sqlite/userToken"),
+		db:     db,
+	}
+}
+
+func (r *UserToken) ConsumeByKindAndToken(ctx context.Context, kind model.UserTokenKind, token string) (model.UserToken, error) {
+	const op = "repository.UserToken.ConsumeByKindAndToken"
+
+	query := `DELETE FROM user_tokens WHERE kind = ? AND token = ? RETURNING *`
+	args := []any{string(kind), token}
+
+	row := r.db.QueryRow(ctx, query, args...)
+	userToken, err := r.scan(row)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return model.UserToken{}, fmt.Errorf("%s: %w", op, model.ErrUserTokenNotFound)
+		}
+
+		return model.UserToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return userToken, nil
+}
+
+func (r *UserToken) Create(ctx context.Context, dto repository.CreateUserTokenDTO) (model.ID, error) {
+	const op = "repository.UserToken.Create"
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+	now := time.Now()
+
+	query := `
+		INSERT INTO user_tokens (id, created_at, updated_at, user_id, kind, token, email, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	args := []any{
+		id.String(), now.Unix(), now.Unix(),
+		dto.UserID.String(), string(dto.Kind), dto.Token, dto.Email,
+		dto.ExpiresAt.Unix(),
+	}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return model.ID{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (r *UserToken) Delete(ctx context.Context, id model.ID) error {
+	const op = "repository.UserToken.Delete"
+
+	query := `DELETE FROM user_tokens WHERE id = ?`
+	args := []any{id.String()}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *UserToken) DeleteByUserAndKind(ctx context.Context, userID model.ID, kind model.UserTokenKind) error {
+	const op = "repository.UserToken.DeleteByUserAndKind"
+
+	query := `DELETE FROM user_tokens WHERE user_id = ? AND kind = ?`
+	args := []any{userID.String(), string(kind)}
+
+	if err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *UserToken) scan(s database.Scanner) (model.UserToken, error) {
+	var entry userTokenEntry
+	if err := s.Scan(
+		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
+		&entry.UserID, &entry.Kind, &entry.Token, &entry.Email,
+		&entry.ExpiresAt,
+	); err != nil {
+		return model.UserToken{}, err
+	}
+
+	id, err := uuid.Parse(entry.ID)
+	if err != nil {
+		return model.UserToken{}, err
+	}
+
+	createdAt := time.Unix(entry.CreatedAt, 0)
+	updatedAt := time.Unix(entry.UpdatedAt, 0)
+
+	userID, err := uuid.Parse(entry.UserID)
+	if err != nil {
+		return model.UserToken{}, err
+	}
+
+	return model.UserToken{
+		Model: model.Model{
+			ID:        id,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		},
+		UserID:    userID,
+		Kind:      model.UserTokenKind(entry.Kind),
+		Email:     entry.Email,
+		ExpiresAt: time.Unix(entry.ExpiresAt, 0),
+	}, nil
+}
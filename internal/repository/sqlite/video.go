@@ -2,8 +2,12 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"html"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/protomem/gotube/internal/database"
@@ -27,6 +31,11 @@ type videoEntry struct {
 	Author        userEntry
 	Public        bool
 	Views         int64
+	Status        string
+	Duration      int64
+	StreamPath    string
+	Likes         int64
+	Dislikes      int64
 }
 
 type Video struct {
@@ -44,14 +53,16 @@ func NewVideo(logger logging.Logger, db database.DB) *Video {
 func (r *Video) FindSortByCreatedAtWherePublic(ctx context.Context, opts repository.FindOptions) ([]model.Video, error) {
 	const op = "repository.Video.FindSortByCreatedAtWherePublic"
 
+	after, afterArgs := afterCursor("videos.created_at", "videos.id", opts.Cursor)
+
 	query := `
 		SELECT videos.*, authors.* FROM videos
 		JOIN users AS authors ON videos.author_id = authors.id
-		WHERE videos.is_public > 0
-		ORDER BY videos.created_at DESC
+		WHERE videos.is_public > 0 AND videos.status = 'ready' AND ` + after + `
+		ORDER BY videos.created_at DESC, videos.id DESC
 		LIMIT ? OFFSET ?
 	`
-	args := []any{opts.Limit, opts.Offset}
+	args := append(afterArgs, opts.Limit, opts.Offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
@@ -79,14 +90,16 @@ func (r *Video) FindSortByCreatedAtWherePublic(ctx context.Context, opts reposit
 func (r *Video) FindSortByViewsWherePublic(ctx context.Context, opts repository.FindOptions) ([]model.Video, error) {
 	const op = "repository.Video.FindSortByViewsWherePublic"
 
+	after, afterArgs := afterCursor("videos.views", "videos.id", opts.Cursor)
+
 	query := `
 		SELECT videos.*, authors.* FROM videos
 		JOIN users AS authors ON videos.author_id = authors.id
-		WHERE videos.is_public > 0
-		ORDER BY videos.views DESC
+		WHERE videos.is_public > 0 AND videos.status = 'ready' AND ` + after + `
+		ORDER BY videos.views DESC, videos.id DESC
 		LIMIT ? OFFSET ?
 	`
-	args := []any{opts.Limit, opts.Offset}
+	args := append(afterArgs, opts.Limit, opts.Offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
@@ -114,14 +127,17 @@ func (r *Video) FindSortByViewsWherePublic(ctx context.Context, opts repository.
 func (r *Video) FindByAuthorSortByCreatedAt(ctx context.Context, authorID model.ID, opts repository.FindOptions) ([]model.Video, error) {
 	const op = "repository.Video.FindByAuthorSortByCreatedAt"
 
+	after, afterArgs := afterCursor("videos.created_at", "videos.id", opts.Cursor)
+
 	query := `
 		SELECT videos.*, authors.* FROM videos
 		JOIN users AS authors ON videos.author_id = authors.id
-		WHERE authors.id = ?
-		ORDER BY videos.created_at DESC
+		WHERE videos.author_id = ? AND ` + after + `
+		ORDER BY videos.created_at DESC, videos.id DESC
 		LIMIT ? OFFSET ?
 	`
-	args := []any{authorID.String(), opts.Limit, opts.Offset}
+	args := append([]any{authorID.String()}, afterArgs...)
+	args = append(args, opts.Limit, opts.Offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
@@ -146,16 +162,30 @@ func (r *Video) FindByAuthorSortByCreatedAt(ctx context.Context, authorID model.
 	return videos, nil
 }
 
-func (r *Video) FindLikeByTitleWherePublic(ctx context.Context, likeTitle string, opts repository.FindOptions) ([]model.Video, error) {
-	const op = "repository.Video.FindLikeByTitleWherePublic"
+// Matches are wrapped in control characters, which the text never holds,
+// so the text can be escaped before they are turned into tags.
+const (
+	_matchStart = "\x02"
+	_matchEnd   = "\x03"
+)
+
+func (r *Video) FindBySubscriberSortByCreatedAtWherePublic(
+	ctx context.Context, subscriberID model.ID, opts repository.FindOptions,
+) ([]model.Video, error) {
+	const op = "repository.Video.FindBySubscriberSortByCreatedAtWherePublic"
+
+	after, afterArgs := afterCursor("videos.created_at", "videos.id", opts.Cursor)
 
 	query := `
 		SELECT videos.*, authors.* FROM videos
 		JOIN users AS authors ON videos.author_id = authors.id
-		WHERE videos.is_public > 0 AND lower(videos.title) LIKE '%' || lower($3) || '%'
+		JOIN subscriptions ON subscriptions.to_user_id = videos.author_id
+		WHERE subscriptions.from_user_id = ? AND videos.is_public > 0 AND videos.status = 'ready' AND ` + after + `
+		ORDER BY videos.created_at DESC, videos.id DESC
 		LIMIT ? OFFSET ?
 	`
-	args := []any{likeTitle, opts.Limit, opts.Offset}
+	args := append([]any{subscriberID.String()}, afterArgs...)
+	args = append(args, opts.Limit, opts.Offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
@@ -180,6 +210,99 @@ func (r *Video) FindLikeByTitleWherePublic(ctx context.Context, likeTitle string
 	return videos, nil
 }
 
+// SearchWherePublic sorts the videos by relevance, which a cursor cannot point into, so they are paged by offset only.
+func (r *Video) SearchWherePublic(ctx context.Context, dto repository.SearchVideosDTO, opts repository.FindOptions) ([]model.Video, error) {
+	const op = "repository.Video.SearchWherePublic"
+
+	match := matchExpr(dto.Query)
+
+	var (
+		query string
+		args  []any
+	)
+
+	if match == "" {
+		query = `
+			SELECT videos.*, authors.*, NULL, NULL FROM videos
+			JOIN users AS authors ON videos.author_id = authors.id
+			WHERE videos.is_public > 0 AND videos.status = 'ready'
+		`
+	} else {
+		// Matches in the title weigh the most, then the author and the description.
+		query = `
+			SELECT videos.*, authors.*,
+				highlight(videos_search, 0, char(2), char(3)),
+				snippet(videos_search, 1, char(2), char(3), '…', 32)
+			FROM videos_search
+			JOIN videos ON videos.rowid = videos_search.rowid
+			JOIN users AS authors ON videos.author_id = authors.id
+			WHERE videos_search MATCH ? AND videos.is_public > 0 AND videos.status = 'ready'
+		`
+		args = append(args, match)
+	}
+
+	if dto.AuthorNickname != nil {
+		query += ` AND authors.nickname = ?`
+		args = append(args, *dto.AuthorNickname)
+	}
+	if dto.UploadedAfter != nil {
+		query += ` AND videos.created_at >= ?`
+		args = append(args, dto.UploadedAfter.Unix())
+	}
+	if dto.UploadedBefore != nil {
+		query += ` AND videos.created_at < ?`
+		args = append(args, dto.UploadedBefore.Unix())
+	}
+	if dto.MinDuration != nil {
+		query += ` AND videos.duration >= ?`
+		args = append(args, *dto.MinDuration)
+	}
+	if dto.MaxDuration != nil {
+		query += ` AND videos.duration <= ?`
+		args = append(args, *dto.MaxDuration)
+	}
+
+	if match == "" {
+		query += ` ORDER BY videos.created_at DESC, videos.id DESC`
+	} else {
+		query += ` ORDER BY bm25(videos_search, 10.0, 2.0, 5.0), videos.created_at DESC, videos.id DESC`
+	}
+
+	query += ` LIMIT ? OFFSET ?`
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		if sqlite.IsNoRows(err) {
+			return []model.Video{}, nil
+		}
+
+		return []model.Video{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	videos := make([]model.Video, 0, opts.Limit)
+	for rows.Next() {
+		var title, description sql.NullString
+
+		video, err := r.scan(rows, &title, &description)
+		if err != nil {
+			return []model.Video{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if title.Valid {
+			video.Highlight = &model.VideoHighlight{
+				Title:       markMatches(title.String),
+				Description: markMatches(description.String),
+			}
+		}
+
+		videos = append(videos, video)
+	}
+
+	return videos, nil
+}
+
 func (r *Video) Get(ctx context.Context, id model.ID) (model.Video, error) {
 	const op = "repository.Video.Get"
 
@@ -214,10 +337,13 @@ func (r *Video) Create(ctx context.Context, dto repository.CreateVideoDTO) (mode
 	now := time.Now()
 
 	query := `
-		INSERT INTO videos (id, created_at, updated_at, title, description, thumbnail_path, video_path, author_id, is_public)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO videos (id, created_at, updated_at, title, description, thumbnail_path, video_path, author_id, is_public, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	args := []any{id.String(), now.Unix(), now.Unix(), dto.Title, dto.Description, dto.ThumbnailPath, dto.VideoPath, dto.AuthorID.String(), dto.Public}
+	args := []any{
+		id.String(), now.Unix(), now.Unix(),
+		dto.Title, dto.Description, dto.ThumbnailPath, dto.VideoPath, dto.AuthorID.String(), dto.Public, dto.Status,
+	}
 
 	if err := r.db.Exec(ctx, query, args...); err != nil {
 		if sqlite.IsKeyConflict(err) {
@@ -258,6 +384,18 @@ func (r *Video) Update(ctx context.Context, id model.ID, dto repository.UpdateVi
 		query += `, is_public = ?`
 		args = append(args, *dto.Public)
 	}
+	if dto.StreamPath != nil {
+		query += `, stream_path = ?`
+		args = append(args, *dto.StreamPath)
+	}
+	if dto.Status != nil {
+		query += `, status = ?`
+		args = append(args, *dto.Status)
+	}
+	if dto.Duration != nil {
+		query += `, duration = ?`
+		args = append(args, *dto.Duration)
+	}
 
 	query += ` WHERE id = ?`
 	args = append(args, id.String())
@@ -269,6 +407,39 @@ func (r *Video) Update(ctx context.Context, id model.ID, dto repository.UpdateVi
 	return nil
 }
 
+func (r *Video) IncrementViews(ctx context.Context, counts map[model.ID]int64) error {
+	const op = "repository.Video.IncrementViews"
+	const batchSize = 200
+
+	ids := make([]model.ID, 0, len(counts))
+	for id := range counts {
+		ids = append(ids, id)
+	}
+
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+
+		query := `UPDATE videos SET views = views + CASE id`
+		args := make([]any, 0, len(batch)*3)
+
+		for _, id := range batch {
+			query += ` WHEN ? THEN ?`
+			args = append(args, id.String(), counts[id])
+		}
+
+		query += ` ELSE 0 END WHERE id IN (?` + strings.Repeat(`, ?`, len(batch)-1) + `)`
+		for _, id := range batch {
+			args = append(args, id.String())
+		}
+
+		if err := r.db.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
 func (r *Video) Delete(ctx context.Context, id model.ID) error {
 	const op = "repository.Video.Delete"
 
@@ -282,20 +453,26 @@ func (r *Video) Delete(ctx context.Context, id model.ID) error {
 	return nil
 }
 
-func (r *Video) scan(s database.Scanner) (model.Video, error) {
+// scan reads a video with its author, columns selected after them are read into extra.
+func (r *Video) scan(s database.Scanner, extra ...any) (model.Video, error) {
 	var entry videoEntry
-	if err := s.Scan(
+	dest := []any{
 		&entry.ID, &entry.CreatedAt, &entry.UpdatedAt,
 		&entry.Title, &entry.Description,
 		&entry.ThumbnailPath, &entry.VideoPath,
 		&entry.AuthorID, &entry.Public,
 		&entry.Views,
+		&entry.Status, &entry.Duration,
+		&entry.StreamPath,
+		&entry.Likes, &entry.Dislikes,
 
 		&entry.Author.ID, &entry.Author.CreatedAt, &entry.Author.UpdatedAt,
 		&entry.Author.Nickname, &entry.Author.Password,
 		&entry.Author.Email, &entry.Author.Verified,
 		&entry.Author.AvatarPath, &entry.Author.Description,
-	); err != nil {
+		&entry.Author.Role,
+	}
+	if err := s.Scan(append(dest, extra...)...); err != nil {
 		return model.Video{}, err
 	}
 
@@ -325,8 +502,13 @@ func (r *Video) scan(s database.Scanner) (model.Video, error) {
 		Description:   entry.Description,
 		ThumbnailPath: entry.ThumbnailPath,
 		VideoPath:     entry.VideoPath,
+		StreamPath:    entry.StreamPath,
 		Public:        entry.Public,
 		Views:         entry.Views,
+		Status:        model.VideoStatus(entry.Status),
+		Duration:      entry.Duration,
+		Likes:         entry.Likes,
+		Dislikes:      entry.Dislikes,
 		Author: model.User{
 			Model: model.Model{
 				ID:        authorID,
@@ -339,6 +521,30 @@ func (r *Video) scan(s database.Scanner) (model.Video, error) {
 			Verified:    entry.Author.Verified,
 			AvatarPath:  entry.Author.AvatarPath,
 			Description: entry.Author.Description,
+			Role:        model.Role(entry.Author.Role),
 		},
 	}, nil
 }
+
+// matchExpr turns a search query into an FTS5 expression matching every word as a prefix.
+// Only letters and digits are kept, so the query cannot use the FTS5 syntax.
+func matchExpr(query string) string {
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// markMatches escapes the text and wraps its matches in <mark> tags.
+func markMatches(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, _matchStart, "<mark>")
+	text = strings.ReplaceAll(text, _matchEnd, "</mark>")
+	return text
+}
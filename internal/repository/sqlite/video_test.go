@@ -0,0 +1,109 @@
+//go:build sqlite_fts5
+
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
+)
+
+func TestVideoSearchFollowsChanges(t *testing.T) {
+	ctx := context.Background()
+	repos := sqliterepo.New(sqlitetest.Logger(t), sqlitetest.Open(t))
+
+	author := createUser(t, repos, "author")
+	videoID := createVideo(t, repos, author.ID, "Sunset timelapse")
+	otherID := createVideo(t, repos, author.ID, "Mountain hike")
+
+	expectSearch := func(query string, want ...model.ID) {
+		t.Helper()
+
+		videos, err := repos.Video.SearchWherePublic(ctx, repository.SearchVideosDTO{Query: query}, repository.FindOptions{Limit: 10})
+		if err != nil {
+			t.Fatalf("search %q: %v", query, err)
+		}
+
+		found := make(map[model.ID]bool, len(videos))
+		for _, video := range videos {
+			if video.Highlight == nil {
+				t.Fatalf("search %q found %s without a highlight", query, video.ID)
+			}
+			found[video.ID] = true
+		}
+
+		if len(found) != len(want) {
+			t.Fatalf("search %q found %d videos, want %d", query, len(found), len(want))
+		}
+		for _, id := range want {
+			if !found[id] {
+				t.Fatalf("search %q did not find %s", query, id)
+			}
+		}
+	}
+
+	expectSearch("sunset", videoID)
+	expectSearch("author", videoID, otherID)
+
+	title := "Sunrise timelapse"
+	if err := repos.Video.Update(ctx, videoID, repository.UpdateVideoDTO{Title: &title}); err != nil {
+		t.Fatalf("update video: %v", err)
+	}
+	expectSearch("sunset")
+	expectSearch("sunrise", videoID)
+
+	nickname := "renamed"
+	if err := repos.User.Update(ctx, author.ID, repository.UpdateUserDTO{Nickname: &nickname}); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	expectSearch("author")
+	expectSearch("renamed", videoID, otherID)
+
+	if err := repos.Video.Delete(ctx, videoID); err != nil {
+		t.Fatalf("delete video: %v", err)
+	}
+	expectSearch("timelapse")
+	expectSearch("renamed", otherID)
+}
+
+func createUser(t *testing.T, repos *repository.Repositories, nickname string) model.User {
+	t.Helper()
+
+	ctx := context.Background()
+
+	id, err := repos.User.Create(ctx, repository.CreateUserDTO{
+		Nickname: nickname,
+		Email:    nickname + "@example.com",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("create user %s: %v", nickname, err)
+	}
+
+	user, err := repos.User.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("get user %s: %v", nickname, err)
+	}
+
+	return user
+}
+
+func createVideo(t *testing.T, repos *repository.Repositories, authorID model.ID, title string) model.ID {
+	t.Helper()
+
+	id, err := repos.Video.Create(context.Background(), repository.CreateVideoDTO{
+		Title:    title,
+		AuthorID: authorID,
+		Public:   true,
+		Status:   model.VideoStatusReady,
+	})
+	if err != nil {
+		t.Fatalf("create video: %v", err)
+	}
+
+	return id
+}
@@ -14,6 +14,10 @@ type CreateSubscriptionDTO struct {
 type Subscription interface {
 	GetByFromUserAndToUser(ctx context.Context, fromUserID, toUserID model.ID) (model.Subscription, error)
 	CountByToUser(ctx context.Context, toUserID model.ID) (int64, error)
+	// FindByFromUserSortByCreatedAt lists the subscriptions of a user with the channels they follow.
+	FindByFromUserSortByCreatedAt(ctx context.Context, fromUserID model.ID, opts FindOptions) ([]model.Subscription, error)
+	// FindByToUserSortByCreatedAt lists the subscribers of a channel.
+	FindByToUserSortByCreatedAt(ctx context.Context, toUserID model.ID, opts FindOptions) ([]model.Subscription, error)
 	Create(ctx context.Context, dto CreateSubscriptionDTO) (model.ID, error)
 	Delete(ctx context.Context, id model.ID) error
 }
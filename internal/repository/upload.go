@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/protomem/gotube/internal/model"
+)
+
+type (
+	CreateUploadDTO struct {
+		OwnerID model.ID
+		Kind    model.MediaKind
+		Parent  string
+		Name    string
+		Type    string
+		Size    int64
+	}
+
+	UpdateUploadProgressDTO struct {
+		// From is the offset the chunk was written at, the update fails when the upload has moved past it.
+		From   int64
+		Offset int64
+		// Part names the stored chunk, it is appended to the parts of the upload.
+		Part string
+	}
+)
+
+type Upload interface {
+	Get(ctx context.Context, id model.ID) (model.Upload, error)
+	Create(ctx context.Context, dto CreateUploadDTO) (model.ID, error)
+	UpdateProgress(ctx context.Context, id model.ID, dto UpdateUploadProgressDTO) error
+	Delete(ctx context.Context, id model.ID) error
+	// DeleteUpdatedBefore removes the uploads that got no chunk since the time and returns how many.
+	DeleteUpdatedBefore(ctx context.Context, before time.Time) (int, error)
+}
@@ -20,10 +20,12 @@ type (
 		Verified    *bool
 		AvatarPath  *string
 		Description *string
+		Role        *model.Role
 	}
 )
 
 type User interface {
+	Find(ctx context.Context, opts FindOptions) ([]model.User, error)
 	Get(ctx context.Context, id model.ID) (model.User, error)
 	GetByNickname(ctx context.Context, nickname string) (model.User, error)
 	GetByEmail(ctx context.Context, email string) (model.User, error)
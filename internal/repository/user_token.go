@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/protomem/gotube/internal/model"
+)
+
+type CreateUserTokenDTO struct {
+	UserID    model.ID
+	Kind      model.UserTokenKind
+	Token     string
+	Email     string
+	ExpiresAt time.Time
+}
+
+type UserToken interface {
+	// ConsumeByKindAndToken deletes the token and returns it, so of concurrent uses only one finds it.
+	ConsumeByKindAndToken(ctx context.Context, kind model.UserTokenKind, token string) (model.UserToken, error)
+	Create(ctx context.Context, dto CreateUserTokenDTO) (model.ID, error)
+	Delete(ctx context.Context, id model.ID) error
+	DeleteByUserAndKind(ctx context.Context, userID model.ID, kind model.UserTokenKind) error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/protomem/gotube/internal/model"
 )
@@ -14,6 +15,7 @@ type (
 		VideoPath     string
 		AuthorID      model.ID
 		Public        bool
+		Status        model.VideoStatus
 	}
 
 	UpdateVideoDTO struct {
@@ -22,16 +24,35 @@ type (
 		ThumbnailPath *string
 		VideoPath     *string
 		Public        *bool
+		StreamPath    *string
+		Status        *model.VideoStatus
+		Duration      *int64
+	}
+
+	SearchVideosDTO struct {
+		// Query is matched against the title, description and author nickname, each word as a prefix.
+		// An empty query matches every video.
+		Query          string
+		AuthorNickname *string
+		UploadedAfter  *time.Time
+		UploadedBefore *time.Time
+		MinDuration    *int64
+		MaxDuration    *int64
 	}
 )
 
 type Video interface {
 	FindSortByCreatedAtWherePublic(ctx context.Context, opts FindOptions) ([]model.Video, error)
+	// FindSortByViewsWherePublic pages by the live view counts, so a cursor is best-effort:
+	// a video whose views change between pages may be skipped or seen twice.
 	FindSortByViewsWherePublic(ctx context.Context, opts FindOptions) ([]model.Video, error)
 	FindByAuthorSortByCreatedAt(ctx context.Context, authorID model.ID, opts FindOptions) ([]model.Video, error)
-	FindLikeByTitleWherePublic(ctx context.Context, likeTitle string, opts FindOptions) ([]model.Video, error)
+	// FindBySubscriberSortByCreatedAtWherePublic lists the videos of the channels the user is subscribed to.
+	FindBySubscriberSortByCreatedAtWherePublic(ctx context.Context, subscriberID model.ID, opts FindOptions) ([]model.Video, error)
+	SearchWherePublic(ctx context.Context, dto SearchVideosDTO, opts FindOptions) ([]model.Video, error)
 	Get(ctx context.Context, id model.ID) (model.Video, error)
 	Create(ctx context.Context, dto CreateVideoDTO) (model.ID, error)
 	Update(ctx context.Context, id model.ID, dto UpdateVideoDTO) error
+	IncrementViews(ctx context.Context, counts map[model.ID]int64) error
 	Delete(ctx context.Context, id model.ID) error
 }
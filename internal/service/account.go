@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/protomem/gotube/internal/config"
+	"github.com/protomem/gotube/internal/mailer"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/hashing"
+)
+
+var _ Account = (*AccountImpl)(nil)
+
+type (
+	ResetPasswordDTO struct {
+		Token    string
+		Password string
+	}
+)
+
+type (
+	Account interface {
+		RequestEmailVerification(ctx context.Context, userID model.ID) error
+		VerifyEmail(ctx context.Context, token string) (model.User, error)
+		RequestPasswordReset(ctx context.Context, email string) error
+		ResetPassword(ctx context.Context, dto ResetPasswordDTO) error
+	}
+
+	AccountImpl struct {
+		conf        config.Auth
+		userRepo    repository.User
+		tokenRepo   repository.UserToken
+		sessionRepo repository.Session
+		hasher      hashing.Hasher
+		mailer      mailer.Mailer
+	}
+)
+
+func NewAccount(
+	conf config.Auth,
+	userRepo repository.User, tokenRepo repository.UserToken, sessionRepo repository.Session,
+	hasher hashing.Hasher, mailer mailer.Mailer,
+) *AccountImpl {
+	return &AccountImpl{
+		conf:        conf,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		hasher:      hasher,
+		mailer:      mailer,
+	}
+}
+
+func (s *AccountImpl) RequestEmailVerification(ctx context.Context, userID model.ID) error {
+	const op = "service.Account.RequestEmailVerification"
+
+	user, err := s.userRepo.Get(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.Verified {
+		return nil
+	}
+
+	token, err := s.issueToken(ctx, user, model.UserTokenVerifyEmail, s.conf.VerifyEmailTokenTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hi %s!\n\nFollow the link to confirm your email address:\n%s\n\nThe link expires in %s.",
+			user.Nickname, s.link("/verify-email", token), s.conf.VerifyEmailTokenTTL,
+		),
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *AccountImpl) VerifyEmail(ctx context.Context, token string) (model.User, error) {
+	const op = "service.Account.VerifyEmail"
+
+	userToken, err := s.consumeToken(ctx, model.UserTokenVerifyEmail, token)
+	if err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := s.userRepo.Get(ctx, userToken.UserID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The email was changed after the token had been issued.
+	if user.Email != userToken.Email {
+		return model.User{}, fmt.Errorf("%s: %w", op, model.ErrUserTokenNotFound)
+	}
+
+	verified := true
+	if err := s.userRepo.Update(ctx, user.ID, repository.UpdateUserDTO{Verified: &verified}); err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	newUser, err := s.userRepo.Get(ctx, user.ID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newUser, nil
+}
+
+func (s *AccountImpl) RequestPasswordReset(ctx context.Context, email string) error {
+	const op = "service.Account.RequestPasswordReset"
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		// Do not reveal whether the email is registered.
+		if errors.Is(err, model.ErrUserNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := s.issueToken(ctx, user, model.UserTokenResetPassword, s.conf.ResetPasswordTokenTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s!\n\nFollow the link to set a new password:\n%s\n\nThe link expires in %s. "+
+				"If you did not request a password reset, ignore this email.",
+			user.Nickname, s.link("/reset-password", token), s.conf.ResetPasswordTokenTTL,
+		),
+	}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *AccountImpl) ResetPassword(ctx context.Context, dto ResetPasswordDTO) error {
+	const op = "service.Account.ResetPassword"
+
+	// TODO: add validation
+
+	userToken, err := s.consumeToken(ctx, model.UserTokenResetPassword, dto.Token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := s.userRepo.Get(ctx, userToken.UserID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The email was changed after the token had been issued, the link went to the old address.
+	if user.Email != userToken.Email {
+		return fmt.Errorf("%s: %w", op, model.ErrUserTokenNotFound)
+	}
+
+	hashPass, err := s.hasher.Generate(dto.Password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.userRepo.Update(ctx, user.ID, repository.UpdateUserDTO{Password: &hashPass}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.sessionRepo.DeleteByUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// issueToken replaces any previous token of the same kind and returns the raw token.
+func (s *AccountImpl) issueToken(ctx context.Context, user model.User, kind model.UserTokenKind, ttl time.Duration) (string, error) {
+	if err := s.tokenRepo.DeleteByUserAndKind(ctx, user.ID, kind); err != nil {
+		return "", err
+	}
+
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.tokenRepo.Create(ctx, repository.CreateUserTokenDTO{
+		UserID:    user.ID,
+		Kind:      kind,
+		Token:     hashOpaqueToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeToken deletes the token in the same statement that finds it, so it can be used only once,
+// along with any other token of its kind.
+func (s *AccountImpl) consumeToken(ctx context.Context, kind model.UserTokenKind, token string) (model.UserToken, error) {
+	userToken, err := s.tokenRepo.ConsumeByKindAndToken(ctx, kind, hashOpaqueToken(token))
+	if err != nil {
+		return model.UserToken{}, err
+	}
+
+	if err := s.tokenRepo.DeleteByUserAndKind(ctx, userToken.UserID, kind); err != nil {
+		return model.UserToken{}, err
+	}
+
+	if time.Now().After(userToken.ExpiresAt) {
+		return model.UserToken{}, model.ErrUserTokenNotFound
+	}
+
+	return userToken, nil
+}
+
+func (s *AccountImpl) link(path, token string) string {
+	return s.conf.LinkBaseURL + path + "?token=" + url.QueryEscape(token)
+}
@@ -2,10 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/protomem/gotube/internal/config"
 	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
 	"github.com/protomem/gotube/pkg/jwt"
 )
 
@@ -18,63 +22,240 @@ type (
 		Email    string
 		Password string
 	}
+
+	DeviceDTO struct {
+		UserAgent string
+		IP        string
+	}
+
+	TokenPair struct {
+		AccessToken  string
+		RefreshToken string
+	}
 )
 
 type (
 	Auth interface {
-		Login(ctx context.Context, dto LoginDTO) (token string, user model.User, err error)
-		Verify(ctx context.Context, token string) (model.User, error)
+		Login(ctx context.Context, dto LoginDTO, device DeviceDTO) (tokens TokenPair, user model.User, err error)
+		Refresh(ctx context.Context, refreshToken string, device DeviceDTO) (TokenPair, error)
+		Logout(ctx context.Context, sessionID model.ID) error
+		Verify(ctx context.Context, token string) (model.User, model.Session, error)
+		FindSessions(ctx context.Context, userID model.ID) ([]model.Session, error)
+		RevokeSession(ctx context.Context, userID, sessionID model.ID) error
 	}
 
 	AuthImpl struct {
-		conf     config.Auth
-		userServ User
+		conf        config.Auth
+		sessionRepo repository.Session
+		userServ    User
 	}
 )
 
-func NewAuth(conf config.Auth, userServ User) *AuthImpl {
+func NewAuth(conf config.Auth, sessionRepo repository.Session, userServ User) *AuthImpl {
 	return &AuthImpl{
-		conf:     conf,
-		userServ: userServ,
+		conf:        conf,
+		sessionRepo: sessionRepo,
+		userServ:    userServ,
 	}
 }
 
-func (s *AuthImpl) Login(ctx context.Context, dto LoginDTO) (string, model.User, error) {
+func (s *AuthImpl) Login(ctx context.Context, dto LoginDTO, device DeviceDTO) (TokenPair, model.User, error) {
 	const op = "service.Auth.Login"
 
 	user, err := s.userServ.GetByEmailAndPassword(ctx, dto.Email, dto.Password)
 	if err != nil {
-		return "", model.User{}, fmt.Errorf("%s: %w", op, err)
+		return TokenPair{}, model.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	token, err := jwt.Generate(jwt.GenerateParams{
-		SigningKey: s.conf.Secret,
-		TTL:        s.conf.AccessTokenTTL,
-		Subject:    user.Nickname,
-		Issuer:     _defaultTokenIssuer,
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return TokenPair{}, model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessionID, err := s.sessionRepo.Create(ctx, repository.CreateSessionDTO{
+		UserID:       user.ID,
+		RefreshToken: hashOpaqueToken(refreshToken),
+		UserAgent:    device.UserAgent,
+		IP:           device.IP,
+		ExpiresAt:    time.Now().Add(s.conf.RefreshTokenTTL),
 	})
 	if err != nil {
-		return "", model.User{}, fmt.Errorf("%s: %w", op, err)
+		return TokenPair{}, model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, err := s.generateAccessToken(user, sessionID)
+	if err != nil {
+		return TokenPair{}, model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, user, nil
+}
+
+func (s *AuthImpl) Refresh(ctx context.Context, refreshToken string, device DeviceDTO) (TokenPair, error) {
+	const op = "service.Auth.Refresh"
+
+	hashedRefreshToken := hashOpaqueToken(refreshToken)
+
+	session, err := s.sessionRepo.GetByRefreshToken(ctx, hashedRefreshToken)
+	if errors.Is(err, model.ErrSessionNotFound) {
+		err = s.revokeReused(ctx, hashedRefreshToken)
+	}
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		if err := s.sessionRepo.Delete(ctx, session.ID); err != nil {
+			return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return TokenPair{}, fmt.Errorf("%s: %w", op, model.ErrSessionNotFound)
+	}
+
+	user, err := s.userServ.Get(ctx, session.UserID)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	newRefreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Rotation: the presented refresh token stops being valid right away.
+	// Losing the race to a concurrent refresh with the same token is treated as reuse.
+	if err := s.sessionRepo.Rotate(ctx, session.ID, hashedRefreshToken, repository.UpdateSessionDTO{
+		RefreshToken: hashOpaqueToken(newRefreshToken),
+		UserAgent:    device.UserAgent,
+		IP:           device.IP,
+		ExpiresAt:    time.Now().Add(s.conf.RefreshTokenTTL),
+	}); err != nil {
+		if errors.Is(err, model.ErrSessionNotFound) {
+			err = s.revokeReused(ctx, hashedRefreshToken)
+		}
+
+		return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	accessToken, err := s.generateAccessToken(user, session.ID)
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return TokenPair{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
+}
+
+// revokeReused deletes the session whose refresh token has already been rotated away from the given one,
+// a rotated token presented again means it has leaked, so neither holder keeps the session.
+// It returns ErrSessionNotFound either way.
+func (s *AuthImpl) revokeReused(ctx context.Context, hashedRefreshToken string) error {
+	session, err := s.sessionRepo.GetByPreviousRefreshToken(ctx, hashedRefreshToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.Delete(ctx, session.ID); err != nil {
+		return err
 	}
 
-	return token, user, nil
+	return model.ErrSessionNotFound
 }
 
-func (s *AuthImpl) Verify(ctx context.Context, token string) (model.User, error) {
+func (s *AuthImpl) Logout(ctx context.Context, sessionID model.ID) error {
+	const op = "service.Auth.Logout"
+
+	if err := s.sessionRepo.Delete(ctx, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *AuthImpl) Verify(ctx context.Context, token string) (model.User, model.Session, error) {
 	const op = "service.Auth.Verify"
 
-	subject, err := jwt.Parse(token, jwt.ParseParams{
+	payload, err := jwt.Parse(token, jwt.ParseParams{
 		SigningKey: s.conf.Secret,
 		Issuer:     _defaultTokenIssuer,
 	})
 	if err != nil {
-		return model.User{}, fmt.Errorf("%s: %w", op, err)
+		return model.User{}, model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessionID, err := uuid.Parse(payload.ID)
+	if err != nil {
+		return model.User{}, model.Session{}, fmt.Errorf("%s: %w", op, jwt.ErrInvalidToken)
+	}
+
+	session, err := s.sessionRepo.Get(ctx, sessionID)
+	if err != nil {
+		return model.User{}, model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return model.User{}, model.Session{}, fmt.Errorf("%s: %w", op, model.ErrSessionNotFound)
+	}
+
+	user, err := s.userServ.GetByNickname(ctx, payload.Subject)
+	if err != nil {
+		return model.User{}, model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if session.UserID != user.ID {
+		return model.User{}, model.Session{}, fmt.Errorf("%s: %w", op, jwt.ErrInvalidToken)
+	}
+
+	// Role changes invalidate previously issued tokens.
+	if model.Role(payload.Role) != user.Role {
+		return model.User{}, model.Session{}, fmt.Errorf("%s: %w", op, jwt.ErrInvalidToken)
+	}
+
+	return user, session, nil
+}
+
+func (s *AuthImpl) FindSessions(ctx context.Context, userID model.ID) ([]model.Session, error) {
+	const op = "service.Auth.FindSessions"
+
+	sessions, err := s.sessionRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return []model.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+func (s *AuthImpl) RevokeSession(ctx context.Context, userID, sessionID model.ID) error {
+	const op = "service.Auth.RevokeSession"
+
+	session, err := s.sessionRepo.Get(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Do not reveal sessions of other users.
+	if session.UserID != userID {
+		return fmt.Errorf("%s: %w", op, model.ErrSessionNotFound)
+	}
+
+	if err := s.sessionRepo.Delete(ctx, session.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	user, err := s.userServ.GetByNickname(ctx, subject)
+	return nil
+}
+
+func (s *AuthImpl) generateAccessToken(user model.User, sessionID model.ID) (string, error) {
+	token, err := jwt.Generate(jwt.GenerateParams{
+		SigningKey: s.conf.Secret,
+		TTL:        s.conf.AccessTokenTTL,
+		Subject:    user.Nickname,
+		Issuer:     _defaultTokenIssuer,
+		ID:         sessionID.String(),
+		Role:       string(user.Role),
+	})
 	if err != nil {
-		return model.User{}, fmt.Errorf("%s: %w", op, err)
+		return "", err
 	}
 
-	return user, nil
+	return token, nil
 }
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/protomem/gotube/internal/authz"
 	"github.com/protomem/gotube/internal/model"
 	"github.com/protomem/gotube/internal/repository"
 )
@@ -15,30 +17,59 @@ type (
 		Message  string
 		VideoID  model.ID
 		AuthorID model.ID
+		// ParentID is the comment replied to, a reply to a reply joins the thread of the top level comment.
+		ParentID *model.ID
+	}
+
+	UpdateCommentDTO struct {
+		Message *string
 	}
 )
 
 type (
 	Comment interface {
 		FindByVideo(ctx context.Context, videoID model.ID, opts FindOptions) ([]model.Comment, error)
+		FindTopByVideo(ctx context.Context, videoID model.ID, opts FindOptions) ([]model.Comment, error)
+		// GetPinned returns the pinned comment of a video, which the listings leave out.
+		GetPinned(ctx context.Context, videoID model.ID) (model.Comment, error)
+		FindReplies(ctx context.Context, commentID model.ID, opts FindOptions) ([]model.Comment, error)
 		Create(ctx context.Context, dto CreateCommentDTO) (model.Comment, error)
+		Update(ctx context.Context, id model.ID, dto UpdateCommentDTO) (model.Comment, error)
+		FindEdits(ctx context.Context, id model.ID) ([]model.CommentEdit, error)
+		// Pin and Heart are left to the author of the video.
+		Pin(ctx context.Context, id model.ID, pinned bool) error
+		Heart(ctx context.Context, id model.ID, hearted bool) error
+		// Delete keeps a comment with replies as deleted, so the thread stays readable.
 		Delete(ctx context.Context, id model.ID) error
 	}
 
 	CommentImpl struct {
-		repo repository.Comment
+		transactor repository.Transactor
+		repo       repository.Comment
+		videoRepo  repository.Video
+		authz      *authz.Authorizer
 	}
 )
 
-func NewComment(repo repository.Comment) *CommentImpl {
+func NewComment(
+	transactor repository.Transactor, repo repository.Comment, videoRepo repository.Video,
+	authorizer *authz.Authorizer,
+) *CommentImpl {
 	return &CommentImpl{
-		repo: repo,
+		transactor: transactor,
+		repo:       repo,
+		videoRepo:  videoRepo,
+		authz:      authorizer,
 	}
 }
 
 func (s *CommentImpl) FindByVideo(ctx context.Context, videoID model.ID, opts FindOptions) ([]model.Comment, error) {
 	const op = "service.Comment.FindByVideo"
 
+	if _, err := s.videoRepo.Get(ctx, videoID); err != nil {
+		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	comments, err := s.repo.FindByVideo(ctx, videoID, repository.FindOptions(opts))
 	if err != nil {
 		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
@@ -47,11 +78,75 @@ func (s *CommentImpl) FindByVideo(ctx context.Context, videoID model.ID, opts Fi
 	return comments, nil
 }
 
+func (s *CommentImpl) FindTopByVideo(ctx context.Context, videoID model.ID, opts FindOptions) ([]model.Comment, error) {
+	const op = "service.Comment.FindTopByVideo"
+
+	if _, err := s.videoRepo.Get(ctx, videoID); err != nil {
+		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comments, err := s.repo.FindByVideoSortByRating(ctx, videoID, repository.FindOptions(opts))
+	if err != nil {
+		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comments, nil
+}
+
+func (s *CommentImpl) GetPinned(ctx context.Context, videoID model.ID) (model.Comment, error) {
+	const op = "service.Comment.GetPinned"
+
+	comment, err := s.repo.GetPinnedByVideo(ctx, videoID)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comment, nil
+}
+
+func (s *CommentImpl) FindReplies(ctx context.Context, commentID model.ID, opts FindOptions) ([]model.Comment, error) {
+	const op = "service.Comment.FindReplies"
+
+	if _, err := s.repo.Get(ctx, commentID); err != nil {
+		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	replies, err := s.repo.FindByParent(ctx, commentID, repository.FindOptions(opts))
+	if err != nil {
+		return []model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return replies, nil
+}
+
 func (s *CommentImpl) Create(ctx context.Context, dto CreateCommentDTO) (model.Comment, error) {
 	const op = "service.Comment.Create"
 
 	// TODO: Add validation
 
+	if _, err := s.videoRepo.Get(ctx, dto.VideoID); err != nil {
+		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if dto.ParentID != nil {
+		parent, err := s.repo.Get(ctx, *dto.ParentID)
+		if err != nil {
+			return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if parent.Deleted {
+			return model.Comment{}, fmt.Errorf("%s: %w", op, model.ErrCommentNotFound)
+		}
+
+		if parent.VideoID != dto.VideoID {
+			return model.Comment{}, fmt.Errorf("%s: %w", op, model.ErrParentCommentMismatch)
+		}
+
+		if parent.ParentID != nil {
+			dto.ParentID = parent.ParentID
+		}
+	}
+
 	id, err := s.repo.Create(ctx, repository.CreateCommentDTO(dto))
 	if err != nil {
 		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
@@ -65,12 +160,170 @@ func (s *CommentImpl) Create(ctx context.Context, dto CreateCommentDTO) (model.C
 	return comment, nil
 }
 
+func (s *CommentImpl) Update(ctx context.Context, id model.ID, dto UpdateCommentDTO) (model.Comment, error) {
+	const op = "service.Comment.Update"
+
+	comment, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if comment.Deleted {
+		return model.Comment{}, fmt.Errorf("%s: %w", op, model.ErrCommentNotFound)
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionUpdate, authz.CommentObject(comment)); err != nil {
+		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if dto.Message == nil || *dto.Message == comment.Message {
+		return comment, nil
+	}
+
+	if err := s.repo.Update(ctx, id, repository.UpdateCommentDTO(dto)); err != nil {
+		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	comment, err = s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comment, nil
+}
+
+func (s *CommentImpl) FindEdits(ctx context.Context, id model.ID) ([]model.CommentEdit, error) {
+	const op = "service.Comment.FindEdits"
+
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return []model.CommentEdit{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	edits, err := s.repo.FindEdits(ctx, id)
+	if err != nil {
+		return []model.CommentEdit{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return edits, nil
+}
+
+func (s *CommentImpl) Pin(ctx context.Context, id model.ID, pinned bool) error {
+	const op = "service.Comment.Pin"
+
+	comment, err := s.curated(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !pinned {
+		if err := s.repo.Unpin(ctx, id); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+
+	if comment.ParentID != nil {
+		return fmt.Errorf("%s: %w", op, model.ErrPinReply)
+	}
+
+	if err := s.repo.Pin(ctx, comment.VideoID, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *CommentImpl) Heart(ctx context.Context, id model.ID, hearted bool) error {
+	const op = "service.Comment.Heart"
+
+	if _, err := s.curated(ctx, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.repo.SetHearted(ctx, id, hearted); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// curated returns a comment the requester may pin or heart as the author of its video.
+func (s *CommentImpl) curated(ctx context.Context, id model.ID) (model.Comment, error) {
+	comment, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	if comment.Deleted {
+		return model.Comment{}, model.ErrCommentNotFound
+	}
+
+	video, err := s.videoRepo.Get(ctx, comment.VideoID)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionCurate, authz.VideoObject(video)); err != nil {
+		return model.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+// Delete reads the replies and removes the comment together with its emptied parent in a transaction,
+// so a reply posted meanwhile is not orphaned and a failure does not leave the parent behind.
 func (s *CommentImpl) Delete(ctx context.Context, id model.ID) error {
 	const op = "service.Comment.Delete"
 
-	if err := s.repo.Delete(ctx, id); err != nil {
+	if err := s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		comment, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if comment.Deleted {
+			return model.ErrCommentNotFound
+		}
+
+		if err := s.authz.Authorize(ctx, authz.ActionDelete, authz.CommentObject(comment)); err != nil {
+			return err
+		}
+
+		if comment.Replies > 0 {
+			return s.repo.MarkDeleted(ctx, id)
+		}
+
+		if err := s.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+
+		if comment.ParentID != nil {
+			return s.purge(ctx, *comment.ParentID)
+		}
+
+		return nil
+	}); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+// purge removes a deleted comment once its last reply is gone.
+func (s *CommentImpl) purge(ctx context.Context, id model.ID) error {
+	comment, err := s.repo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, model.ErrCommentNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if !comment.Deleted || comment.Replies > 0 {
+		return nil
+	}
+
+	return s.repo.Delete(ctx, id)
+}
@@ -0,0 +1,542 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/authz"
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/internal/config"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	"github.com/protomem/gotube/pkg/filetype"
+	"github.com/protomem/gotube/pkg/imaging"
+)
+
+var _ Media = (*MediaImpl)(nil)
+
+type (
+	SaveMediaDTO struct {
+		// Parent is the folder of the owner, named by the owner ID.
+		Parent string
+		Kind   model.MediaKind
+		Type   string
+		Size   int64
+		Body   io.Reader
+	}
+
+	StoreMediaDTO struct {
+		OwnerID model.ID
+		Kind    model.MediaKind
+		// Type is the content type claimed by the client, the stored type is detected from the content.
+		Type string
+		Size int64
+		Body io.Reader
+	}
+)
+
+type (
+	Media interface {
+		Get(ctx context.Context, id model.ID) (model.Media, error)
+		// GetOwned returns the media if it belongs to the owner and is of the kind,
+		// it guards every place a user links media to.
+		GetOwned(ctx context.Context, id, ownerID model.ID, kind model.MediaKind) (model.Media, error)
+		// Save stores a file uploaded to the parent folder by its owner.
+		Save(ctx context.Context, dto SaveMediaDTO) (model.Media, error)
+		// Store keeps a file for the owner without checking access, for files produced by the app itself.
+		Store(ctx context.Context, dto StoreMediaDTO) (model.Media, error)
+		Delete(ctx context.Context, parent, name string) error
+		// Variant returns the name of the smallest copy of an image at least size pixels big,
+		// the name itself if there is none.
+		Variant(ctx context.Context, parent, name string, size int) (string, error)
+
+		// Referenced reports whether a stored object is still in use, the garbage collector removes the others.
+		Referenced(ctx context.Context, parent, name string) (bool, error)
+		// Forget drops the record of an object the garbage collector has removed.
+		Forget(ctx context.Context, parent, name string) error
+		// Expire removes the uploads abandoned for longer than their ttl, so the garbage collector takes their parts.
+		Expire(ctx context.Context) (int, error)
+	}
+
+	MediaImpl struct {
+		conf       config.Media
+		uploadConf config.Upload
+		repo       repository.Media
+		videoRepo  repository.Video
+		uploadRepo repository.Upload
+		bstore     blobstore.Storage
+		authz      *authz.Authorizer
+	}
+)
+
+func NewMedia(
+	conf config.Media, uploadConf config.Upload,
+	repo repository.Media, videoRepo repository.Video, uploadRepo repository.Upload,
+	bstore blobstore.Storage, authorizer *authz.Authorizer,
+) *MediaImpl {
+	return &MediaImpl{
+		conf:       conf,
+		uploadConf: uploadConf,
+		repo:       repo,
+		videoRepo:  videoRepo,
+		uploadRepo: uploadRepo,
+		bstore:     bstore,
+		authz:      authorizer,
+	}
+}
+
+func (s *MediaImpl) Get(ctx context.Context, id model.ID) (model.Media, error) {
+	const op = "service.Media.Get"
+
+	media, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return media, nil
+}
+
+func (s *MediaImpl) GetOwned(ctx context.Context, id, ownerID model.ID, kind model.MediaKind) (model.Media, error) {
+	const op = "service.Media.GetOwned"
+
+	media, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Media of other users is reported as missing, so its IDs can not be probed.
+	if media.OwnerID != ownerID {
+		return model.Media{}, fmt.Errorf("%s: %w", op, model.ErrMediaNotFound)
+	}
+
+	if media.Kind != kind {
+		return model.Media{}, fmt.Errorf("%s: %w", op, model.ErrMediaKindMismatch)
+	}
+
+	return media, nil
+}
+
+func (s *MediaImpl) Save(ctx context.Context, dto SaveMediaDTO) (model.Media, error) {
+	const op = "service.Media.Save"
+
+	if !dto.Kind.Valid() {
+		return model.Media{}, fmt.Errorf("%s: %w", op, model.ErrInvalidMediaKind)
+	}
+
+	ownerID, err := parseMediaParent(dto.Parent)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionUpdate, authz.MediaObject(ownerID)); err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	media, err := s.Store(ctx, StoreMediaDTO{
+		OwnerID: ownerID,
+		Kind:    dto.Kind,
+		Type:    dto.Type,
+		Size:    dto.Size,
+		Body:    dto.Body,
+	})
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return media, nil
+}
+
+func (s *MediaImpl) Store(ctx context.Context, dto StoreMediaDTO) (model.Media, error) {
+	const op = "service.Media.Store"
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	body := bufio.NewReaderSize(dto.Body, filetype.HeadSize)
+
+	typ, err := s.inspect(dto.Kind, dto.Type, body)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Every file gets a fresh name, so stored files are never overwritten.
+	parent, name := dto.OwnerID.String(), id.String()+typ.Ext
+
+	var (
+		content  io.Reader = body
+		size               = dto.Size
+		variants []imageVariant
+	)
+
+	// Images are kept without their metadata, along with smaller copies.
+	if typ.IsImage() {
+		data, vs, err := s.processImage(id, typ, body)
+		if err != nil {
+			return model.Media{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		content, size, variants = bytes.NewReader(data), int64(len(data)), vs
+	}
+
+	hash := sha256.New()
+	if err := s.bstore.Put(ctx, parent, name, blobstore.Object{
+		Type: typ.MIME,
+		Size: size,
+		Body: io.TeeReader(content, hash),
+	}); err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stored := []string{name}
+	cleanup := func() {
+		for _, name := range stored {
+			_ = s.bstore.Del(ctx, parent, name)
+		}
+	}
+
+	variantDTOs := make([]repository.CreateMediaVariantDTO, 0, len(variants))
+	for _, variant := range variants {
+		if err := s.bstore.Put(ctx, parent, variant.Name, blobstore.Object{
+			Type: variant.ContentType,
+			Size: variant.Bytes,
+			Body: bytes.NewReader(variant.data),
+		}); err != nil {
+			cleanup()
+			return model.Media{}, fmt.Errorf("%s: %w", op, err)
+		}
+		stored = append(stored, variant.Name)
+
+		variantDTOs = append(variantDTOs, repository.CreateMediaVariantDTO{
+			Size:        variant.Size,
+			Name:        variant.Name,
+			Width:       variant.Width,
+			Height:      variant.Height,
+			ContentType: variant.ContentType,
+			Bytes:       variant.Bytes,
+		})
+	}
+
+	if err := s.repo.Create(ctx, repository.CreateMediaDTO{
+		ID:          id,
+		OwnerID:     dto.OwnerID,
+		Kind:        dto.Kind,
+		Parent:      parent,
+		Name:        name,
+		Size:        size,
+		ContentType: typ.MIME,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		Variants:    variantDTOs,
+	}); err != nil {
+		cleanup()
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	media, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return media, nil
+}
+
+func (s *MediaImpl) Delete(ctx context.Context, parent, name string) error {
+	const op = "service.Media.Delete"
+
+	if err := s.authz.Authorize(ctx, authz.ActionDelete, authz.MediaParentObject(parent)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Files stored before media were recorded have no entry.
+	media, err := s.repo.GetByPath(ctx, parent, name)
+	recorded := err == nil
+	if err != nil && !errors.Is(err, model.ErrMediaNotFound) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.bstore.Del(ctx, parent, name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !recorded {
+		// The file may be a variant, which is not served once deleted.
+		if err := s.repo.DeleteByPath(ctx, parent, name); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	}
+
+	for _, variant := range media.Variants {
+		if err := s.bstore.Del(ctx, parent, variant.Name); err != nil && !errors.Is(err, blobstore.ErrObjectNotFound) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := s.repo.Delete(ctx, media.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *MediaImpl) Variant(ctx context.Context, parent, name string, size int) (string, error) {
+	const op = "service.Media.Variant"
+
+	media, err := s.repo.GetByPath(ctx, parent, name)
+	if err != nil {
+		// Files stored before media were recorded have no variants.
+		if errors.Is(err, model.ErrMediaNotFound) {
+			return name, nil
+		}
+
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, variant := range media.Variants {
+		if variant.Size >= size {
+			return variant.Name, nil
+		}
+	}
+
+	return name, nil
+}
+
+func (s *MediaImpl) Referenced(ctx context.Context, parent, name string) (bool, error) {
+	const op = "service.Media.Referenced"
+
+	var err error
+	switch parent {
+	case _hlsParent:
+		// HLS files are named after their video and live as long as it does.
+		_, err = s.videoRepo.Get(ctx, objectOwnerID(name))
+	case _uploadPartsParent:
+		// Parts are named after their upload and are removed when it completes or is abandoned.
+		// A part the upload did not record lost a race with another chunk.
+		var upload model.Upload
+		upload, err = s.uploadRepo.Get(ctx, objectOwnerID(name))
+		if err == nil && (uploadExpired(upload, s.uploadConf.TTL) || !slices.Contains(upload.Parts, name)) {
+			return false, nil
+		}
+	default:
+		// Variants live as long as the image they were made of.
+		media, err := s.repo.GetByVariant(ctx, parent, name)
+		if err == nil {
+			name = media.Name
+		} else if !errors.Is(err, model.ErrMediaNotFound) {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		linked, err := s.repo.IsLinked(ctx, mediaPath(parent, name))
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return linked, nil
+	}
+
+	if err != nil {
+		if errors.Is(err, model.ErrVideoNotFound) || errors.Is(err, model.ErrUploadNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+func (s *MediaImpl) Forget(ctx context.Context, parent, name string) error {
+	const op = "service.Media.Forget"
+
+	if err := s.repo.DeleteByPath(ctx, parent, name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *MediaImpl) Expire(ctx context.Context) (int, error) {
+	const op = "service.Media.Expire"
+
+	if s.uploadConf.TTL <= 0 {
+		return 0, nil
+	}
+
+	expired, err := s.uploadRepo.DeleteUpdatedBefore(ctx, time.Now().Add(-s.uploadConf.TTL))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expired, nil
+}
+
+// inspect detects the type of the content from its head, which it peeks without consuming,
+// and checks it against the kind, the type claimed by the client and the limits.
+func (s *MediaImpl) inspect(kind model.MediaKind, claimed string, body *bufio.Reader) (filetype.Type, error) {
+	head, err := body.Peek(filetype.HeadSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return filetype.Type{}, err
+	}
+
+	typ, ok := filetype.Detect(head)
+	if !ok || !kind.Allows(typ.MIME) {
+		return filetype.Type{}, model.ErrMediaTypeNotAllowed
+	}
+
+	// Clients label files by their extension, so only a claim of another sort of content is refused.
+	if major := claimedMajorType(claimed); major != "" && !strings.HasPrefix(typ.MIME, major+"/") {
+		return filetype.Type{}, model.ErrMediaTypeMismatch
+	}
+
+	if typ.IsImage() && s.conf.MaxImageDimension > 0 {
+		width, height, err := filetype.Dimensions(typ, head)
+		if err != nil {
+			return filetype.Type{}, model.ErrMediaTypeMismatch
+		}
+
+		if width > s.conf.MaxImageDimension || height > s.conf.MaxImageDimension {
+			return filetype.Type{}, model.ErrMediaTooLarge
+		}
+	}
+
+	return typ, nil
+}
+
+// _maxImageSize bounds the images decoded in memory.
+const _maxImageSize = 64 * 1024 * 1024
+
+type imageVariant struct {
+	model.MediaVariant
+	data []byte
+}
+
+// processImage strips the metadata of an image and makes its variants, named after the media ID and their size.
+// Images which can not be decoded in pure Go are only stripped.
+func (s *MediaImpl) processImage(id model.ID, typ filetype.Type, body io.Reader) ([]byte, []imageVariant, error) {
+	data, err := io.ReadAll(io.LimitReader(body, _maxImageSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(data) > _maxImageSize {
+		return nil, nil, model.ErrMediaTooLarge
+	}
+
+	stripped, err := imaging.Strip(typ.MIME, data)
+	if err != nil {
+		return nil, nil, model.ErrMediaTypeMismatch
+	}
+
+	if !imaging.Decodable(typ.MIME) {
+		return stripped, nil, nil
+	}
+
+	img, err := imaging.Decode(typ.MIME, data)
+	if err != nil {
+		return nil, nil, model.ErrMediaTypeMismatch
+	}
+
+	// Stripping loses the orientation, so turned images are stored upright.
+	if typ.MIME == imaging.FormatJPEG && imaging.Orientation(data) != 1 {
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, typ.MIME, img, s.conf.ImageQuality); err != nil {
+			return nil, nil, err
+		}
+		stripped = buf.Bytes()
+	}
+
+	variantType := filetype.PNG
+	if imaging.EncodedFormat(typ.MIME) == imaging.FormatJPEG {
+		variantType = filetype.JPEG
+	}
+
+	sizes := slices.Clone(s.conf.ImageSizes)
+	slices.SortFunc(sizes, func(a, b int) int { return b - a })
+
+	// Each variant is scaled from the previous, larger one.
+	variants := make([]imageVariant, 0, len(sizes))
+	for _, size := range sizes {
+		bounds := img.Bounds()
+		if size <= 0 || (bounds.Dx() <= size && bounds.Dy() <= size) {
+			continue
+		}
+
+		img = imaging.Fit(img, size)
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, variantType.MIME, img, s.conf.ImageQuality); err != nil {
+			return nil, nil, err
+		}
+
+		variants = append(variants, imageVariant{
+			MediaVariant: model.MediaVariant{
+				Size:        size,
+				Name:        fmt.Sprintf("%s.%d%s", id, size, variantType.Ext),
+				Width:       img.Bounds().Dx(),
+				Height:      img.Bounds().Dy(),
+				ContentType: variantType.MIME,
+				Bytes:       int64(buf.Len()),
+			},
+			data: buf.Bytes(),
+		})
+	}
+
+	return stripped, variants, nil
+}
+
+// claimedMajorType returns the major type of a content type sent by a client, empty if it tells nothing.
+func claimedMajorType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "application/octet-stream" {
+		return ""
+	}
+
+	major, _, _ := strings.Cut(mediaType, "/")
+	return major
+}
+
+// objectOwnerID returns the ID an internal object is named after, uuid.Nil if the name has none.
+func objectOwnerID(name string) model.ID {
+	prefix, _, _ := strings.Cut(name, ".")
+	id, err := uuid.Parse(prefix)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+// linkMedia returns the path to store for a media the owner links as kind, uuid.Nil unlinks it.
+func linkMedia(ctx context.Context, media Media, id, ownerID model.ID, kind model.MediaKind) (string, error) {
+	if id == uuid.Nil {
+		return "", nil
+	}
+
+	m, err := media.GetOwned(ctx, id, ownerID, kind)
+	if err != nil {
+		return "", err
+	}
+
+	return m.Path(), nil
+}
+
+// parseMediaParent returns the owner of a media folder.
+func parseMediaParent(parent string) (model.ID, error) {
+	ownerID, err := uuid.Parse(parent)
+	if err != nil {
+		return model.ID{}, model.ErrInvalidMediaName
+	}
+	return ownerID, nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/internal/config"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/processing"
+	"github.com/protomem/gotube/internal/repository"
+)
+
+const JobProcessVideo = "video.process"
+
+// _hlsParent is the blobstore folder holding HLS files, served only through the video stream.
+const _hlsParent = "_hls"
+
+var _ Processing = (*ProcessingImpl)(nil)
+
+type (
+	ProcessVideoPayload struct {
+		VideoID model.ID `json:"videoId"`
+	}
+)
+
+type (
+	Processing interface {
+		// Start queues processing of a video stored in media.
+		Start(ctx context.Context, video model.Video) error
+		RunVideoJob(ctx context.Context, payload []byte) error
+		FailVideoJob(ctx context.Context, payload []byte, cause error) error
+	}
+
+	JobQueue interface {
+		Enqueue(ctx context.Context, kind string, payload any) error
+	}
+
+	ProcessingImpl struct {
+		conf      config.Processing
+		videoRepo repository.Video
+		bstore    blobstore.Storage
+		media     Media
+		pipeline  *processing.Pipeline
+		jobs      JobQueue
+	}
+)
+
+func NewProcessing(
+	conf config.Processing,
+	videoRepo repository.Video, bstore blobstore.Storage, media Media,
+	pipeline *processing.Pipeline, jobs JobQueue,
+) *ProcessingImpl {
+	return &ProcessingImpl{
+		conf:      conf,
+		videoRepo: videoRepo,
+		bstore:    bstore,
+		media:     media,
+		pipeline:  pipeline,
+		jobs:      jobs,
+	}
+}
+
+func (s *ProcessingImpl) Start(ctx context.Context, video model.Video) error {
+	const op = "service.Processing.Start"
+
+	if _, _, ok := parseMediaPath(video.VideoPath); !ok || video.Status != model.VideoStatusUploading {
+		return nil
+	}
+
+	if err := s.enqueue(ctx, video.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *ProcessingImpl) RunVideoJob(ctx context.Context, payload []byte) error {
+	const op = "service.Processing.RunVideoJob"
+
+	var job ProcessVideoPayload
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	video, err := s.videoRepo.Get(ctx, job.VideoID)
+	if err != nil {
+		// The video was deleted while waiting in the queue.
+		if errors.Is(err, model.ErrVideoNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	parent, name, ok := parseMediaPath(video.VideoPath)
+	if !ok {
+		return fmt.Errorf("%s: video path %q is not a media file", op, video.VideoPath)
+	}
+
+	workDir, err := os.MkdirTemp(s.conf.WorkDir, "gotube-"+video.ID.String()+"-")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	input := filepath.Join(workDir, "source"+filepath.Ext(name))
+	if err := s.download(ctx, parent, name, input); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	task := processing.NewTask(workDir, input)
+	if err := s.pipeline.Run(ctx, task); err != nil {
+		// Oversized videos fail for good, the job is not retried.
+		if errors.Is(err, processing.ErrLimitExceeded) {
+			if err := s.fail(ctx, video.ID); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	dto := repository.UpdateVideoDTO{}
+
+	if output, ok := task.Outputs[processing.OutputVideo]; ok {
+		media, err := s.store(ctx, video.Author.ID, model.MediaKindVideo, output)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		videoPath := media.Path()
+		dto.VideoPath = &videoPath
+	}
+
+	if err := s.uploadHLS(ctx, video.ID, task.Outputs); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, ok := task.Outputs[processing.OutputHLS+processing.HLSMasterPlaylist]; ok {
+		streamPath := "/videos/" + video.ID.String() + "/stream/" + processing.HLSMasterPlaylist
+		dto.StreamPath = &streamPath
+	}
+
+	// A thumbnail chosen by the author is kept.
+	if output, ok := task.Outputs[processing.OutputThumbnail]; ok && video.ThumbnailPath == "" {
+		media, err := s.store(ctx, video.Author.ID, model.MediaKindThumbnail, output)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		thumbnailPath := media.Path()
+		dto.ThumbnailPath = &thumbnailPath
+	}
+
+	status := model.VideoStatusReady
+	duration := int64(task.Metadata.Duration.Seconds())
+	dto.Status = &status
+	dto.Duration = &duration
+
+	if err := s.videoRepo.Update(ctx, video.ID, dto); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *ProcessingImpl) FailVideoJob(ctx context.Context, payload []byte, _ error) error {
+	const op = "service.Processing.FailVideoJob"
+
+	var job ProcessVideoPayload
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.fail(ctx, job.VideoID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *ProcessingImpl) fail(ctx context.Context, videoID model.ID) error {
+	status := model.VideoStatusFailed
+	return s.videoRepo.Update(ctx, videoID, repository.UpdateVideoDTO{Status: &status})
+}
+
+func (s *ProcessingImpl) enqueue(ctx context.Context, videoID model.ID) error {
+	status := model.VideoStatusProcessing
+	if err := s.videoRepo.Update(ctx, videoID, repository.UpdateVideoDTO{Status: &status}); err != nil {
+		return err
+	}
+
+	return s.jobs.Enqueue(ctx, JobProcessVideo, ProcessVideoPayload{VideoID: videoID})
+}
+
+func (s *ProcessingImpl) download(ctx context.Context, parent, name, path string) error {
+	obj, err := s.bstore.Open(ctx, parent, name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = obj.Body.Close() }()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := io.Copy(file, obj.Body); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (s *ProcessingImpl) upload(ctx context.Context, parent, name string, output processing.Output) error {
+	file, err := os.Open(output.Path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return s.bstore.Put(ctx, parent, name, blobstore.Object{
+		Type: output.Type,
+		Size: stat.Size(),
+		Body: file,
+	})
+}
+
+// store keeps an output as media of the video author.
+func (s *ProcessingImpl) store(
+	ctx context.Context,
+	ownerID model.ID, kind model.MediaKind, output processing.Output,
+) (model.Media, error) {
+	file, err := os.Open(output.Path)
+	if err != nil {
+		return model.Media{}, err
+	}
+	defer func() { _ = file.Close() }()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return model.Media{}, err
+	}
+
+	return s.media.Store(ctx, StoreMediaDTO{
+		OwnerID: ownerID,
+		Kind:    kind,
+		Type:    output.Type,
+		Size:    stat.Size(),
+		Body:    file,
+	})
+}
+
+func (s *ProcessingImpl) uploadHLS(ctx context.Context, videoID model.ID, outputs map[string]processing.Output) error {
+	for key, output := range outputs {
+		rel, ok := strings.CutPrefix(key, processing.OutputHLS)
+		if !ok {
+			continue
+		}
+
+		if err := s.upload(ctx, _hlsParent, hlsObjectName(videoID, rel), output); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hlsObjectName flattens the path of a HLS file relative to the master playlist into a blobstore name.
+func hlsObjectName(videoID model.ID, rel string) string {
+	return videoID.String() + "." + strings.ReplaceAll(rel, "/", ".")
+}
+
+const _mediaPathPrefix = "/media/"
+
+func mediaPath(parent, name string) string {
+	return _mediaPathPrefix + parent + "/" + name
+}
+
+// parseMediaPath splits a path served by the media handler into the blobstore parent and name.
+func parseMediaPath(path string) (parent, name string, ok bool) {
+	rest, ok := strings.CutPrefix(path, _mediaPathPrefix)
+	if !ok {
+		return "", "", false
+	}
+
+	parent, name, ok = strings.Cut(rest, "/")
+	if !ok || !ValidMediaName(parent) || !ValidMediaName(name) {
+		return "", "", false
+	}
+
+	return parent, name, true
+}
@@ -16,63 +16,87 @@ type (
 		UserID  model.ID
 		VideoID model.ID
 	}
+
+	CommentRatingDTO struct {
+		UserID    model.ID
+		CommentID model.ID
+	}
 )
 
 type (
 	Rating interface {
 		Count(ctx context.Context, videoID model.ID) (likes int64, unlikes int64, err error)
+		FindByUserAndVideos(ctx context.Context, userID model.ID, videoIDs []model.ID) ([]model.Rating, error)
+		Get(ctx context.Context, dto RatingDTO) (model.Rating, error)
 		Like(ctx context.Context, dto RatingDTO) error
 		Dislike(ctx context.Context, dto RatingDTO) error
 		Delete(ctx context.Context, dto RatingDTO) error
+
+		LikeComment(ctx context.Context, dto CommentRatingDTO) error
+		DislikeComment(ctx context.Context, dto CommentRatingDTO) error
+		DeleteCommentRating(ctx context.Context, dto CommentRatingDTO) error
 	}
 
 	RatingImpl struct {
-		repo repository.Rating
+		transactor  repository.Transactor
+		repo        repository.Rating
+		commentRepo repository.CommentRating
+		videos      repository.Video
+		comments    repository.Comment
 	}
 )
 
-func NewRating(repo repository.Rating) *RatingImpl {
+func NewRating(
+	transactor repository.Transactor, repo repository.Rating, commentRepo repository.CommentRating,
+	videos repository.Video, comments repository.Comment,
+) *RatingImpl {
 	return &RatingImpl{
-		repo: repo,
+		transactor:  transactor,
+		repo:        repo,
+		commentRepo: commentRepo,
+		videos:      videos,
+		comments:    comments,
 	}
 }
 
+// Count reads the counters kept on the video, so it does not count the ratings themselves.
 func (s *RatingImpl) Count(ctx context.Context, videoID model.ID) (int64, int64, error) {
 	const op = "service.Rating.Count"
 
-	likes, err := s.repo.CountLikes(ctx, videoID)
+	video, err := s.videos.Get(ctx, videoID)
 	if err != nil {
 		return 0, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	dislikes, err := s.repo.CountDislikes(ctx, videoID)
+	return video.Likes, video.Dislikes, nil
+}
+
+func (s *RatingImpl) FindByUserAndVideos(ctx context.Context, userID model.ID, videoIDs []model.ID) ([]model.Rating, error) {
+	const op = "service.Rating.FindByUserAndVideos"
+
+	ratings, err := s.repo.FindByUserAndVideos(ctx, userID, videoIDs)
 	if err != nil {
-		return 0, 0, fmt.Errorf("%s: %w", op, err)
+		return []model.Rating{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return likes, dislikes, nil
+	return ratings, nil
 }
 
-func (s *RatingImpl) Like(ctx context.Context, dto RatingDTO) error {
-	const op = "service.Rating.Like"
+func (s *RatingImpl) Get(ctx context.Context, dto RatingDTO) (model.Rating, error) {
+	const op = "service.Rating.Get"
 
 	rating, err := s.repo.Get(ctx, repository.RatingDTO(dto))
-	if err != nil && !errors.Is(err, model.ErrRatingNotFound) {
-		return fmt.Errorf("%s: %w", op, err)
+	if err != nil {
+		return model.Rating{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if !errors.Is(err, model.ErrRatingNotFound) && !rating.Like {
-		if err := s.repo.Delete(ctx, repository.RatingDTO(dto)); err != nil {
-			return fmt.Errorf("%s: %w", op, err)
-		}
-	}
+	return rating, nil
+}
 
-	repoDTO := repository.CreateRatingDTO{
-		RatingDTO: repository.RatingDTO(dto),
-		Like:      true,
-	}
+func (s *RatingImpl) Like(ctx context.Context, dto RatingDTO) error {
+	const op = "service.Rating.Like"
 
-	if _, err := s.repo.Create(ctx, repoDTO); err != nil && !errors.Is(err, model.ErrRatingExists) {
+	if err := s.rateVideo(ctx, dto, true); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -82,35 +106,133 @@ func (s *RatingImpl) Like(ctx context.Context, dto RatingDTO) error {
 func (s *RatingImpl) Dislike(ctx context.Context, dto RatingDTO) error {
 	const op = "service.Rating.Dislike"
 
-	rating, err := s.repo.Get(ctx, repository.RatingDTO(dto))
-	if err != nil && !errors.Is(err, model.ErrRatingNotFound) {
+	if err := s.rateVideo(ctx, dto, false); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if !errors.Is(err, model.ErrRatingNotFound) && rating.Like {
-		if err := s.repo.Delete(ctx, repository.RatingDTO(dto)); err != nil {
-			return fmt.Errorf("%s: %w", op, err)
-		}
+	return nil
+}
+
+func (s *RatingImpl) Delete(ctx context.Context, dto RatingDTO) error {
+	const op = "service.Rating.Delete"
+
+	if _, err := s.videos.Get(ctx, dto.VideoID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	repoDTO := repository.CreateRatingDTO{
-		RatingDTO: repository.RatingDTO(dto),
-		Like:      false,
+	if err := s.repo.Delete(ctx, repository.RatingDTO(dto)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if _, err := s.repo.Create(ctx, repoDTO); err != nil && !errors.Is(err, model.ErrRatingExists) {
+	return nil
+}
+
+func (s *RatingImpl) LikeComment(ctx context.Context, dto CommentRatingDTO) error {
+	const op = "service.Rating.LikeComment"
+
+	if err := s.rateComment(ctx, dto, true); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *RatingImpl) Delete(ctx context.Context, dto RatingDTO) error {
-	const op = "service.Rating.Delete"
+func (s *RatingImpl) DislikeComment(ctx context.Context, dto CommentRatingDTO) error {
+	const op = "service.Rating.DislikeComment"
 
-	if err := s.repo.Delete(ctx, repository.RatingDTO(dto)); err != nil {
+	if err := s.rateComment(ctx, dto, false); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+func (s *RatingImpl) DeleteCommentRating(ctx context.Context, dto CommentRatingDTO) error {
+	const op = "service.Rating.DeleteCommentRating"
+
+	if _, err := s.comments.Get(ctx, dto.CommentID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.commentRepo.Delete(ctx, repository.CommentRatingDTO(dto)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// rateVideo replaces the opposite rating of the user in a transaction,
+// so concurrent ratings of the same user cannot leave both a like and a dislike.
+func (s *RatingImpl) rateVideo(ctx context.Context, dto RatingDTO, like bool) error {
+	return s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := s.videos.Get(ctx, dto.VideoID); err != nil {
+			return err
+		}
+
+		rating, err := s.repo.Get(ctx, repository.RatingDTO(dto))
+		if err != nil && !errors.Is(err, model.ErrRatingNotFound) {
+			return err
+		}
+
+		if err == nil {
+			if rating.Like == like {
+				return nil
+			}
+
+			if err := s.repo.Delete(ctx, repository.RatingDTO(dto)); err != nil {
+				return err
+			}
+		}
+
+		repoDTO := repository.CreateRatingDTO{
+			RatingDTO: repository.RatingDTO(dto),
+			Like:      like,
+		}
+
+		if _, err := s.repo.Create(ctx, repoDTO); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// rateComment is rateVideo for comments, deleted comments cannot be rated.
+func (s *RatingImpl) rateComment(ctx context.Context, dto CommentRatingDTO, like bool) error {
+	return s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		comment, err := s.comments.Get(ctx, dto.CommentID)
+		if err != nil {
+			return err
+		}
+
+		if comment.Deleted {
+			return model.ErrCommentNotFound
+		}
+
+		rating, err := s.commentRepo.Get(ctx, repository.CommentRatingDTO(dto))
+		if err != nil && !errors.Is(err, model.ErrRatingNotFound) {
+			return err
+		}
+
+		if err == nil {
+			if rating.Like == like {
+				return nil
+			}
+
+			if err := s.commentRepo.Delete(ctx, repository.CommentRatingDTO(dto)); err != nil {
+				return err
+			}
+		}
+
+		repoDTO := repository.CreateCommentRatingDTO{
+			CommentRatingDTO: repository.CommentRatingDTO(dto),
+			Like:             like,
+		}
+
+		if _, err := s.commentRepo.Create(ctx, repoDTO); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+//go:build sqlite_fts5
+
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
+	"github.com/protomem/gotube/internal/service"
+)
+
+func TestRatingConcurrentRatesKeepCounters(t *testing.T) {
+	const (
+		users  = 8
+		rounds = 50
+	)
+
+	ctx := context.Background()
+	repos := sqliterepo.New(sqlitetest.Logger(t), sqlitetest.OpenFile(t))
+	rating := service.NewRating(repos.Transactor, repos.Rating, repos.CommentRating, repos.Video, repos.Comment)
+
+	author := createUser(t, repos, "author")
+	videoID, err := repos.Video.Create(ctx, repository.CreateVideoDTO{
+		Title:    "video",
+		AuthorID: author.ID,
+		Public:   true,
+		Status:   model.VideoStatusReady,
+	})
+	if err != nil {
+		t.Fatalf("create video: %v", err)
+	}
+
+	raters := make([]model.User, 0, users)
+	for i := 0; i < users; i++ {
+		raters = append(raters, createUser(t, repos, fmt.Sprintf("rater%d", i)))
+	}
+
+	// Every user likes and dislikes the video at the same time, only the last rating of each may count.
+	var wg sync.WaitGroup
+	errs := make(chan error, users*rounds*2)
+	for _, rater := range raters {
+		for i := 0; i < rounds; i++ {
+			dto := service.RatingDTO{UserID: rater.ID, VideoID: videoID}
+
+			wg.Add(2)
+			go func() { defer wg.Done(); errs <- rating.Like(ctx, dto) }()
+			go func() { defer wg.Done(); errs <- rating.Dislike(ctx, dto) }()
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("rate: %v", err)
+		}
+	}
+
+	var wantLikes, wantDislikes int64
+	for _, rater := range raters {
+		r, err := rating.Get(ctx, service.RatingDTO{UserID: rater.ID, VideoID: videoID})
+		if err != nil {
+			t.Fatalf("get rating of %s: %v", rater.Nickname, err)
+		}
+
+		if r.Like {
+			wantLikes++
+		} else {
+			wantDislikes++
+		}
+	}
+
+	likes, dislikes, err := rating.Count(ctx, videoID)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+
+	if likes != wantLikes || dislikes != wantDislikes {
+		t.Fatalf("counters = %d likes, %d dislikes, ratings = %d likes, %d dislikes", likes, dislikes, wantLikes, wantDislikes)
+	}
+	if likes+dislikes != users {
+		t.Fatalf("counters = %d ratings, want %d", likes+dislikes, users)
+	}
+}
+
+func createUser(t *testing.T, repos *repository.Repositories, nickname string) model.User {
+	t.Helper()
+
+	ctx := context.Background()
+
+	id, err := repos.User.Create(ctx, repository.CreateUserDTO{
+		Nickname: nickname,
+		Email:    nickname + "@example.com",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("create user %s: %v", nickname, err)
+	}
+
+	user, err := repos.User.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("get user %s: %v", nickname, err)
+	}
+
+	return user
+}
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"github.com/protomem/gotube/internal/authz"
+	"github.com/protomem/gotube/internal/blobstore"
 	"github.com/protomem/gotube/internal/config"
+	"github.com/protomem/gotube/internal/mailer"
+	"github.com/protomem/gotube/internal/processing"
 	"github.com/protomem/gotube/internal/repository"
 	"github.com/protomem/gotube/pkg/hashing"
 )
@@ -9,28 +13,45 @@ import (
 type Services struct {
 	User
 	Auth
+	Account
 	Subscription
 	Video
 	Rating
 	Comment
+	Upload
+	Processing
+	Media
 }
 
-func New(authConf config.Auth, repos *repository.Repositories, hasher hashing.Hasher) *Services {
+func New(
+	authConf config.Auth, uploadConf config.Upload, processingConf config.Processing, mediaConf config.Media,
+	repos *repository.Repositories, bstore blobstore.Storage,
+	hasher hashing.Hasher, authorizer *authz.Authorizer, mailer mailer.Mailer, views ViewCounter,
+	pipeline *processing.Pipeline, jobs JobQueue,
+) *Services {
 	var (
-		user    = NewUser(repos.User, hasher)
-		auth    = NewAuth(authConf, user)
-		sub     = NewSubscription(repos.Subscription, user)
-		video   = NewVideo(repos.Video, user)
-		rating  = NewRating(repos.Rating)
-		comment = NewComment(repos.Comment)
+		media   = NewMedia(mediaConf, uploadConf, repos.Media, repos.Video, repos.Upload, bstore, authorizer)
+		proc    = NewProcessing(processingConf, repos.Video, bstore, media, pipeline, jobs)
+		user    = NewUser(repos.User, repos.Session, hasher, authorizer, media)
+		auth    = NewAuth(authConf, repos.Session, user)
+		account = NewAccount(authConf, repos.User, repos.UserToken, repos.Session, hasher, mailer)
+		sub     = NewSubscription(repos.Transactor, repos.Subscription, user)
+		video   = NewVideo(repos.Video, bstore, user, authorizer, views, proc, media)
+		rating  = NewRating(repos.Transactor, repos.Rating, repos.CommentRating, repos.Video, repos.Comment)
+		comment = NewComment(repos.Transactor, repos.Comment, repos.Video, authorizer)
+		upload  = NewUpload(uploadConf, repos.Upload, bstore, authorizer, media)
 	)
 
 	return &Services{
 		User:         user,
 		Auth:         auth,
+		Account:      account,
 		Subscription: sub,
 		Video:        video,
 		Rating:       rating,
 		Comment:      comment,
+		Upload:       upload,
+		Processing:   proc,
+		Media:        media,
 	}
 }
@@ -19,20 +19,24 @@ type SubscriptionDTO struct {
 type (
 	Subscription interface {
 		CountSubscribers(ctx context.Context, userNickname string) (int64, error)
+		FindSubscriptions(ctx context.Context, userNickname string, opts FindOptions) ([]model.Subscription, error)
+		FindSubscribers(ctx context.Context, userNickname string, opts FindOptions) ([]model.Subscription, error)
 		Subscribe(ctx context.Context, dto SubscriptionDTO) error
 		Unsubscribe(ctx context.Context, dto SubscriptionDTO) error
 	}
 
 	SubscriptionImpl struct {
-		repo     repository.Subscription
-		userServ User
+		transactor repository.Transactor
+		repo       repository.Subscription
+		userServ   User
 	}
 )
 
-func NewSubscription(repo repository.Subscription, userServ User) *SubscriptionImpl {
+func NewSubscription(transactor repository.Transactor, repo repository.Subscription, userServ User) *SubscriptionImpl {
 	return &SubscriptionImpl{
-		repo:     repo,
-		userServ: userServ,
+		transactor: transactor,
+		repo:       repo,
+		userServ:   userServ,
 	}
 }
 
@@ -52,46 +56,82 @@ func (s *SubscriptionImpl) CountSubscribers(ctx context.Context, userNickname st
 	return count, nil
 }
 
-func (s *SubscriptionImpl) Subscribe(ctx context.Context, dto SubscriptionDTO) error {
-	const op = "service.Subscription.Subscribe"
+func (s *SubscriptionImpl) FindSubscriptions(ctx context.Context, userNickname string, opts FindOptions) ([]model.Subscription, error) {
+	const op = "service.Subscription.FindSubscriptions"
 
-	fromUser, toUser, err := s.getUsers(ctx, dto.FromUserNickname, dto.ToUserNickname)
+	user, err := s.userServ.GetByNickname(ctx, userNickname)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return []model.Subscription{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if _, err := s.repo.Create(ctx, repository.CreateSubscriptionDTO{
-		FromUserID: fromUser.ID,
-		ToUserID:   toUser.ID,
-	}); err != nil {
-		if errors.Is(err, model.ErrSubscriptionExists) {
-			return nil
-		}
-
-		return fmt.Errorf("%s: %w", op, err)
+	subs, err := s.repo.FindByFromUserSortByCreatedAt(ctx, user.ID, repository.FindOptions(opts))
+	if err != nil {
+		return []model.Subscription{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return subs, nil
 }
 
-func (s *SubscriptionImpl) Unsubscribe(ctx context.Context, dto SubscriptionDTO) error {
-	const op = "service.Subscription.Unsubscribe"
+func (s *SubscriptionImpl) FindSubscribers(ctx context.Context, userNickname string, opts FindOptions) ([]model.Subscription, error) {
+	const op = "service.Subscription.FindSubscribers"
 
-	fromUser, toUser, err := s.getUsers(ctx, dto.FromUserNickname, dto.ToUserNickname)
+	user, err := s.userServ.GetByNickname(ctx, userNickname)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return []model.Subscription{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	sub, err := s.repo.GetByFromUserAndToUser(ctx, fromUser.ID, toUser.ID)
+	subs, err := s.repo.FindByToUserSortByCreatedAt(ctx, user.ID, repository.FindOptions(opts))
 	if err != nil {
-		if errors.Is(err, model.ErrSubscriptionNotFound) {
-			return nil
+		return []model.Subscription{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return subs, nil
+}
+
+func (s *SubscriptionImpl) Subscribe(ctx context.Context, dto SubscriptionDTO) error {
+	const op = "service.Subscription.Subscribe"
+
+	if err := s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		fromUser, toUser, err := s.getUsers(ctx, dto.FromUserNickname, dto.ToUserNickname)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.repo.Create(ctx, repository.CreateSubscriptionDTO{
+			FromUserID: fromUser.ID,
+			ToUserID:   toUser.ID,
+		}); err != nil && !errors.Is(err, model.ErrSubscriptionExists) {
+			return err
 		}
 
+		return nil
+	}); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := s.repo.Delete(ctx, sub.ID); err != nil {
+	return nil
+}
+
+func (s *SubscriptionImpl) Unsubscribe(ctx context.Context, dto SubscriptionDTO) error {
+	const op = "service.Subscription.Unsubscribe"
+
+	if err := s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		fromUser, toUser, err := s.getUsers(ctx, dto.FromUserNickname, dto.ToUserNickname)
+		if err != nil {
+			return err
+		}
+
+		sub, err := s.repo.GetByFromUserAndToUser(ctx, fromUser.ID, toUser.ID)
+		if err != nil {
+			if errors.Is(err, model.ErrSubscriptionNotFound) {
+				return nil
+			}
+
+			return err
+		}
+
+		return s.repo.Delete(ctx, sub.ID)
+	}); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -0,0 +1,77 @@
+//go:build sqlite_fts5
+
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
+	"github.com/protomem/gotube/internal/service"
+)
+
+func TestSubscriptionConcurrentSubscribesKeepCount(t *testing.T) {
+	const (
+		followers = 8
+		repeats   = 10
+	)
+
+	ctx := context.Background()
+	repos := sqliterepo.New(sqlitetest.Logger(t), sqlitetest.OpenFile(t))
+	userServ := service.NewUser(repos.User, repos.Session, nil, nil, nil)
+	subs := service.NewSubscription(repos.Transactor, repos.Subscription, userServ)
+
+	createUser(t, repos, "channel")
+
+	nicknames := make([]string, 0, followers)
+	for i := 0; i < followers; i++ {
+		nickname := fmt.Sprintf("follower%d", i)
+		createUser(t, repos, nickname)
+		nicknames = append(nicknames, nickname)
+	}
+
+	run := func(fn func(ctx context.Context, dto service.SubscriptionDTO) error, nicknames []string) {
+		t.Helper()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, len(nicknames)*repeats)
+		for _, nickname := range nicknames {
+			for i := 0; i < repeats; i++ {
+				dto := service.SubscriptionDTO{FromUserNickname: nickname, ToUserNickname: "channel"}
+
+				wg.Add(1)
+				go func() { defer wg.Done(); errs <- fn(ctx, dto) }()
+			}
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				t.Fatalf("subscription: %v", err)
+			}
+		}
+	}
+
+	assertCount := func(want int64) {
+		t.Helper()
+
+		count, err := subs.CountSubscribers(ctx, "channel")
+		if err != nil {
+			t.Fatalf("count subscribers: %v", err)
+		}
+		if count != want {
+			t.Fatalf("subscribers = %d, want %d", count, want)
+		}
+	}
+
+	// Repeated subscriptions of a follower at the same time make a single one.
+	run(subs.Subscribe, nicknames)
+	assertCount(followers)
+
+	run(subs.Unsubscribe, nicknames[:followers/2])
+	assertCount(followers - followers/2)
+}
@@ -1,6 +1,10 @@
 package service
 
+import "github.com/protomem/gotube/internal/model"
+
 type FindOptions struct {
 	Limit  uint64
 	Offset uint64
+	// Cursor, if set, starts the page after the row it points at, which keeps pages stable while rows are added.
+	Cursor *model.Cursor
 }
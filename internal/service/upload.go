@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/authz"
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/internal/config"
+	"github.com/protomem/gotube/internal/ctxstore"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/repository"
+)
+
+// _uploadPartsParent is the blobstore folder holding parts of unfinished uploads.
+const _uploadPartsParent = "_uploads"
+
+var _ Upload = (*UploadImpl)(nil)
+
+type (
+	CreateUploadDTO struct {
+		Kind   model.MediaKind
+		Parent string
+		Name   string
+		Type   string
+		Size   int64
+	}
+
+	WriteChunkDTO struct {
+		Offset int64
+		Size   int64
+		Body   io.Reader
+	}
+)
+
+type (
+	Upload interface {
+		Get(ctx context.Context, id model.ID) (model.Upload, error)
+		Create(ctx context.Context, dto CreateUploadDTO) (model.Upload, error)
+		WriteChunk(ctx context.Context, id model.ID, dto WriteChunkDTO) (model.Upload, error)
+		// Complete assembles the parts into a media file.
+		Complete(ctx context.Context, id model.ID) (model.Media, error)
+		Abort(ctx context.Context, id model.ID) error
+	}
+
+	UploadImpl struct {
+		conf   config.Upload
+		repo   repository.Upload
+		bstore blobstore.Storage
+		authz  *authz.Authorizer
+		media  Media
+	}
+)
+
+func NewUpload(
+	conf config.Upload,
+	repo repository.Upload, bstore blobstore.Storage,
+	authorizer *authz.Authorizer, media Media,
+) *UploadImpl {
+	return &UploadImpl{
+		conf:   conf,
+		repo:   repo,
+		bstore: bstore,
+		authz:  authorizer,
+		media:  media,
+	}
+}
+
+func (s *UploadImpl) Get(ctx context.Context, id model.ID) (model.Upload, error) {
+	const op = "service.Upload.Get"
+
+	upload, err := s.get(ctx, id)
+	if err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return upload, nil
+}
+
+func (s *UploadImpl) Create(ctx context.Context, dto CreateUploadDTO) (model.Upload, error) {
+	const op = "service.Upload.Create"
+
+	if !ValidMediaName(dto.Parent) || !ValidMediaName(dto.Name) {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, model.ErrInvalidMediaName)
+	}
+
+	if _, err := parseMediaParent(dto.Parent); err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !dto.Kind.Valid() {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, model.ErrInvalidMediaKind)
+	}
+
+	if dto.Size <= 0 || dto.Size > s.conf.MaxSize {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, model.ErrUploadTooLarge)
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionUpdate, authz.MediaParentObject(dto.Parent)); err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	owner := ctxstore.MustUser(ctx)
+
+	id, err := s.repo.Create(ctx, repository.CreateUploadDTO{
+		OwnerID: owner.ID,
+		Kind:    dto.Kind,
+		Parent:  dto.Parent,
+		Name:    dto.Name,
+		Type:    dto.Type,
+		Size:    dto.Size,
+	})
+	if err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	upload, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return upload, nil
+}
+
+func (s *UploadImpl) WriteChunk(ctx context.Context, id model.ID, dto WriteChunkDTO) (model.Upload, error) {
+	const op = "service.Upload.WriteChunk"
+
+	upload, err := s.get(ctx, id)
+	if err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Chunks are accepted strictly in order, a client resumes from the reported offset.
+	if dto.Offset != upload.Offset {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, model.ErrUploadOffsetMismatch)
+	}
+
+	if dto.Size <= 0 || dto.Size > s.conf.MaxChunkSize || upload.Offset+dto.Size > upload.Size {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, model.ErrUploadTooLarge)
+	}
+
+	// Every chunk is stored as a part of its own, so concurrent chunks at the same offset never share a blob.
+	part, err := s.partName(upload.ID, upload.Offset)
+	if err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.bstore.Put(ctx, _uploadPartsParent, part, blobstore.Object{
+		Type: upload.Type,
+		Size: dto.Size,
+		Body: dto.Body,
+	}); err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, errors.Join(err, s.deletePart(ctx, part)))
+	}
+
+	from := upload.Offset
+	upload.Offset += dto.Size
+	upload.Parts = append(upload.Parts, part)
+
+	// Only one of concurrent chunks at the same offset is recorded, the parts of the others are dropped.
+	if err := s.repo.UpdateProgress(ctx, upload.ID, repository.UpdateUploadProgressDTO{
+		From:   from,
+		Offset: upload.Offset,
+		Part:   part,
+	}); err != nil {
+		return model.Upload{}, fmt.Errorf("%s: %w", op, errors.Join(err, s.deletePart(ctx, part)))
+	}
+
+	return upload, nil
+}
+
+func (s *UploadImpl) Complete(ctx context.Context, id model.ID) (model.Media, error) {
+	const op = "service.Upload.Complete"
+
+	upload, err := s.get(ctx, id)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if upload.Offset != upload.Size {
+		return model.Media{}, fmt.Errorf("%s: %w", op, model.ErrUploadIncomplete)
+	}
+
+	ownerID, err := parseMediaParent(upload.Parent)
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	body := &partsReader{ctx: ctx, bstore: s.bstore, names: upload.Parts}
+	defer func() { _ = body.Close() }()
+
+	media, err := s.media.Store(ctx, StoreMediaDTO{
+		OwnerID: ownerID,
+		Kind:    upload.Kind,
+		Type:    upload.Type,
+		Size:    upload.Size,
+		Body:    body,
+	})
+	if err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.cleanup(ctx, upload); err != nil {
+		return model.Media{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return media, nil
+}
+
+func (s *UploadImpl) Abort(ctx context.Context, id model.ID) error {
+	const op = "service.Upload.Abort"
+
+	upload, err := s.get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.cleanup(ctx, upload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *UploadImpl) get(ctx context.Context, id model.ID) (model.Upload, error) {
+	upload, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	// An abandoned upload is gone for its owner even before the garbage collector sweeps it.
+	if uploadExpired(upload, s.conf.TTL) {
+		return model.Upload{}, model.ErrUploadNotFound
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionUpdate, authz.MediaObject(upload.OwnerID)); err != nil {
+		return model.Upload{}, err
+	}
+
+	return upload, nil
+}
+
+func (s *UploadImpl) cleanup(ctx context.Context, upload model.Upload) error {
+	for _, part := range upload.Parts {
+		if err := s.deletePart(ctx, part); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.Delete(ctx, upload.ID)
+}
+
+func (s *UploadImpl) deletePart(ctx context.Context, part string) error {
+	if err := s.bstore.Del(ctx, _uploadPartsParent, part); err != nil && !errors.Is(err, blobstore.ErrObjectNotFound) {
+		return err
+	}
+	return nil
+}
+
+// partName names a part after its upload, which the garbage collector relies on, and the offset it starts at.
+func (*UploadImpl) partName(id model.ID, offset int64) (string, error) {
+	suffix, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return id.String() + "." + strconv.FormatInt(offset, 10) + "." + suffix.String(), nil
+}
+
+// uploadExpired reports whether the upload got no chunk for longer than the ttl.
+func uploadExpired(upload model.Upload, ttl time.Duration) bool {
+	return ttl > 0 && time.Since(upload.UpdatedAt) > ttl
+}
+
+// ValidMediaName reports whether the name can be used as a media parent or file name.
+func ValidMediaName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, "_")
+}
+
+// partsReader streams upload parts one after another, opening each only when it is reached.
+type partsReader struct {
+	ctx    context.Context
+	bstore blobstore.Storage
+	names  []string
+
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.names) == 0 {
+				return 0, io.EOF
+			}
+
+			obj, err := r.bstore.Open(r.ctx, _uploadPartsParent, r.names[0])
+			if err != nil {
+				return 0, err
+			}
+
+			r.current = obj.Body
+			r.names = r.names[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			_ = r.current.Close()
+			r.current = nil
+
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+//go:build sqlite_fts5
+
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/protomem/gotube/internal/authz"
+	"github.com/protomem/gotube/internal/blobstore"
+	"github.com/protomem/gotube/internal/blobstore/inmem"
+	"github.com/protomem/gotube/internal/config"
+	"github.com/protomem/gotube/internal/ctxstore"
+	"github.com/protomem/gotube/internal/database/sqlite/sqlitetest"
+	"github.com/protomem/gotube/internal/model"
+	sqliterepo "github.com/protomem/gotube/internal/repository/sqlite"
+	"github.com/protomem/gotube/internal/service"
+)
+
+func TestUploadConcurrentChunksAtSameOffset(t *testing.T) {
+	const (
+		rounds  = 20
+		writers = 4
+		size    = 4096
+	)
+
+	logger := sqlitetest.Logger(t)
+	repos := sqliterepo.New(logger, sqlitetest.OpenFile(t))
+
+	bstore, err := inmem.New(logger)
+	if err != nil {
+		t.Fatalf("new blob storage: %v", err)
+	}
+
+	uploadConf := config.Upload{MaxSize: size, MaxChunkSize: size}
+	authorizer := authz.New()
+	media := service.NewMedia(config.Media{}, uploadConf, repos.Media, repos.Video, repos.Upload, bstore, authorizer)
+	uploads := service.NewUpload(uploadConf, repos.Upload, bstore, authorizer, media)
+
+	owner := createUser(t, repos, "owner")
+	ctx := ctxstore.WithUser(context.Background(), owner)
+
+	for round := 0; round < rounds; round++ {
+		upload, err := uploads.Create(ctx, service.CreateUploadDTO{
+			Kind:   model.MediaKindVideo,
+			Parent: owner.ID.String(),
+			Name:   "video.mp4",
+			Type:   "video/mp4",
+			Size:   size,
+		})
+		if err != nil {
+			t.Fatalf("create upload: %v", err)
+		}
+
+		// Every writer sends the whole file at once, each with a body of its own.
+		bodies := make([][]byte, writers)
+		for i := range bodies {
+			bodies[i] = bytes.Repeat([]byte{byte('a' + i)}, size)
+			copy(bodies[i], "\x00\x00\x00\x18ftypisom")
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, writers)
+		for i := range bodies {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, errs[i] = uploads.WriteChunk(ctx, upload.ID, service.WriteChunkDTO{
+					Offset: 0,
+					Size:   size,
+					Body:   bytes.NewReader(bodies[i]),
+				})
+			}()
+		}
+		wg.Wait()
+
+		winner := -1
+		for i, err := range errs {
+			switch {
+			case err == nil && winner < 0:
+				winner = i
+			case err == nil:
+				t.Fatalf("round %d: writers %d and %d both recorded the chunk", round, winner, i)
+			case !errors.Is(err, model.ErrUploadOffsetMismatch):
+				t.Fatalf("round %d: write chunk: %v", round, err)
+			}
+		}
+		if winner < 0 {
+			t.Fatalf("round %d: no writer recorded the chunk", round)
+		}
+
+		stored, err := uploads.Complete(ctx, upload.ID)
+		if err != nil {
+			t.Fatalf("round %d: complete: %v", round, err)
+		}
+
+		obj, err := bstore.Get(ctx, stored.Parent, stored.Name)
+		if err != nil {
+			t.Fatalf("round %d: get media: %v", round, err)
+		}
+
+		content, err := io.ReadAll(obj.Body)
+		if err != nil {
+			t.Fatalf("round %d: read media: %v", round, err)
+		}
+
+		if !bytes.Equal(content, bodies[winner]) {
+			t.Fatalf("round %d: media is not the body of writer %d which recorded the chunk", round, winner)
+		}
+	}
+
+	// The parts of the losing writers are dropped, the completed uploads leave none behind.
+	if err := bstore.Walk(context.Background(), func(info blobstore.ObjectInfo) error {
+		if info.Parent == "_uploads" {
+			t.Errorf("part %s left behind", info.Name)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/protomem/gotube/internal/authz"
 	"github.com/protomem/gotube/internal/model"
 	"github.com/protomem/gotube/internal/repository"
 	"github.com/protomem/gotube/pkg/hashing"
@@ -20,11 +21,12 @@ type (
 	}
 
 	UpdateUserDTO struct {
-		Nickname    *string
-		Email       *string
-		Verified    *bool
-		AvatarPath  *string
-		Description *string
+		Nickname *string
+		Email    *string
+		Verified *bool
+		// AvatarMediaID links an avatar uploaded by the user, uuid.Nil removes the avatar.
+		AvatarMediaID *model.ID
+		Description   *string
 
 		NewPassword *string
 		OldPassword *string
@@ -33,26 +35,60 @@ type (
 
 type (
 	User interface {
+		Find(ctx context.Context, opts FindOptions) ([]model.User, error)
+		Get(ctx context.Context, id model.ID) (model.User, error)
 		GetByNickname(ctx context.Context, nickname string) (model.User, error)
 		GetByEmailAndPassword(ctx context.Context, email, password string) (model.User, error)
 		Create(ctx context.Context, dto CreateUserDTO) (model.User, error)
 		UpdateByNickname(ctx context.Context, nickname string, dto UpdateUserDTO) (model.User, error)
+		SetRoleByNickname(ctx context.Context, nickname string, role model.Role) (model.User, error)
 		DeleteByNickname(ctx context.Context, nickname string) error
 	}
 
 	UserImpl struct {
-		repo   repository.User
-		hasher hashing.Hasher
+		repo        repository.User
+		sessionRepo repository.Session
+		hasher      hashing.Hasher
+		authz       *authz.Authorizer
+		media       Media
 	}
 )
 
-func NewUser(repo repository.User, hasher hashing.Hasher) *UserImpl {
+func NewUser(
+	repo repository.User, sessionRepo repository.Session,
+	hasher hashing.Hasher, authorizer *authz.Authorizer, media Media,
+) *UserImpl {
 	return &UserImpl{
-		repo:   repo,
-		hasher: hasher,
+		repo:        repo,
+		sessionRepo: sessionRepo,
+		hasher:      hasher,
+		authz:       authorizer,
+		media:       media,
 	}
 }
 
+func (s *UserImpl) Find(ctx context.Context, opts FindOptions) ([]model.User, error) {
+	const op = "service.User.Find"
+
+	users, err := s.repo.Find(ctx, repository.FindOptions(opts))
+	if err != nil {
+		return []model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func (s *UserImpl) Get(ctx context.Context, id model.ID) (model.User, error) {
+	const op = "service.User.Get"
+
+	user, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
 func (s *UserImpl) GetByNickname(ctx context.Context, nickname string) (model.User, error) {
 	const op = "service.User.GetByNickname"
 
@@ -119,14 +155,25 @@ func (s *UserImpl) UpdateByNickname(ctx context.Context, nickname string, dto Up
 		return model.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := s.authz.Authorize(ctx, authz.ActionUpdate, authz.UserObject(oldUser)); err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
 	repoDTO := repository.UpdateUserDTO{
 		Nickname:    dto.Nickname,
 		Email:       dto.Email,
 		Verified:    dto.Verified,
-		AvatarPath:  dto.AvatarPath,
 		Description: dto.Description,
 	}
 
+	if dto.AvatarMediaID != nil {
+		avatarPath, err := linkMedia(ctx, s.media, *dto.AvatarMediaID, oldUser.ID, model.MediaKindAvatar)
+		if err != nil {
+			return model.User{}, fmt.Errorf("%s: %w", op, err)
+		}
+		repoDTO.AvatarPath = &avatarPath
+	}
+
 	if dto.Email != nil {
 		repoDTO.Verified = new(bool)
 		*repoDTO.Verified = false
@@ -152,6 +199,13 @@ func (s *UserImpl) UpdateByNickname(ctx context.Context, nickname string, dto Up
 		return model.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// Changing the password signs out every device.
+	if repoDTO.Password != nil {
+		if err := s.sessionRepo.DeleteByUser(ctx, oldUser.ID); err != nil {
+			return model.User{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	newUser, err := s.repo.Get(ctx, oldUser.ID)
 	if err != nil {
 		return model.User{}, fmt.Errorf("%s: %w", op, err)
@@ -160,6 +214,34 @@ func (s *UserImpl) UpdateByNickname(ctx context.Context, nickname string, dto Up
 	return newUser, nil
 }
 
+func (s *UserImpl) SetRoleByNickname(ctx context.Context, nickname string, role model.Role) (model.User, error) {
+	const op = "service.User.SetRoleByNickname"
+
+	if !role.Valid() {
+		return model.User{}, fmt.Errorf("%s: %w", op, model.ErrInvalidRole)
+	}
+
+	user, err := s.repo.GetByNickname(ctx, nickname)
+	if err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionManage, authz.UserObject(user)); err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.repo.Update(ctx, user.ID, repository.UpdateUserDTO{Role: &role}); err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	newUser, err := s.repo.Get(ctx, user.ID)
+	if err != nil {
+		return model.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return newUser, nil
+}
+
 func (s *UserImpl) DeleteByNickname(ctx context.Context, nickname string) error {
 	const op = "service.User.DeleteByNickname"
 
@@ -168,6 +250,10 @@ func (s *UserImpl) DeleteByNickname(ctx context.Context, nickname string) error
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := s.authz.Authorize(ctx, authz.ActionDelete, authz.UserObject(user)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	if err := s.repo.Delete(ctx, user.ID); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
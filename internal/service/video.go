@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/protomem/gotube/internal/authz"
+	"github.com/protomem/gotube/internal/blobstore"
 	"github.com/protomem/gotube/internal/model"
 	"github.com/protomem/gotube/internal/repository"
 )
@@ -13,20 +17,45 @@ var _ Video = (*VideoImpl)(nil)
 
 type (
 	CreateVideoDTO struct {
-		Title         string
-		Description   *string
-		ThumbnailPath string
-		VideoPath     string
-		AuthorID      model.ID
-		Public        *bool
+		Title       string
+		Description *string
+		// ThumbnailMediaID is optional, a thumbnail is taken from the video while it is processed.
+		ThumbnailMediaID *model.ID
+		VideoMediaID     model.ID
+		AuthorID         model.ID
+		Public           *bool
+	}
+
+	OpenStreamDTO struct {
+		VideoID model.ID
+		UserID  *model.ID
+		// Name is the path of the file relative to the master playlist.
+		Name string
+	}
+
+	RecordViewDTO struct {
+		VideoID model.ID
+		UserID  *model.ID
+		IP      string
+	}
+
+	SearchVideoDTO struct {
+		Term           string
+		AuthorNickname *string
+		UploadedAfter  *time.Time
+		UploadedBefore *time.Time
+		// MinDuration and MaxDuration are in seconds.
+		MinDuration *int64
+		MaxDuration *int64
 	}
 
 	UpdateVideoDTO struct {
-		Title         *string
-		Description   *string
-		ThumbnailPath *string
-		VideoPath     *string
-		Public        *bool
+		Title       *string
+		Description *string
+		// ThumbnailMediaID set to uuid.Nil removes the thumbnail.
+		ThumbnailMediaID *model.ID
+		VideoMediaID     *model.ID
+		Public           *bool
 	}
 )
 
@@ -35,23 +64,44 @@ type (
 		FindLatest(ctx context.Context, opts FindOptions) ([]model.Video, error)
 		FindPopular(ctx context.Context, opts FindOptions) ([]model.Video, error)
 		FindByAuthor(ctx context.Context, authorNickname string, opts FindOptions) ([]model.Video, error)
-		Search(ctx context.Context, term string, opts FindOptions) ([]model.Video, error)
+		// Feed lists the latest videos of the channels the user is subscribed to.
+		Feed(ctx context.Context, userID model.ID, opts FindOptions) ([]model.Video, error)
+		Search(ctx context.Context, dto SearchVideoDTO, opts FindOptions) ([]model.Video, error)
 		Get(ctx context.Context, id model.ID) (model.Video, error)
 		Create(ctx context.Context, dto CreateVideoDTO) (model.Video, error)
 		Update(ctx context.Context, id model.ID, dto UpdateVideoDTO) (model.Video, error)
 		Delete(ctx context.Context, id model.ID) error
+		RecordView(ctx context.Context, dto RecordViewDTO) (counted bool, err error)
+		OpenStream(ctx context.Context, dto OpenStreamDTO) (blobstore.ObjectFile, error)
+	}
+
+	ViewCounter interface {
+		Record(videoID model.ID, viewer string) bool
 	}
 
 	VideoImpl struct {
-		repo     repository.Video
-		userServ User
+		repo       repository.Video
+		bstore     blobstore.Storage
+		userServ   User
+		authz      *authz.Authorizer
+		views      ViewCounter
+		processing Processing
+		media      Media
 	}
 )
 
-func NewVideo(repo repository.Video, userServ User) Video {
+func NewVideo(
+	repo repository.Video, bstore blobstore.Storage,
+	userServ User, authorizer *authz.Authorizer, views ViewCounter, processing Processing, media Media,
+) Video {
 	return &VideoImpl{
-		repo:     repo,
-		userServ: userServ,
+		repo:       repo,
+		bstore:     bstore,
+		userServ:   userServ,
+		authz:      authorizer,
+		views:      views,
+		processing: processing,
+		media:      media,
 	}
 }
 
@@ -93,10 +143,28 @@ func (s *VideoImpl) FindByAuthor(ctx context.Context, authorNickname string, opt
 	return videos, nil
 }
 
-func (s *VideoImpl) Search(ctx context.Context, term string, opts FindOptions) ([]model.Video, error) {
+func (s *VideoImpl) Feed(ctx context.Context, userID model.ID, opts FindOptions) ([]model.Video, error) {
+	const op = "service.Video.Feed"
+
+	videos, err := s.repo.FindBySubscriberSortByCreatedAtWherePublic(ctx, userID, repository.FindOptions(opts))
+	if err != nil {
+		return []model.Video{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return videos, nil
+}
+
+func (s *VideoImpl) Search(ctx context.Context, dto SearchVideoDTO, opts FindOptions) ([]model.Video, error) {
 	const op = "service.Video.Search"
 
-	videos, err := s.repo.FindLikeByTitleWherePublic(ctx, term, repository.FindOptions(opts))
+	videos, err := s.repo.SearchWherePublic(ctx, repository.SearchVideosDTO{
+		Query:          dto.Term,
+		AuthorNickname: dto.AuthorNickname,
+		UploadedAfter:  dto.UploadedAfter,
+		UploadedBefore: dto.UploadedBefore,
+		MinDuration:    dto.MinDuration,
+		MaxDuration:    dto.MaxDuration,
+	}, repository.FindOptions(opts))
 	if err != nil {
 		return []model.Video{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -120,13 +188,30 @@ func (s *VideoImpl) Create(ctx context.Context, dto CreateVideoDTO) (model.Video
 
 	// TODO: Add validation
 
+	videoPath, err := linkMedia(ctx, s.media, dto.VideoMediaID, dto.AuthorID, model.MediaKindVideo)
+	if err != nil {
+		return model.Video{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if videoPath == "" {
+		return model.Video{}, fmt.Errorf("%s: %w", op, model.ErrMediaNotFound)
+	}
+
+	var thumbnailPath string
+	if dto.ThumbnailMediaID != nil {
+		thumbnailPath, err = linkMedia(ctx, s.media, *dto.ThumbnailMediaID, dto.AuthorID, model.MediaKindThumbnail)
+		if err != nil {
+			return model.Video{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	repoDTO := repository.CreateVideoDTO{
 		Title:         dto.Title,
 		Description:   s.autoGenerateVideoDescription(),
-		ThumbnailPath: dto.ThumbnailPath,
-		VideoPath:     dto.VideoPath,
+		ThumbnailPath: thumbnailPath,
+		VideoPath:     videoPath,
 		AuthorID:      dto.AuthorID,
 		Public:        true,
+		Status:        s.initialStatus(videoPath),
 	}
 	if dto.Description != nil {
 		repoDTO.Description = *dto.Description
@@ -140,7 +225,7 @@ func (s *VideoImpl) Create(ctx context.Context, dto CreateVideoDTO) (model.Video
 		return model.Video{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	video, err := s.repo.Get(ctx, id)
+	video, err := s.startProcessing(ctx, id)
 	if err != nil {
 		return model.Video{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -151,15 +236,52 @@ func (s *VideoImpl) Create(ctx context.Context, dto CreateVideoDTO) (model.Video
 func (s *VideoImpl) Update(ctx context.Context, id model.ID, dto UpdateVideoDTO) (model.Video, error) {
 	const op = "service.Video.Update"
 
-	if _, err := s.repo.Get(ctx, id); err != nil {
+	oldVideo, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Video{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionUpdate, authz.VideoObject(oldVideo)); err != nil {
 		return model.Video{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := s.repo.Update(ctx, id, repository.UpdateVideoDTO(dto)); err != nil {
+	repoDTO := repository.UpdateVideoDTO{
+		Title:       dto.Title,
+		Description: dto.Description,
+		Public:      dto.Public,
+	}
+
+	// Media is checked against the author, also when a moderator edits the video.
+	if dto.ThumbnailMediaID != nil {
+		thumbnailPath, err := linkMedia(ctx, s.media, *dto.ThumbnailMediaID, oldVideo.Author.ID, model.MediaKindThumbnail)
+		if err != nil {
+			return model.Video{}, fmt.Errorf("%s: %w", op, err)
+		}
+		repoDTO.ThumbnailPath = &thumbnailPath
+	}
+
+	if dto.VideoMediaID != nil {
+		videoPath, err := linkMedia(ctx, s.media, *dto.VideoMediaID, oldVideo.Author.ID, model.MediaKindVideo)
+		if err != nil {
+			return model.Video{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if videoPath == "" {
+			return model.Video{}, fmt.Errorf("%s: %w", op, model.ErrMediaNotFound)
+		}
+
+		// A new source file has to be processed again.
+		if videoPath != oldVideo.VideoPath {
+			status := s.initialStatus(videoPath)
+			repoDTO.VideoPath = &videoPath
+			repoDTO.Status = &status
+		}
+	}
+
+	if err := s.repo.Update(ctx, id, repoDTO); err != nil {
 		return model.Video{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	newVideo, err := s.repo.Get(ctx, id)
+	newVideo, err := s.startProcessing(ctx, id)
 	if err != nil {
 		return model.Video{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -170,6 +292,15 @@ func (s *VideoImpl) Update(ctx context.Context, id model.ID, dto UpdateVideoDTO)
 func (s *VideoImpl) Delete(ctx context.Context, id model.ID) error {
 	const op = "service.Video.Delete"
 
+	video, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.authz.Authorize(ctx, authz.ActionDelete, authz.VideoObject(video)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	if err := s.repo.Delete(ctx, id); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -177,6 +308,87 @@ func (s *VideoImpl) Delete(ctx context.Context, id model.ID) error {
 	return nil
 }
 
+func (s *VideoImpl) RecordView(ctx context.Context, dto RecordViewDTO) (bool, error) {
+	const op = "service.Video.RecordView"
+
+	video, err := s.repo.Get(ctx, dto.VideoID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !s.visible(video, dto.UserID) {
+		return false, fmt.Errorf("%s: %w", op, model.ErrVideoNotFound)
+	}
+
+	// Signed in viewers are recognized on any device, anonymous ones by address.
+	viewer := "ip:" + dto.IP
+	if dto.UserID != nil {
+		viewer = "user:" + dto.UserID.String()
+	}
+
+	return s.views.Record(video.ID, viewer), nil
+}
+
+func (s *VideoImpl) OpenStream(ctx context.Context, dto OpenStreamDTO) (blobstore.ObjectFile, error) {
+	const op = "service.Video.OpenStream"
+
+	video, err := s.repo.Get(ctx, dto.VideoID)
+	if err != nil {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !s.visible(video, dto.UserID) {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, model.ErrVideoNotFound)
+	}
+
+	if video.Status != model.VideoStatusReady || video.StreamPath == "" {
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, model.ErrStreamNotFound)
+	}
+
+	for _, part := range strings.Split(dto.Name, "/") {
+		if !ValidMediaName(part) {
+			return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, model.ErrStreamNotFound)
+		}
+	}
+
+	obj, err := s.bstore.Open(ctx, _hlsParent, hlsObjectName(video.ID, dto.Name))
+	if err != nil {
+		if errors.Is(err, blobstore.ErrObjectNotFound) {
+			return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, model.ErrStreamNotFound)
+		}
+
+		return blobstore.ObjectFile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return obj, nil
+}
+
+// visible hides private videos from everyone but the author.
+func (*VideoImpl) visible(video model.Video, userID *model.ID) bool {
+	return video.Public || (userID != nil && *userID == video.Author.ID)
+}
+
+// initialStatus marks videos stored in media as waiting for the upload, external ones are ready as is.
+func (*VideoImpl) initialStatus(videoPath string) model.VideoStatus {
+	if _, _, ok := parseMediaPath(videoPath); ok {
+		return model.VideoStatusUploading
+	}
+	return model.VideoStatusReady
+}
+
+func (s *VideoImpl) startProcessing(ctx context.Context, id model.ID) (model.Video, error) {
+	video, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return model.Video{}, err
+	}
+
+	if err := s.processing.Start(ctx, video); err != nil {
+		return model.Video{}, err
+	}
+
+	return s.repo.Get(ctx, id)
+}
+
 func (s *VideoImpl) autoGenerateVideoDescription() string {
 	return fmt.Sprintf("Auto generated description %d/%s", time.Now().Year(), time.Now().Month().String())
 }
@@ -0,0 +1,160 @@
+package viewcounter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/pkg/logging"
+)
+
+type Store interface {
+	IncrementViews(ctx context.Context, counts map[model.ID]int64) error
+}
+
+type Options struct {
+	// Window is the period during which repeated views of a video by the same viewer are ignored.
+	Window time.Duration
+	// FlushInterval is how often buffered counts are written to the store.
+	FlushInterval time.Duration
+	// MaxPending triggers an early flush when that many views are buffered.
+	MaxPending int
+}
+
+// Counter de-duplicates views and buffers them in memory, so the store receives
+// one batched write per flush instead of one write per view.
+type Counter struct {
+	logger logging.Logger
+	store  Store
+	opts   Options
+
+	mux     sync.Mutex
+	seen    map[string]time.Time
+	pending map[model.ID]int64
+	total   int
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+func New(logger logging.Logger, store Store, opts Options) *Counter {
+	return &Counter{
+		logger:  logger.With("component", "viewcounter"),
+		store:   store,
+		opts:    opts,
+		seen:    make(map[string]time.Time),
+		pending: make(map[model.ID]int64),
+		flushCh: make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+}
+
+// Record counts a view unless the viewer has already watched the video within the window.
+func (c *Counter) Record(videoID model.ID, viewer string) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	now := time.Now()
+	key := videoID.String() + "|" + viewer
+
+	if last, ok := c.seen[key]; ok && now.Sub(last) < c.opts.Window {
+		return false
+	}
+
+	c.seen[key] = now
+	c.pending[videoID]++
+	c.total++
+
+	if c.opts.MaxPending > 0 && c.total >= c.opts.MaxPending {
+		select {
+		case c.flushCh <- struct{}{}:
+		default:
+		}
+	}
+
+	return true
+}
+
+func (c *Counter) Start() {
+	go c.loop()
+}
+
+func (c *Counter) Flush(ctx context.Context) error {
+	const op = "viewcounter.Flush"
+
+	c.mux.Lock()
+	counts := c.pending
+	c.pending = make(map[model.ID]int64)
+	c.total = 0
+	c.prune(time.Now())
+	c.mux.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	if err := c.store.IncrementViews(ctx, counts); err != nil {
+		c.restore(counts)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.logger.WithContext(ctx).Debug("views flushed", "videos", len(counts))
+
+	return nil
+}
+
+// Close stops the background loop and flushes the remaining views.
+func (c *Counter) Close(ctx context.Context) error {
+	close(c.stopCh)
+
+	select {
+	case <-c.doneCh:
+	case <-ctx.Done():
+		return fmt.Errorf("viewcounter.Close: %w", ctx.Err())
+	}
+
+	return c.Flush(ctx)
+}
+
+func (c *Counter) loop() {
+	defer close(c.doneCh)
+
+	ticker := time.NewTicker(c.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+		case <-c.flushCh:
+		}
+
+		if err := c.Flush(context.Background()); err != nil {
+			c.logger.Error("failed to flush views", "err", err)
+		}
+	}
+}
+
+// restore puts back counts that failed to be written, so they are retried on the next flush.
+func (c *Counter) restore(counts map[model.ID]int64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for videoID, count := range counts {
+		c.pending[videoID] += count
+		c.total += int(count)
+	}
+}
+
+func (c *Counter) prune(now time.Time) {
+	for key, last := range c.seen {
+		if now.Sub(last) >= c.opts.Window {
+			delete(c.seen, key)
+		}
+	}
+}
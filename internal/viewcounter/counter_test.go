@@ -0,0 +1,224 @@
+package viewcounter_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/protomem/gotube/internal/model"
+	"github.com/protomem/gotube/internal/viewcounter"
+	"github.com/protomem/gotube/pkg/logging/std"
+)
+
+var errTest = errors.New("test")
+
+// fakeStore keeps the written counts and fails the writes while err is set.
+type fakeStore struct {
+	mux    sync.Mutex
+	err    error
+	counts map[model.ID]int64
+
+	writeCh chan struct{}
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		counts:  make(map[model.ID]int64),
+		writeCh: make(chan struct{}, 1),
+	}
+}
+
+func (s *fakeStore) IncrementViews(_ context.Context, counts map[model.ID]int64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	for id, count := range counts {
+		s.counts[id] += count
+	}
+
+	select {
+	case s.writeCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (s *fakeStore) setErr(err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.err = err
+}
+
+func (s *fakeStore) written() map[model.ID]int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return maps.Clone(s.counts)
+}
+
+func newCounter(t *testing.T, store viewcounter.Store, opts viewcounter.Options) *viewcounter.Counter {
+	t.Helper()
+
+	logger, err := std.New("error", io.Discard)
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+
+	return viewcounter.New(logger, store, opts)
+}
+
+func TestCounterIgnoresRepeatedViewsWithinWindow(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	counter := newCounter(t, store, viewcounter.Options{Window: time.Hour})
+
+	video, other := uuid.New(), uuid.New()
+
+	if !counter.Record(video, "alice") {
+		t.Fatal("first view of alice is not counted")
+	}
+	if counter.Record(video, "alice") {
+		t.Fatal("repeated view of alice is counted")
+	}
+	if !counter.Record(video, "bob") {
+		t.Fatal("view of bob is not counted")
+	}
+	if !counter.Record(other, "alice") {
+		t.Fatal("view of alice of another video is not counted")
+	}
+
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	// The viewers are remembered across flushes until the window passes.
+	if counter.Record(video, "alice") {
+		t.Fatal("repeated view of alice after flush is counted")
+	}
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	expectCounts(t, store.written(), map[model.ID]int64{video: 2, other: 1})
+}
+
+func TestCounterCountsViewsAfterWindow(t *testing.T) {
+	const window = 20 * time.Millisecond
+
+	ctx := context.Background()
+	store := newFakeStore()
+	counter := newCounter(t, store, viewcounter.Options{Window: window})
+
+	video := uuid.New()
+
+	if !counter.Record(video, "alice") {
+		t.Fatal("first view is not counted")
+	}
+
+	time.Sleep(2 * window)
+
+	if !counter.Record(video, "alice") {
+		t.Fatal("view after the window is not counted")
+	}
+
+	// The flush drops viewers whose window passed, they are counted again afterwards.
+	time.Sleep(2 * window)
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if !counter.Record(video, "alice") {
+		t.Fatal("view after the window and a flush is not counted")
+	}
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	expectCounts(t, store.written(), map[model.ID]int64{video: 3})
+}
+
+func TestCounterFlushesEarlyWhenMaxPendingReached(t *testing.T) {
+	const maxPending = 3
+
+	store := newFakeStore()
+	counter := newCounter(t, store, viewcounter.Options{
+		Window:        time.Hour,
+		FlushInterval: time.Hour,
+		MaxPending:    maxPending,
+	})
+	counter.Start()
+	t.Cleanup(func() { _ = counter.Close(context.Background()) })
+
+	video := uuid.New()
+	viewers := []string{"alice", "bob", "carol"}
+
+	for _, viewer := range viewers[:maxPending-1] {
+		counter.Record(video, viewer)
+	}
+
+	select {
+	case <-store.writeCh:
+		t.Fatal("flushed before max pending views")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	counter.Record(video, viewers[maxPending-1])
+
+	select {
+	case <-store.writeCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no flush after max pending views, long before the flush interval")
+	}
+
+	expectCounts(t, store.written(), map[model.ID]int64{video: maxPending})
+}
+
+func TestCounterRestoresCountsOfFailedFlush(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	counter := newCounter(t, store, viewcounter.Options{Window: time.Hour})
+
+	video, other := uuid.New(), uuid.New()
+	counter.Record(video, "alice")
+	counter.Record(video, "bob")
+
+	store.setErr(errTest)
+	if err := counter.Flush(ctx); !errors.Is(err, errTest) {
+		t.Fatalf("flush = %v, want %v", err, errTest)
+	}
+	expectCounts(t, store.written(), map[model.ID]int64{})
+
+	// Views recorded after the failure are added to the restored ones.
+	counter.Record(video, "carol")
+	counter.Record(other, "alice")
+
+	store.setErr(nil)
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	expectCounts(t, store.written(), map[model.ID]int64{video: 3, other: 1})
+
+	// Restored counts are written once.
+	if err := counter.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	expectCounts(t, store.written(), map[model.ID]int64{video: 3, other: 1})
+}
+
+func expectCounts(t *testing.T, got, want map[model.ID]int64) {
+	t.Helper()
+
+	if !maps.Equal(got, want) {
+		t.Fatalf("written counts = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// Package filetype recognizes image and video files by their leading bytes,
+// so the type of an upload does not depend on its name or the client.
+package filetype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"strings"
+
+	// Registered for image.DecodeConfig.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// HeadSize is enough of a file to detect its type and, for images, to read the dimensions.
+const HeadSize = 1 << 20
+
+var ErrUnknownDimensions = errors.New("unknown dimensions")
+
+type Type struct {
+	MIME string
+	// Ext is the usual extension of the type, with the leading dot.
+	Ext string
+}
+
+var (
+	JPEG      = Type{MIME: "image/jpeg", Ext: ".jpg"}
+	PNG       = Type{MIME: "image/png", Ext: ".png"}
+	GIF       = Type{MIME: "image/gif", Ext: ".gif"}
+	WebP      = Type{MIME: "image/webp", Ext: ".webp"}
+	MP4       = Type{MIME: "video/mp4", Ext: ".mp4"}
+	QuickTime = Type{MIME: "video/quicktime", Ext: ".mov"}
+	WebM      = Type{MIME: "video/webm", Ext: ".webm"}
+	Matroska  = Type{MIME: "video/x-matroska", Ext: ".mkv"}
+	AVI       = Type{MIME: "video/x-msvideo", Ext: ".avi"}
+)
+
+// IsImage reports whether the type is one of the recognized image formats.
+func (t Type) IsImage() bool {
+	return strings.HasPrefix(t.MIME, "image/")
+}
+
+// Detect returns the type of the content starting with head.
+func Detect(head []byte) (Type, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return JPEG, true
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG, true
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return GIF, true
+	case isRIFF(head, "WEBP"):
+		return WebP, true
+	case isRIFF(head, "AVI "):
+		return AVI, true
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return detectEBML(head)
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return detectISO(string(head[8:12]))
+	default:
+		return Type{}, false
+	}
+}
+
+// Dimensions returns the width and height of an image of the type, read from its head.
+func Dimensions(t Type, head []byte) (int, int, error) {
+	if t == WebP {
+		return webpDimensions(head)
+	}
+
+	if !t.IsImage() {
+		return 0, 0, ErrUnknownDimensions
+	}
+
+	conf, _, err := image.DecodeConfig(bytes.NewReader(head))
+	if err != nil {
+		return 0, 0, ErrUnknownDimensions
+	}
+
+	return conf.Width, conf.Height, nil
+}
+
+func isRIFF(head []byte, form string) bool {
+	return len(head) >= 12 && string(head[0:4]) == "RIFF" && string(head[8:12]) == form
+}
+
+// detectEBML tells WebM from other Matroska files by the DocType of the EBML header.
+func detectEBML(head []byte) (Type, bool) {
+	header := head[:min(len(head), 64)]
+
+	switch {
+	case bytes.Contains(header, []byte("webm")):
+		return WebM, true
+	case bytes.Contains(header, []byte("matroska")):
+		return Matroska, true
+	default:
+		return Type{}, false
+	}
+}
+
+// detectISO maps the major brand of an ISO base media file, images and audio sharing the container are not videos.
+func detectISO(brand string) (Type, bool) {
+	switch brand {
+	case "qt  ":
+		return QuickTime, true
+	case "avif", "avis", "heic", "heix", "heim", "heis", "mif1", "msf1", "M4A ", "M4B ", "M4P ":
+		return Type{}, false
+	default:
+		return MP4, true
+	}
+}
+
+func webpDimensions(head []byte) (int, int, error) {
+	if len(head) < 30 {
+		return 0, 0, ErrUnknownDimensions
+	}
+
+	switch string(head[12:16]) {
+	case "VP8 ":
+		// A key frame starts with a 3 byte tag and the 9d 01 2a start code.
+		if !bytes.Equal(head[23:26], []byte{0x9D, 0x01, 0x2A}) {
+			return 0, 0, ErrUnknownDimensions
+		}
+		width := int(binary.LittleEndian.Uint16(head[26:28]) & 0x3FFF)
+		height := int(binary.LittleEndian.Uint16(head[28:30]) & 0x3FFF)
+		return width, height, nil
+	case "VP8L":
+		if head[20] != 0x2F {
+			return 0, 0, ErrUnknownDimensions
+		}
+		bits := binary.LittleEndian.Uint32(head[21:25])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1, nil
+	case "VP8X":
+		width := int(head[24]) | int(head[25])<<8 | int(head[26])<<16
+		height := int(head[27]) | int(head[28])<<8 | int(head[29])<<16
+		return width + 1, height + 1, nil
+	default:
+		return 0, 0, ErrUnknownDimensions
+	}
+}
@@ -3,6 +3,10 @@ package httplib
 const (
 	HeaderContentType   = "Content-Type"
 	HeaderAuthorization = "Authorization"
+	HeaderETag          = "ETag"
+	HeaderLocation      = "Location"
+	HeaderUploadOffset  = "Upload-Offset"
+	HeaderUploadLength  = "Upload-Length"
 )
 
 const (
@@ -0,0 +1,197 @@
+// Package imaging strips metadata from images and makes smaller copies of them, using only the standard library.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// Formats by their content type.
+const (
+	FormatJPEG = "image/jpeg"
+	FormatPNG  = "image/png"
+	FormatGIF  = "image/gif"
+	FormatWebP = "image/webp"
+)
+
+// Decodable reports whether images of the format can be decoded, WebP can only be stripped.
+func Decodable(format string) bool {
+	switch format {
+	case FormatJPEG, FormatPNG, FormatGIF:
+		return true
+	default:
+		return false
+	}
+}
+
+// Strip removes the metadata of an image without decoding it, GIF images carry none and are returned as is.
+func Strip(format string, data []byte) ([]byte, error) {
+	switch format {
+	case FormatJPEG:
+		return StripJPEG(data)
+	case FormatPNG:
+		return StripPNG(data)
+	case FormatWebP:
+		return StripWebP(data)
+	case FormatGIF:
+		return data, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// Decode decodes an image and turns a JPEG image upright by its EXIF orientation.
+// Only the first frame of an animated GIF is decoded.
+func Decode(format string, data []byte) (image.Image, error) {
+	var (
+		img image.Image
+		err error
+	)
+
+	switch format {
+	case FormatJPEG:
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = Orient(img, Orientation(data))
+		}
+	case FormatPNG:
+		img, err = png.Decode(bytes.NewReader(data))
+	case FormatGIF:
+		img, err = gif.Decode(bytes.NewReader(data))
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	return img, nil
+}
+
+// Encode writes the image as JPEG of the quality or as PNG, images of other formats are written as PNG.
+func Encode(w io.Writer, format string, img image.Image, quality int) error {
+	if format == FormatJPEG {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}
+	return png.Encode(w, img)
+}
+
+// EncodedFormat is the format Encode writes images of the format in.
+func EncodedFormat(format string) string {
+	if format == FormatJPEG {
+		return FormatJPEG
+	}
+	return FormatPNG
+}
+
+// Orient turns the image by an EXIF orientation, 1 to 8, so it is shown upright.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+
+	// Orientations from 5 on swap the sides.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+
+			si, di := src.PixOffset(sx, sy), dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
+
+// Fit scales the image down so its longer side is at most size, averaging the pixels each new pixel covers.
+// Smaller images are returned unchanged.
+func Fit(img image.Image, size int) image.Image {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if size <= 0 || (w <= size && h <= size) {
+		return img
+	}
+
+	dw, dh := size, max(1, h*size/w)
+	if h > w {
+		dw, dh = max(1, w*size/h), size
+	}
+
+	src := toRGBA(img)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		sy0, sy1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+
+		for x := 0; x < dw; x++ {
+			sx0, sx1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			// Premultiplied channels average without darkening transparent edges.
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				i := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					b += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// toRGBA returns the image as RGBA with its origin at zero.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+
+	return rgba
+}
@@ -0,0 +1,214 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ErrMalformed = errors.New("malformed image")
+
+// JPEG markers.
+const (
+	_markerSOI  = 0xD8
+	_markerSOS  = 0xDA
+	_markerAPP1 = 0xE1
+	_markerAPPD = 0xED
+	_markerCOM  = 0xFE
+)
+
+// _orientationTag is the EXIF tag telling how the stored pixels are turned.
+const _orientationTag = 0x0112
+
+// StripJPEG drops the EXIF, XMP, IPTC and comment segments without decoding the image.
+// Segments needed to show the image as intended, like ICC profiles, are kept.
+func StripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != _markerSOI {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+
+	for pos := 2; ; {
+		marker, segment, next, err := nextSegment(data, pos)
+		if err != nil {
+			return nil, err
+		}
+
+		// The entropy coded data follows the start of scan, it is copied as is.
+		if marker == _markerSOS {
+			return append(out, data[pos:]...), nil
+		}
+
+		if marker != _markerAPP1 && marker != _markerAPPD && marker != _markerCOM {
+			out = append(out, segment...)
+		}
+
+		pos = next
+	}
+}
+
+// Orientation returns the EXIF orientation of a JPEG image, 1 when it has none.
+func Orientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != _markerSOI {
+		return 1
+	}
+
+	for pos := 2; ; {
+		marker, segment, next, err := nextSegment(data, pos)
+		if err != nil || marker == _markerSOS {
+			return 1
+		}
+
+		if marker == _markerAPP1 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[10:])
+		}
+
+		pos = next
+	}
+}
+
+// nextSegment returns the marker at pos, the whole segment with its marker and the position after it.
+func nextSegment(data []byte, pos int) (byte, []byte, int, error) {
+	// Markers may be padded with any number of 0xFF bytes.
+	for pos+1 < len(data) && data[pos] == 0xFF && data[pos+1] == 0xFF {
+		pos++
+	}
+
+	if pos+4 > len(data) || data[pos] != 0xFF {
+		return 0, nil, 0, ErrMalformed
+	}
+
+	marker := data[pos+1]
+	length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+	end := pos + 2 + length
+
+	if length < 2 || end > len(data) {
+		return 0, nil, 0, ErrMalformed
+	}
+
+	return marker, data[pos:end], end, nil
+}
+
+// exifOrientation reads the orientation from the first IFD of a TIFF header.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if order.Uint16(tiff[entry:entry+2]) == _orientationTag {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value < 1 || value > 8 {
+				return 1
+			}
+			return value
+		}
+	}
+
+	return 1
+}
+
+// _pngTextChunks hold metadata which is not needed to show the image.
+var _pngTextChunks = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+// StripPNG drops the text, EXIF and time chunks.
+func StripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+
+	for pos := len(signature); pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		// Length, type, data and CRC.
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:pos+4]))
+		if end > len(data) || end < pos {
+			return nil, ErrMalformed
+		}
+
+		if !_pngTextChunks[string(data[pos+4:pos+8])] {
+			out = append(out, data[pos:end]...)
+		}
+
+		pos = end
+	}
+
+	return out, nil
+}
+
+// VP8X flags announcing metadata chunks.
+const (
+	_webpFlagXMP  = 0x04
+	_webpFlagEXIF = 0x08
+)
+
+// StripWebP drops the EXIF and XMP chunks and clears their flags.
+func StripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+
+	for pos := 12; pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+
+		// Chunks are padded to an even size.
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size&1
+		if end > len(data) || end < pos {
+			return nil, ErrMalformed
+		}
+
+		switch fourCC := string(data[pos : pos+4]); fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= _webpFlagEXIF | _webpFlagXMP
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+
+		pos = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+
+	return out, nil
+}
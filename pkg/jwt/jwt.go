@@ -15,6 +15,19 @@ type GenerateParams struct {
 	TTL        time.Duration
 	Subject    string
 	Issuer     string
+	ID         string
+	Role       string
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
+type Payload struct {
+	ID      string
+	Subject string
+	Role    string
 }
 
 func Generate(params GenerateParams) (string, error) {
@@ -24,13 +37,17 @@ func Generate(params GenerateParams) (string, error) {
 
 	now := time.Now()
 
-	claims := jwt.RegisteredClaims{
-		Subject:   params.Subject,
-		IssuedAt:  jwt.NewNumericDate(now),
-		NotBefore: jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(params.TTL)),
-		Issuer:    params.Issuer,
-		Audience:  jwt.ClaimStrings{params.Issuer},
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   params.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(params.TTL)),
+			Issuer:    params.Issuer,
+			Audience:  jwt.ClaimStrings{params.Issuer},
+			ID:        params.ID,
+		},
+		Role: params.Role,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -48,25 +65,25 @@ type ParseParams struct {
 	Issuer     string
 }
 
-func Parse(signedToken string, params ParseParams) (subject string, err error) {
+func Parse(signedToken string, params ParseParams) (Payload, error) {
 	opts := []jwt.ParserOption{
 		jwt.WithIssuer(params.Issuer), jwt.WithAudience(params.Issuer),
 		jwt.WithLeeway(time.Minute), jwt.WithIssuedAt(), jwt.WithExpirationRequired(),
 	}
 
-	token, err := jwt.ParseWithClaims(signedToken, &jwt.RegisteredClaims{}, func(token *jwt.Token) (any, error) {
+	token, err := jwt.ParseWithClaims(signedToken, &tokenClaims{}, func(token *jwt.Token) (any, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(params.SigningKey), nil
 	}, opts...)
 	if err != nil {
-		return "", err
+		return Payload{}, err
 	}
 
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
-		return claims.Subject, nil
+	if claims, ok := token.Claims.(*tokenClaims); ok && token.Valid {
+		return Payload{ID: claims.ID, Subject: claims.Subject, Role: claims.Role}, nil
 	}
 
-	return "", ErrInvalidToken
+	return Payload{}, ErrInvalidToken
 }
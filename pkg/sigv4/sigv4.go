@@ -0,0 +1,284 @@
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Algorithm = "AWS4-HMAC-SHA256"
+
+	// UnsignedPayload lets a body be streamed without hashing it up front.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// EmptyPayload is the hash of an empty body.
+	EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	HeaderDate          = "X-Amz-Date"
+	HeaderContentSHA256 = "X-Amz-Content-Sha256"
+
+	_timeFormat  = "20060102T150405Z"
+	_dateFormat  = "20060102"
+	_maxClockGap = 15 * time.Minute
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("signature does not match")
+	ErrExpired          = errors.New("request expired")
+)
+
+type Credentials struct {
+	AccessKey string
+	SecretKey string
+}
+
+// Signer signs requests with AWS Signature Version 4.
+type Signer struct {
+	Credentials Credentials
+	Region      string
+	Service     string
+}
+
+// Sign adds the date, payload hash and authorization headers to the request.
+// The payload hash is either the hex SHA-256 of the body or UnsignedPayload.
+func (s Signer) Sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+
+	req.Header.Set(HeaderDate, now.Format(_timeFormat))
+	req.Header.Set(HeaderContentSHA256, payloadHash)
+
+	signedHeaders := signableHeaders(req)
+	signature := s.signature(req, req.URL.Query(), signedHeaders, payloadHash, now)
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		Algorithm, s.Credentials.AccessKey, s.scope(now), strings.Join(signedHeaders, ";"), signature,
+	))
+}
+
+// Presign returns the request URL with a signature valid for ttl in its query.
+func (s Signer) Presign(req *http.Request, ttl time.Duration, now time.Time) *url.URL {
+	now = now.UTC()
+
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", Algorithm)
+	query.Set("X-Amz-Credential", s.Credentials.AccessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(_timeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	signature := s.signature(req, query, []string{"host"}, UnsignedPayload, now)
+	query.Set("X-Amz-Signature", signature)
+
+	u := *req.URL
+	u.RawQuery = canonicalQuery(query)
+
+	return &u
+}
+
+// Verify checks a request signed by the header or the query method, the server side of Sign and Presign.
+func (s Signer) Verify(req *http.Request, now time.Time) error {
+	if req.URL.Query().Get("X-Amz-Signature") != "" {
+		return s.verifyQuery(req, now)
+	}
+	return s.verifyHeader(req, now)
+}
+
+func (s Signer) verifyHeader(req *http.Request, now time.Time) error {
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		return ErrMissingSignature
+	}
+
+	fields, ok := strings.CutPrefix(auth, Algorithm+" ")
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	params := make(map[string]string)
+	for _, field := range strings.Split(fields, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		params[key] = value
+	}
+
+	date, err := time.Parse(_timeFormat, req.Header.Get(HeaderDate))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if now.Sub(date).Abs() > _maxClockGap {
+		return ErrExpired
+	}
+
+	if params["Credential"] != s.Credentials.AccessKey+"/"+s.scope(date) {
+		return ErrInvalidSignature
+	}
+
+	signedHeaders := strings.Split(params["SignedHeaders"], ";")
+	expected := s.signature(req, req.URL.Query(), signedHeaders, req.Header.Get(HeaderContentSHA256), date)
+
+	if !hmac.Equal([]byte(expected), []byte(params["Signature"])) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (s Signer) verifyQuery(req *http.Request, now time.Time) error {
+	query := req.URL.Query()
+
+	date, err := time.Parse(_timeFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if now.After(date.Add(time.Duration(expires) * time.Second)) {
+		return ErrExpired
+	}
+
+	if query.Get("X-Amz-Credential") != s.Credentials.AccessKey+"/"+s.scope(date) {
+		return ErrInvalidSignature
+	}
+
+	signature := query.Get("X-Amz-Signature")
+	query.Del("X-Amz-Signature")
+
+	signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	expected := s.signature(req, query, signedHeaders, UnsignedPayload, date)
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (s Signer) signature(req *http.Request, query url.Values, signedHeaders []string, payloadHash string, now time.Time) string {
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		EncodePath(req.URL.Path),
+		canonicalQuery(query),
+		canonicalHeaders(req, signedHeaders),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		Algorithm,
+		now.Format(_timeFormat),
+		s.scope(now),
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.Credentials.SecretKey), now.Format(_dateFormat))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s Signer) scope(now time.Time) string {
+	return now.Format(_dateFormat) + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+// signableHeaders picks the host and the x-amz-* headers, other headers may be changed by proxies.
+func signableHeaders(req *http.Request) []string {
+	headers := []string{"host"}
+	for key := range req.Header {
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, "x-amz-") || key == "content-md5" {
+			headers = append(headers, key)
+		}
+	}
+	sort.Strings(headers)
+	return headers
+}
+
+func canonicalHeaders(req *http.Request, signedHeaders []string) string {
+	var b strings.Builder
+	for _, key := range signedHeaders {
+		value := req.Header.Get(key)
+		if key == "host" {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+		b.WriteString(key + ":" + strings.Join(strings.Fields(value), " ") + "\n")
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, Encode(key)+"="+Encode(value))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// EncodePath escapes every segment of the path as required by the signature.
+func EncodePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = Encode(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Encode escapes everything except the unreserved characters of RFC 3986.
+func Encode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}